@@ -0,0 +1,408 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/bitlife"
+	"github.com/jpbetz/cellularautomata/grid"
+	"io"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// Object is a still life, oscillator or spaceship in a catalog.
+type Object struct {
+	Name   string
+	Kind   Kind
+	Period int
+	// Displacement is how far a spaceship moves every Period generations
+	Displacement grid.Position
+}
+
+// Catalog recognizes objects in every phase, rotation and reflection.
+type Catalog struct {
+	Rule bitlife.Rule
+	// MaxPeriod is the longest period of the objects that can be added
+	MaxPeriod int
+
+	objects map[string]*Object
+}
+
+func NewCatalog(rule bitlife.Rule) *Catalog {
+	return &Catalog{Rule: rule, MaxPeriod: 64, objects: map[string]*Object{}}
+}
+
+// Add adds the object of the pattern, given as rows of . and * ended by $, in the style of macrocell files. The
+// pattern is run until it repeats to find its other phases, so it must be a single still life, oscillator or spaceship.
+func (c *Catalog) Add(name, pattern string) error {
+	cells := []grid.Position{}
+	for y, row := range strings.Split(pattern, "$") {
+		for x, ch := range row {
+			switch ch {
+			case '*':
+				cells = append(cells, grid.Position{X: x, Y: y})
+			case '.':
+			default:
+				return fmt.Errorf("pattern of %s has invalid character %q", name, ch)
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return fmt.Errorf("pattern of %s is empty", name)
+	}
+
+	first, offset := normalize(cells)
+	phases := []string{canonical(cells)}
+	for generation := 1; generation <= c.MaxPeriod; generation++ {
+		cells = step(c.Rule, cells)
+		if len(cells) == 0 {
+			return fmt.Errorf("%s dies out", name)
+		}
+		next, nextOffset := normalize(cells)
+		if next == first {
+			object := &Object{
+				Name:         name,
+				Kind:         Oscillator,
+				Period:       generation,
+				Displacement: grid.Position{X: nextOffset.X - offset.X, Y: nextOffset.Y - offset.Y},
+			}
+			if object.Displacement != grid.Origin {
+				object.Kind = Spaceship
+			} else if generation == 1 {
+				object.Kind = StillLife
+			}
+			for _, phase := range phases {
+				c.objects[phase] = object
+			}
+			return nil
+		}
+		phases = append(phases, canonical(cells))
+	}
+	return fmt.Errorf("%s does not repeat within %d generations", name, c.MaxPeriod)
+}
+
+// Match returns the object the cells are a phase of, or nil.
+func (c *Catalog) Match(cells []grid.Position) *Object {
+	return c.objects[canonical(cells)]
+}
+
+// Objects returns the objects of the catalog sorted by name.
+func (c *Catalog) Objects() []*Object {
+	seen := map[*Object]bool{}
+	objects := []*Object{}
+	for _, object := range c.objects {
+		if !seen[object] {
+			seen[object] = true
+			objects = append(objects, object)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+	return objects
+}
+
+// ConwayCatalog holds the common objects of Conway's Game of Life.
+var ConwayCatalog = mustCatalog(bitlife.Conway, [][2]string{
+	{"block", "**$**"},
+	{"beehive", ".**.$*..*$.**."},
+	{"loaf", ".**.$*..*$.*.*$..*"},
+	{"boat", "**.$*.*$.*"},
+	{"ship", "**.$*.*$.**"},
+	{"tub", ".*.$*.*$.*"},
+	{"pond", ".**.$*..*$*..*$.**."},
+	{"long boat", "**..$*.*.$.*.*$..*"},
+	{"barge", ".*..$*.*.$.*.*$..*"},
+	{"snake", "**.*$*.**"},
+	{"aircraft carrier", "**..$*..*$..**"},
+	{"eater", "**..$*.*.$..*.$..**"},
+	{"blinker", "***"},
+	{"toad", ".***$***."},
+	{"beacon", "**..$**..$..**$..**"},
+	{"pulsar", "..***...***..$$*....*.*....*$*....*.*....*$*....*.*....*$..***...***..$$" +
+		"..***...***..$*....*.*....*$*....*.*....*$*....*.*....*$$..***...***.."},
+	{"pentadecathlon", "..*....*..$**.****.**$..*....*.."},
+	{"glider", ".*.$..*$***"},
+	{"lightweight spaceship", ".*..*$*....$*...*$****."},
+	{"middleweight spaceship", "...*..$.*...*$*.....$*....*$*****."},
+	{"heavyweight spaceship", "...**..$.*....*$*......$*.....*$******."},
+})
+
+func mustCatalog(rule bitlife.Rule, objects [][2]string) *Catalog {
+	catalog := NewCatalog(rule)
+	for _, object := range objects {
+		if err := catalog.Add(object[0], object[1]); err != nil {
+			panic(err)
+		}
+	}
+	return catalog
+}
+
+// Census counts the objects of a plane by name. Objects that are not in the catalog are named "unknown" followed by
+// their cells, e.g. "unknown **$*.*".
+type Census map[string]int
+
+// TakeCensus splits the live cells of the plane into objects and counts them. Cells within 2 cells of each other are
+// first matched together, which keeps objects such as the pulsar, made of several unconnected parts, whole. If that
+// doesn't match an object of the catalog, each connected part is matched on its own.
+func TakeCensus(plane grid.Plane, alive func(cell grid.Cell) bool, catalog *Catalog) Census {
+	census := Census{}
+	for _, cluster := range components(liveCells(plane, alive), 2) {
+		if object := catalog.Match(cluster); object != nil {
+			census[object.Name]++
+			continue
+		}
+		clustered := make(map[grid.Position]bool, len(cluster))
+		for _, p := range cluster {
+			clustered[p] = true
+		}
+		for _, part := range components(clustered, 1) {
+			if object := catalog.Match(part); object != nil {
+				census[object.Name]++
+			} else {
+				census["unknown "+canonical(part)]++
+			}
+		}
+	}
+	return census
+}
+
+// Escaping returns the spaceships of the catalog that are flying away from the other live cells of the plane, so
+// that they can be removed before they reach the edges of a bounded plane. A spaceship is escaping once it is more than
+// gap cells beyond the bounding box of the other cells on a side it moves away from, or when it is alone.
+func (c *Catalog) Escaping(plane grid.Plane, alive func(cell grid.Cell) bool, gap int) [][]grid.Position {
+	clusters := components(liveCells(plane, alive), 2)
+	bounds := make([]grid.Rectangle, len(clusters))
+	for i, cluster := range clusters {
+		bounds[i] = boundingBox(cluster)
+	}
+	escaping := [][]grid.Position{}
+	for i, cluster := range clusters {
+		object := c.Match(cluster)
+		if object == nil || object.Kind != Spaceship {
+			continue
+		}
+		others, alone := grid.Rectangle{}, true
+		for j, b := range bounds {
+			if j == i {
+				continue
+			}
+			if alone {
+				others, alone = b, false
+			}
+			others = extend(extend(others, b.Corner1), b.Corner2)
+		}
+		// the catalog knows how far the spaceship moves but not in which direction, which depends on its orientation
+		_, before := normalize(cluster)
+		moved := cluster
+		for generation := 0; generation < object.Period; generation++ {
+			moved = step(c.Rule, moved)
+		}
+		_, after := normalize(moved)
+		dx, dy, own := after.X-before.X, after.Y-before.Y, bounds[i]
+		if alone ||
+			dx > 0 && own.Corner1.X > others.Corner2.X+gap || dx < 0 && own.Corner2.X < others.Corner1.X-gap ||
+			dy > 0 && own.Corner1.Y > others.Corner2.Y+gap || dy < 0 && own.Corner2.Y < others.Corner1.Y-gap {
+			escaping = append(escaping, cluster)
+		}
+	}
+	return escaping
+}
+
+// Names returns the names of the census, most common first.
+func (c Census) Names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if c[names[i]] != c[names[j]] {
+			return c[names[i]] > c[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// String returns the census as a table of counts and names.
+func (c Census) String() string {
+	var out bytes.Buffer
+	for _, name := range c.Names() {
+		fmt.Fprintf(&out, "%6d  %s\n", c[name], name)
+	}
+	return out.String()
+}
+
+// WriteJSON writes the census as a JSON object of names to counts.
+func (c Census) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]int(c))
+}
+
+// liveCells returns the positions of the live cells of the plane.
+func liveCells(plane grid.Plane, alive func(cell grid.Cell) bool) map[grid.Position]bool {
+	live := map[grid.Position]bool{}
+	if board, ok := plane.(*bitlife.Board); ok && alive(board.Alive) && !alive(board.Dead) {
+		// skip the words of a bitboard with no live cells
+		for y := 0; y < board.H; y++ {
+			for i, word := range board.Row(y) {
+				for ; word != 0; word &= word - 1 {
+					live[grid.Position{X: i*64 + bits.TrailingZeros64(word), Y: y}] = true
+				}
+			}
+		}
+		return live
+	}
+	bounds := plane.Bounds()
+	for y := bounds.Corner1.Y; y <= bounds.Corner2.Y; y++ {
+		for x := bounds.Corner1.X; x <= bounds.Corner2.X; x++ {
+			p := grid.Position{X: x, Y: y}
+			if alive(plane.Get(p)) {
+				live[p] = true
+			}
+		}
+	}
+	return live
+}
+
+// boundingBox returns the smallest rectangle holding the cells.
+func boundingBox(cells []grid.Position) grid.Rectangle {
+	box := grid.Rectangle{Corner1: cells[0], Corner2: cells[0]}
+	for _, p := range cells[1:] {
+		box = extend(box, p)
+	}
+	return box
+}
+
+// extend returns the rectangle grown to hold the position.
+func extend(r grid.Rectangle, p grid.Position) grid.Rectangle {
+	if p.X < r.Corner1.X {
+		r.Corner1.X = p.X
+	}
+	if p.Y < r.Corner1.Y {
+		r.Corner1.Y = p.Y
+	}
+	if p.X > r.Corner2.X {
+		r.Corner2.X = p.X
+	}
+	if p.Y > r.Corner2.Y {
+		r.Corner2.Y = p.Y
+	}
+	return r
+}
+
+// components returns the groups of cells connected through cells at most radius apart.
+func components(cells map[grid.Position]bool, radius int) [][]grid.Position {
+	seen := make(map[grid.Position]bool, len(cells))
+	groups := [][]grid.Position{}
+	for start := range cells {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		group := []grid.Position{start}
+		for i := 0; i < len(group); i++ {
+			for dy := -radius; dy <= radius; dy++ {
+				for dx := -radius; dx <= radius; dx++ {
+					p := grid.Position{X: group[i].X + dx, Y: group[i].Y + dy}
+					if cells[p] && !seen[p] {
+						seen[p] = true
+						group = append(group, p)
+					}
+				}
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// normalize returns the rows of the cells relative to the top left of their bounding box, and the top left.
+func normalize(cells []grid.Position) (string, grid.Position) {
+	min, max := cells[0], cells[0]
+	for _, p := range cells {
+		if p.X < min.X {
+			min.X = p.X
+		}
+		if p.Y < min.Y {
+			min.Y = p.Y
+		}
+		if p.X > max.X {
+			max.X = p.X
+		}
+		if p.Y > max.Y {
+			max.Y = p.Y
+		}
+	}
+	w, h := max.X-min.X+1, max.Y-min.Y+1
+	rows := make([][]byte, h)
+	for y := range rows {
+		rows[y] = bytes.Repeat([]byte{'.'}, w)
+	}
+	for _, p := range cells {
+		rows[p.Y-min.Y][p.X-min.X] = '*'
+	}
+	text := make([]string, h)
+	for y, row := range rows {
+		text[y] = strings.TrimRight(string(row), ".")
+	}
+	return strings.Join(text, "$"), min
+}
+
+// canonical returns the same rows for the cells in any rotation or reflection.
+func canonical(cells []grid.Position) string {
+	best := ""
+	transformed := make([]grid.Position, len(cells))
+	for t := 0; t < 8; t++ {
+		for i, p := range cells {
+			x, y := p.X, p.Y
+			if t&1 != 0 {
+				x = -x
+			}
+			if t&2 != 0 {
+				y = -y
+			}
+			if t&4 != 0 {
+				x, y = y, x
+			}
+			transformed[i] = grid.Position{X: x, Y: y}
+		}
+		if rows, _ := normalize(transformed); best == "" || rows < best {
+			best = rows
+		}
+	}
+	return best
+}
+
+// step advances the cells by one generation of the rule on an unbounded plane.
+func step(rule bitlife.Rule, cells []grid.Position) []grid.Position {
+	alive := make(map[grid.Position]bool, len(cells))
+	for _, p := range cells {
+		alive[p] = true
+	}
+	counts := map[grid.Position]int{}
+	for _, p := range cells {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx != 0 || dy != 0 {
+					counts[grid.Position{X: p.X + dx, Y: p.Y + dy}]++
+				}
+			}
+		}
+	}
+	next := []grid.Position{}
+	for p, count := range counts {
+		if rule.Next(alive[p], count) {
+			next = append(next, p)
+		}
+	}
+	for _, p := range cells {
+		if counts[p] == 0 && rule.Next(true, 0) {
+			next = append(next, p)
+		}
+	}
+	return next
+}
@@ -0,0 +1,120 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/jpbetz/cellularautomata/bitlife"
+	"github.com/jpbetz/cellularautomata/grid"
+	"strings"
+	"testing"
+)
+
+func TestConwayCatalog(t *testing.T) {
+	cases := []struct {
+		name         string
+		kind         Kind
+		period       int
+		displacement grid.Position
+	}{
+		{"block", StillLife, 1, grid.Origin},
+		{"eater", StillLife, 1, grid.Origin},
+		{"beacon", Oscillator, 2, grid.Origin},
+		{"pulsar", Oscillator, 3, grid.Origin},
+		{"pentadecathlon", Oscillator, 15, grid.Origin},
+		{"glider", Spaceship, 4, grid.Position{X: 1, Y: 1}},
+		{"heavyweight spaceship", Spaceship, 4, grid.Position{X: -2, Y: 0}},
+	}
+	objects := map[string]*Object{}
+	for _, object := range ConwayCatalog.Objects() {
+		objects[object.Name] = object
+	}
+	for _, c := range cases {
+		object, ok := objects[c.name]
+		if !ok {
+			t.Errorf("Expected %s in the catalog", c.name)
+			continue
+		}
+		if object.Kind != c.kind || object.Period != c.period || object.Displacement != c.displacement {
+			t.Errorf("Expected %s to be a %s of period %d moving %v but was %+v", c.name, c.kind, c.period,
+				c.displacement, object)
+		}
+	}
+}
+
+// place sets the cells of the pattern with its top left at x, y.
+func place(board *bitlife.Board, x, y int, pattern string) {
+	for dy, row := range strings.Split(pattern, "$") {
+		for dx, ch := range row {
+			board.SetAlive(grid.Position{X: x + dx, Y: y + dy}, ch == '*')
+		}
+	}
+}
+
+func TestCensus(t *testing.T) {
+	board := bitlife.NewBoard(64, 64, cell(true), cell(false))
+	place(board, 1, 1, "**$**")
+	place(board, 10, 1, "**$**")
+	// the second phase of a beacon, in two parts
+	place(board, 20, 1, "**..$*...$...*$..**")
+	// a glider flipped and rotated
+	place(board, 30, 1, "*..$*.*$**.")
+	place(board, 1, 10, "***")
+	// the second phase of a pulsar
+	for _, p := range step(bitlife.Conway, pulsarCells()) {
+		board.SetAlive(grid.Position{X: p.X + 20, Y: p.Y + 20}, true)
+	}
+	// a blinker with a block too close to be matched together, and something unknown
+	place(board, 1, 40, "***$$**$**")
+	place(board, 50, 50, "*..$.**")
+
+	census := TakeCensus(board, isAlive, ConwayCatalog)
+	expected := Census{"block": 3, "beacon": 1, "glider": 1, "blinker": 2, "pulsar": 1, "unknown *$*$.*": 1}
+	if len(census) != len(expected) {
+		t.Errorf("Expected census %v but was %v", expected, census)
+	}
+	for name, count := range expected {
+		if census[name] != count {
+			t.Errorf("Expected %d %s but found %d in %v", count, name, census[name], census)
+		}
+	}
+
+	if names := census.Names(); names[0] != "block" || names[1] != "blinker" {
+		t.Errorf("Expected the most common objects first but was %v", names)
+	}
+	var out bytes.Buffer
+	if err := census.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	decoded := Census{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded["pulsar"] != 1 {
+		t.Errorf("Expected the census as JSON but was %s", out.String())
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	board := bitlife.NewBoard(64, 64, cell(true), cell(false))
+	place(board, 30, 30, "**$**")
+	// gliders moving down and right, one flying away from the block and one towards it
+	place(board, 40, 40, ".*.$..*$***")
+	place(board, 20, 20, ".*.$..*$***")
+
+	escaping := ConwayCatalog.Escaping(board, isAlive, 4)
+	if len(escaping) != 1 || boundingBox(escaping[0]).Corner1 != (grid.Position{X: 40, Y: 40}) {
+		t.Errorf("Expected only the glider flying away to be escaping but was %v", escaping)
+	}
+}
+
+func pulsarCells() []grid.Position {
+	cells := []grid.Position{}
+	quarter := []string{"..***", "", "*....*", "*....*", "*....*", "..***"}
+	for y, row := range quarter {
+		for x, ch := range row {
+			if ch == '*' {
+				cells = append(cells,
+					grid.Position{X: x, Y: y}, grid.Position{X: 12 - x, Y: y},
+					grid.Position{X: x, Y: 12 - y}, grid.Position{X: 12 - x, Y: 12 - y})
+			}
+		}
+	}
+	return cells
+}
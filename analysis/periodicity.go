@@ -0,0 +1,239 @@
+package analysis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/bitlife"
+	"github.com/jpbetz/cellularautomata/grid"
+	"hash/fnv"
+	"math/bits"
+)
+
+// Kind is what a pattern settled into.
+type Kind int
+
+const (
+	// Unknown is a pattern that has not repeated yet.
+	Unknown Kind = iota
+	// Dead is a pattern with no live cells left.
+	Dead
+	// StillLife is a pattern that no longer changes.
+	StillLife
+	// Oscillator is a pattern that returns to the same state in the same place every Period generations.
+	Oscillator
+	// Spaceship is a pattern that returns to the same state moved by Displacement every Period generations.
+	Spaceship
+)
+
+var kindNames = []string{"unknown", "dead", "still life", "oscillator", "spaceship"}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Result is the periodicity found by a Detector.
+type Result struct {
+	Kind   Kind
+	Period int
+	// Displacement is how far the pattern moves every Period generations
+	Displacement grid.Position
+	// Generation is the first generation the pattern was seen repeating in, counting the first observed as 0
+	Generation int
+}
+
+func (r Result) String() string {
+	switch r.Kind {
+	case Oscillator:
+		return fmt.Sprintf("oscillator with period %d", r.Period)
+	case Spaceship:
+		return fmt.Sprintf("spaceship moving %d,%d every %d generations", r.Displacement.X, r.Displacement.Y, r.Period)
+	case Unknown:
+		return "no repetition yet"
+	default:
+		return r.Kind.String()
+	}
+}
+
+// DefaultMaxPeriod is the longest period a Detector looks for unless told otherwise.
+const DefaultMaxPeriod = 1024
+
+// Detector finds when the live cells of a plane repeat, up to translation. It is given the plane every generation and
+// keeps a hash of the live cells relative to their bounding box, so a pattern that repeats in a different place is
+// found too, with how far it moved. Patterns are compared by their 64 bit hashes only.
+//
+// A single object is classified exactly. A plane with several objects is periodic only as a whole, e.g. blinkers and
+// a block together are an oscillator of period 2, but a glider flying away from a block never repeats.
+type Detector struct {
+	// Alive returns whether a cell is alive
+	Alive func(cell grid.Cell) bool
+	// MaxPeriod is the number of generations remembered, the longest period that can be found
+	MaxPeriod int
+
+	generation int
+	result     Result
+	history    map[uint64]observation
+	order      []uint64
+}
+
+type observation struct {
+	generation int
+	// the top left of the bounding box of the live cells
+	offset grid.Position
+}
+
+func NewDetector(alive func(cell grid.Cell) bool) *Detector {
+	d := &Detector{Alive: alive, MaxPeriod: DefaultMaxPeriod}
+	d.Reset()
+	return d
+}
+
+// Reset forgets the generations seen so far, e.g. after the plane was edited.
+func (d *Detector) Reset() {
+	d.generation = 0
+	d.result = Result{}
+	d.history = map[uint64]observation{}
+	d.order = nil
+}
+
+// Result returns the periodicity found so far.
+func (d *Detector) Result() Result {
+	return d.result
+}
+
+// Observe records the plane as the next generation and returns the periodicity found so far.
+func (d *Detector) Observe(plane grid.Plane) Result {
+	hash, offset, population := d.normalize(plane)
+	if seen, ok := d.history[hash]; ok {
+		result := Result{
+			Kind:         Oscillator,
+			Period:       d.generation - seen.generation,
+			Displacement: grid.Position{X: offset.X - seen.offset.X, Y: offset.Y - seen.offset.Y},
+			Generation:   d.generation,
+		}
+		switch {
+		case population == 0:
+			result.Kind = Dead
+		case result.Displacement != grid.Origin:
+			result.Kind = Spaceship
+		case result.Period == 1:
+			result.Kind = StillLife
+		}
+		if d.result.Kind != Unknown && d.result.Kind == result.Kind && d.result.Period == result.Period {
+			// still the same cycle
+			result.Generation = d.result.Generation
+		}
+		d.result = result
+	}
+
+	d.history[hash] = observation{d.generation, offset}
+	d.order = append(d.order, hash)
+	if len(d.order) > d.MaxPeriod {
+		oldest := d.order[0]
+		if d.history[oldest].generation == d.generation-len(d.order)+1 {
+			delete(d.history, oldest)
+		}
+		d.order = d.order[1:]
+	}
+	d.generation++
+	return d.result
+}
+
+// Stable observes the plane and returns whether the pattern has repeated, for use with engine.Engine.RunUntil to run
+// a pattern until it stabilizes.
+func (d *Detector) Stable(plane grid.Plane) bool {
+	return d.Observe(plane).Kind != Unknown
+}
+
+// normalize hashes the live cells of the plane relative to the top left of their bounding box.
+func (d *Detector) normalize(plane grid.Plane) (uint64, grid.Position, int) {
+	if board, ok := plane.(*bitlife.Board); ok && d.Alive(board.Alive) && !d.Alive(board.Dead) {
+		return normalizeBoard(board)
+	}
+	bounds := plane.Bounds()
+	live := []grid.Position{}
+	offset := bounds.Corner2
+	for y := bounds.Corner1.Y; y <= bounds.Corner2.Y; y++ {
+		for x := bounds.Corner1.X; x <= bounds.Corner2.X; x++ {
+			p := grid.Position{X: x, Y: y}
+			if d.Alive(plane.Get(p)) {
+				live = append(live, p)
+				if x < offset.X {
+					offset.X = x
+				}
+				if y < offset.Y {
+					offset.Y = y
+				}
+			}
+		}
+	}
+	if len(live) == 0 {
+		offset = grid.Origin
+	}
+
+	hash := fnv.New64a()
+	var buf [8]byte
+	for _, p := range live {
+		x, y := uint32(p.X-offset.X), uint32(p.Y-offset.Y)
+		buf[0], buf[1], buf[2], buf[3] = byte(x), byte(x>>8), byte(x>>16), byte(x>>24)
+		buf[4], buf[5], buf[6], buf[7] = byte(y), byte(y>>8), byte(y>>16), byte(y>>24)
+		hash.Write(buf[:])
+	}
+	return hash.Sum64(), offset, len(live)
+}
+
+// normalizeBoard is normalize for a bitboard, reading 64 cells at a time instead of getting every cell, so that
+// detecting repeats costs about as much as the step. The hashes differ from those of normalize, but a Detector is
+// only given one kind of plane.
+func normalizeBoard(board *bitlife.Board) (uint64, grid.Position, int) {
+	top, bottom, left, right := board.H, -1, board.W, -1
+	population := 0
+	for y := 0; y < board.H; y++ {
+		for i, word := range board.Row(y) {
+			if word == 0 {
+				continue
+			}
+			population += bits.OnesCount64(word)
+			if y < top {
+				top = y
+			}
+			bottom = y
+			if x := i*64 + bits.TrailingZeros64(word); x < left {
+				left = x
+			}
+			if x := i*64 + 63 - bits.LeadingZeros64(word); x > right {
+				right = x
+			}
+		}
+	}
+	hash := fnv.New64a()
+	if population == 0 {
+		return hash.Sum64(), grid.Origin, 0
+	}
+
+	// the rows of the bounding box, shifted to start at its left edge, after the number of words in each
+	words := (right-left)/64 + 1
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(words))
+	hash.Write(buf[:])
+	for y := top; y <= bottom; y++ {
+		row := board.Row(y)
+		for k := 0; k < words; k++ {
+			binary.LittleEndian.PutUint64(buf[:], wordAt(row, left+64*k))
+			hash.Write(buf[:])
+		}
+	}
+	return hash.Sum64(), grid.Position{X: left, Y: top}, population
+}
+
+// wordAt returns the 64 cells of a row of a bitboard from column x on, with the cells beyond the row dead.
+func wordAt(row []uint64, x int) uint64 {
+	i, shift := x/64, uint(x%64)
+	if i >= len(row) {
+		return 0
+	}
+	word := row[i] >> shift
+	if shift > 0 && i+1 < len(row) {
+		word |= row[i+1] << (64 - shift)
+	}
+	return word
+}
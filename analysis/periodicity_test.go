@@ -0,0 +1,155 @@
+package analysis
+
+import (
+	"github.com/jpbetz/cellularautomata/bitlife"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/nsf/termbox-go"
+	"math/rand"
+	"testing"
+)
+
+type cell bool
+
+func (c cell) Rune() rune                     { return ' ' }
+func (c cell) FgAttribute() termbox.Attribute { return termbox.ColorDefault }
+func (c cell) BgAttribute() termbox.Attribute { return termbox.ColorDefault }
+
+// life steps a bitlife.Board with Conway's rule.
+type life struct{}
+
+func (l life) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	return nil
+}
+
+func (l life) StepPlane(plane grid.Plane) []grid.Position {
+	plane.(*bitlife.Board).Step(bitlife.Conway)
+	return nil
+}
+
+func newLife(pattern []string) *engine.Engine {
+	board := bitlife.NewBoard(32, 32, cell(true), cell(false))
+	for y, row := range pattern {
+		for x, c := range row {
+			board.SetAlive(grid.Position{X: x + 8, Y: y + 8}, c == '*')
+		}
+	}
+	return &engine.Engine{Plane: board, UI: io.NullRenderer{}, Handler: life{}, PlaneHandler: life{}}
+}
+
+func isAlive(c grid.Cell) bool {
+	return c == cell(true)
+}
+
+func TestDetector(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern []string
+		result  Result
+	}{
+		{"empty", []string{}, Result{Kind: Dead, Period: 1, Generation: 1}},
+		{"block", []string{"**", "**"}, Result{Kind: StillLife, Period: 1, Generation: 1}},
+		{"blinker", []string{"***"}, Result{Kind: Oscillator, Period: 2, Generation: 2}},
+		{"toad", []string{".***", "***."}, Result{Kind: Oscillator, Period: 2, Generation: 2}},
+		{"glider", []string{".*.", "..*", "***"}, Result{Kind: Spaceship, Period: 4,
+			Displacement: grid.Position{X: 1, Y: 1}, Generation: 4}},
+		{"lwss", []string{".*..*", "*....", "*...*", "****."}, Result{Kind: Spaceship, Period: 4,
+			Displacement: grid.Position{X: -2, Y: 0}, Generation: 4}},
+		// dies out in 2 generations
+		{"domino", []string{"**"}, Result{Kind: Dead, Period: 1, Generation: 2}},
+		// becomes a blinker
+		{"tee", []string{"***", ".*."}, Result{Kind: Oscillator, Period: 2, Generation: 11}},
+	}
+	for _, c := range cases {
+		e := newLife(c.pattern)
+		d := NewDetector(isAlive)
+		if !e.RunUntil(d.Stable, 100) {
+			t.Errorf("Expected %s to stabilize within 100 generations", c.name)
+			continue
+		}
+		if d.Result() != c.result {
+			t.Errorf("Expected %s to be %+v but was %+v", c.name, c.result, d.Result())
+		}
+	}
+}
+
+func TestDetectorRunsOut(t *testing.T) {
+	// the r-pentomino takes 1103 generations to stabilize, on an unbounded plane
+	e := newLife([]string{".**", "**.", ".*."})
+	d := NewDetector(isAlive)
+	if e.RunUntil(d.Stable, 10) {
+		t.Errorf("Expected the r-pentomino not to stabilize within 10 generations but it was %s", d.Result())
+	}
+	if e.Generation != 10 {
+		t.Errorf("Expected 10 generations but ran %d", e.Generation)
+	}
+}
+
+func TestDetectorMaxPeriod(t *testing.T) {
+	e := newLife([]string{"***"})
+	d := NewDetector(isAlive)
+	d.MaxPeriod = 1
+	if e.RunUntil(d.Stable, 10) {
+		t.Errorf("Expected a blinker not to be found with a max period of 1 but it was %s", d.Result())
+	}
+}
+
+func TestDetectorAcrossWords(t *testing.T) {
+	// a glider flying across the boundary between the first two words of each row of the bitboard, and the same
+	// glider on a board of cells, which the detector reads a cell at a time
+	glider := []string{".*.", "..*", "***"}
+	board := bitlife.NewBoard(128, 16, cell(true), cell(false))
+	cells := grid.NewBasicBoard(128, 16)
+	cells.Initialize(cell(false))
+	for y, row := range glider {
+		for x, c := range row {
+			board.SetAlive(grid.Position{X: x + 60, Y: y + 2}, c == '*')
+			cells.Set(grid.Position{X: x + 60, Y: y + 2}, cell(c == '*'))
+		}
+	}
+	e := &engine.Engine{Plane: board, UI: io.NullRenderer{}, Handler: life{}, PlaneHandler: life{}}
+	d := NewDetector(isAlive)
+	if !e.RunUntil(d.Stable, 20) {
+		t.Fatalf("Expected the glider to be found within 20 generations")
+	}
+	expected := Result{Kind: Spaceship, Period: 4, Displacement: grid.Position{X: 1, Y: 1}, Generation: 4}
+	if d.Result() != expected {
+		t.Errorf("Expected the glider to be %+v but was %+v", expected, d.Result())
+	}
+	if _, offset, population := d.normalize(board); offset != (grid.Position{X: 61, Y: 3}) || population != 5 {
+		t.Errorf("Expected the glider at 61, 3 with 5 cells but found %d cells at %v", population, offset)
+	}
+	if _, offset, population := d.normalize(cells); offset != (grid.Position{X: 60, Y: 2}) || population != 5 {
+		t.Errorf("Expected the glider at 60, 2 with 5 cells on the board of cells but found %d cells at %v",
+			population, offset)
+	}
+}
+
+// BenchmarkBitboardStep shows the cost of detecting repeats every generation, as conway does on the bitboard
+// backend, next to the cost of the step.
+func BenchmarkBitboardStep(b *testing.B) {
+	for _, detect := range []bool{false, true} {
+		name := "step"
+		if detect {
+			name = "step and detect"
+		}
+		b.Run(name, func(b *testing.B) {
+			board := bitlife.NewBoard(512, 512, cell(true), cell(false))
+			random := rand.New(rand.NewSource(1))
+			for x := 0; x < 512; x++ {
+				for y := 0; y < 512; y++ {
+					board.SetAlive(grid.Position{X: x, Y: y}, random.Intn(2) == 0)
+				}
+			}
+			d := NewDetector(isAlive)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				board.Step(bitlife.Conway)
+				if detect {
+					d.Observe(board)
+				}
+			}
+		})
+	}
+}
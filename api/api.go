@@ -0,0 +1,435 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// StatesHandler may be implemented by an UpdateHandler to name the states of its cells, so that cells can be written
+// through the API, and read back by name instead of by rune. Cells of named states must be comparable.
+type StatesHandler interface {
+	States() map[string]grid.Cell
+}
+
+// SavingHandler may be implemented by an UpdateHandler whose command saves the board on io.Save, to tell whether it
+// can save the plane it runs on.
+type SavingHandler interface {
+	CanSave() bool
+}
+
+// EditedHandler may be implemented by an UpdateHandler to hear of cells written through the API, which does not go
+// through the input handling of the command, e.g. to forget what it found out about the board before. Edited is
+// called between two generations, after the cells are set.
+type EditedHandler interface {
+	Edited()
+}
+
+// Server controls the running simulation over HTTP. Commands that the UI also has, such as pausing, are sent as input
+// events, and edits of the plane are made with engine.Engine.Edit, so that both are serialized with the UI input and
+// with the clock.
+type Server struct {
+	Input chan<- io.InputEvent
+	// Engine returns the engine to act on, or nil while there is none
+	Engine func() *engine.Engine
+	// Addr is the address listened on, which requests must name as their host, or empty not to check the port
+	Addr string
+}
+
+// NewServer returns a server sending input events to the channel the UI sends its own to, and acting on the engine
+// of the running command.
+func NewServer(input chan<- io.InputEvent) *Server {
+	return &Server{Input: input, Engine: engine.Running}
+}
+
+// Status is the state of the simulation.
+type Status struct {
+	Playing      bool     `json:"playing"`
+	Generation   int      `json:"generation"`
+	ClockSpeedMs int64    `json:"clock_speed_ms"`
+	Width        int      `json:"width"`
+	Height       int      `json:"height"`
+	States       []string `json:"states,omitempty"`
+	Err          string   `json:"error,omitempty"`
+}
+
+// Speed is the body of a request to change the clock speed.
+type Speed struct {
+	ClockSpeedMs int64 `json:"clock_speed_ms"`
+}
+
+// Click is the body of a request to click a cell, as if it was clicked in the UI.
+type Click struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Key is the body of a request to press a key, as if it was pressed in the UI.
+type Key struct {
+	Key string `json:"key"`
+}
+
+// Handler serves the endpoints of the API:
+//
+//	GET  /status              the Status
+//	POST /pause, /resume      pause or resume the clock, doing nothing if it already is
+//	POST /step?n=N            step N generations, 1 by default, while paused
+//	PUT  /speed               set the clock speed from a Speed
+//	GET  /cells?x=&y=&w=&h=   the Region of cells, by default the whole board
+//	PUT  /cells               write a Region of cells, by state name
+//	GET  /board               the whole board as a Region
+//	GET  /board.png?scale=N   the whole board as a PNG of N by N pixels per cell, 8 by default
+//	GET  /pattern?x=&y=&w=&h= the cells as an RLE pattern, by default the whole board
+//	PUT  /pattern?x=&y=       load an RLE pattern with its top left corner at x, y, by default the top left of the board
+//	POST /click, /key         send a Click or a Key, as if from the UI
+//	POST /save                save the board to the file of the command, if it has one, as if from the UI
+//
+// JSON bodies must be sent with the content type application/json. Requests from web pages of other origins, and
+// requests for other hosts than the address of the API, are refused.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.get(s.status))
+	mux.HandleFunc("/pause", s.post(func(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+		s.setPlaying(w, e, false)
+	}))
+	mux.HandleFunc("/resume", s.post(func(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+		s.setPlaying(w, e, true)
+	}))
+	mux.HandleFunc("/step", s.post(s.step))
+	mux.HandleFunc("/speed", s.handle([]string{http.MethodPut, http.MethodPost}, s.speed))
+	mux.HandleFunc("/cells", s.handle([]string{http.MethodGet, http.MethodPut}, s.cells))
+	mux.HandleFunc("/board", s.get(func(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+		writeJSON(w, readRegion(e, e.Plane.Bounds()))
+	}))
+	mux.HandleFunc("/board.png", s.get(s.boardPNG))
+	mux.HandleFunc("/click", s.post(s.click))
+	mux.HandleFunc("/key", s.post(s.key))
+	mux.HandleFunc("/pattern", s.handle([]string{http.MethodGet, http.MethodPut}, s.pattern))
+	mux.HandleFunc("/save", s.post(func(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+		if saving, ok := e.Handler.(SavingHandler); !ok || !saving.CanSave() {
+			httpError(w, http.StatusNotImplemented, "this simulation can't save")
+			return
+		}
+		s.send(w, io.Save{})
+	}))
+	return mux
+}
+
+type engineHandlerFunc func(w http.ResponseWriter, r *http.Request, e *engine.Engine)
+
+// handle adapts an engineHandlerFunc to the methods it accepts, answering other methods, requests that may come from
+// a web page of another site, and requests made before there is an engine, with an error.
+func (s *Server) handle(methods []string, f engineHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			httpError(w, http.StatusForbidden, "host %s is not the address of the API", r.Host)
+			return
+		}
+		// as for the WebSocket of the web renderer, pages may only use the API they are served with
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				httpError(w, http.StatusForbidden, "origin %s may not use the API at %s", origin, r.Host)
+				return
+			}
+		}
+		allowed := false
+		for _, method := range methods {
+			allowed = allowed || r.Method == method
+		}
+		if !allowed {
+			w.Header().Set("Allow", fmt.Sprint(methods))
+			httpError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+			return
+		}
+		e := s.Engine()
+		if e == nil {
+			httpError(w, http.StatusServiceUnavailable, "no simulation is running")
+			return
+		}
+		f(w, r, e)
+	}
+}
+
+// allowedHost returns whether the host of a request names the address listened on. The names of other sites, which a
+// page could resolve to the API by DNS rebinding, are refused, but localhost and IP addresses can't be rebound.
+func (s *Server) allowedHost(host string) bool {
+	if host == s.Addr {
+		return true
+	}
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname, port = host, ""
+	}
+	if s.Addr != "" {
+		if _, listenPort, err := net.SplitHostPort(s.Addr); err != nil || port != listenPort {
+			return false
+		}
+	}
+	return hostname == "localhost" || net.ParseIP(hostname) != nil
+}
+
+// readJSON decodes the body of a request, answering with an error if it isn't JSON. Requiring the content type keeps
+// web pages of other sites from sending bodies without asking first, as they may for plain text and forms.
+func readJSON(w http.ResponseWriter, r *http.Request, value interface{}, what string) bool {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil ||
+		mediaType != "application/json" {
+		httpError(w, http.StatusUnsupportedMediaType, "the %s must be sent as application/json", what)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(value); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid %s: %v", what, err)
+		return false
+	}
+	return true
+}
+
+func (s *Server) get(f engineHandlerFunc) http.HandlerFunc {
+	return s.handle([]string{http.MethodGet}, f)
+}
+
+func (s *Server) post(f engineHandlerFunc) http.HandlerFunc {
+	return s.handle([]string{http.MethodPost}, f)
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+	var status Status
+	e.Edit(func() {
+		bounds := e.Plane.Bounds()
+		status = Status{
+			Playing:      e.Playing,
+			Generation:   e.Generation,
+			ClockSpeedMs: int64(e.ClockSpeed / time.Millisecond),
+			Width:        bounds.Corner2.X - bounds.Corner1.X + 1,
+			Height:       bounds.Corner2.Y - bounds.Corner1.Y + 1,
+		}
+		if e.Err != nil {
+			status.Err = e.Err.Error()
+		}
+	})
+	if states, ok := e.Handler.(StatesHandler); ok {
+		status.States = stateNames(states.States())
+	}
+	writeJSON(w, status)
+}
+
+// setPlaying pauses or resumes with a Pause event, since the command owns the clock, unless it is already done.
+func (s *Server) setPlaying(w http.ResponseWriter, e *engine.Engine, playing bool) {
+	var current bool
+	e.Edit(func() {
+		current = e.Playing
+	})
+	if current == playing {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.send(w, io.Pause{})
+}
+
+func (s *Server) step(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+	n := 1
+	if value := r.URL.Query().Get("n"); value != "" {
+		var err error
+		if n, err = strconv.Atoi(value); err != nil || n < 1 {
+			httpError(w, http.StatusBadRequest, "n must be a positive number of generations")
+			return
+		}
+	}
+	playing := false
+	e.Edit(func() {
+		if playing = e.Playing; playing {
+			return
+		}
+		for i := 0; i < n && e.Err == nil; i++ {
+			e.Step()
+		}
+	})
+	if playing {
+		httpError(w, http.StatusConflict, "pause the simulation before stepping it")
+		return
+	}
+	s.status(w, r, e)
+}
+
+func (s *Server) speed(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+	var speed Speed
+	if !readJSON(w, r, &speed, "speed") {
+		return
+	}
+	if speed.ClockSpeedMs < 1 {
+		httpError(w, http.StatusBadRequest, "clock_speed_ms must be at least 1")
+		return
+	}
+	e.Edit(func() {
+		e.SetClockSpeed(time.Duration(speed.ClockSpeedMs) * time.Millisecond)
+	})
+	s.status(w, r, e)
+}
+
+func (s *Server) cells(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+	if r.Method == http.MethodPut {
+		var region Region
+		if !readJSON(w, r, &region, "region") {
+			return
+		}
+		states, ok := e.Handler.(StatesHandler)
+		if !ok {
+			httpError(w, http.StatusNotImplemented, "this simulation has no named states to write")
+			return
+		}
+		if err := writeRegion(e, states.States(), region); err != nil {
+			httpError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	rect, err := queryRegion(r, e.Plane.Bounds())
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	writeJSON(w, readRegion(e, rect))
+}
+
+// queryRegion returns the rectangle given by the x, y, w and h parameters of the request, which default to the whole
+// board.
+func queryRegion(r *http.Request, bounds grid.Rectangle) (grid.Rectangle, error) {
+	query := r.URL.Query()
+	rect := grid.Rectangle{Corner1: bounds.Corner1}
+	width, height := bounds.Corner2.X-bounds.Corner1.X+1, bounds.Corner2.Y-bounds.Corner1.Y+1
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"x", &rect.Corner1.X}, {"y", &rect.Corner1.Y}, {"w", &width}, {"h", &height}} {
+		if value := query.Get(param.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return rect, fmt.Errorf("%s must be a number", param.name)
+			}
+			*param.value = n
+		}
+	}
+	rect.Corner2 = grid.Position{X: rect.Corner1.X + width - 1, Y: rect.Corner1.Y + height - 1}
+	if width < 1 || height < 1 || !bounds.Contains(rect.Corner1) || !bounds.Contains(rect.Corner2) {
+		return rect, fmt.Errorf("region must be within the board")
+	}
+	return rect, nil
+}
+
+func (s *Server) boardPNG(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+	scale := 8
+	if value := r.URL.Query().Get("scale"); value != "" {
+		var err error
+		if scale, err = strconv.Atoi(value); err != nil || scale < 1 || scale > 64 {
+			httpError(w, http.StatusBadRequest, "scale must be a number of pixels from 1 to 64")
+			return
+		}
+	}
+	var img *image.RGBA
+	e.Edit(func() {
+		bounds := e.Plane.Bounds()
+		width, height := bounds.Corner2.X-bounds.Corner1.X+1, bounds.Corner2.Y-bounds.Corner1.Y+1
+		img = image.NewRGBA(image.Rect(0, 0, width*scale, height*scale))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				c := grid.CellColor(e.Plane.Get(grid.Position{X: bounds.Corner1.X + x, Y: bounds.Corner1.Y + y}))
+				rgba := color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}
+				for py := y * scale; py < (y+1)*scale; py++ {
+					for px := x * scale; px < (x+1)*scale; px++ {
+						img.SetRGBA(px, py, rgba)
+					}
+				}
+			}
+		}
+	})
+	w.Header().Set("Content-Type", "image/png")
+	if err := png.Encode(w, img); err != nil {
+		log.Printf("Failed to write board image: %v\n", err)
+	}
+}
+
+func (s *Server) click(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+	var click Click
+	if !readJSON(w, r, &click, "click") {
+		return
+	}
+	position := grid.Position{X: click.X, Y: click.Y}
+	if !e.Plane.Bounds().Contains(position) {
+		httpError(w, http.StatusBadRequest, "cell %d, %d is not on the board", click.X, click.Y)
+		return
+	}
+	s.send(w, io.Click{Position: position})
+}
+
+func (s *Server) key(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+	var key Key
+	if !readJSON(w, r, &key, "key") {
+		return
+	}
+	runes := []rune(key.Key)
+	if len(runes) != 1 {
+		httpError(w, http.StatusBadRequest, "key must be a single character")
+		return
+	}
+	s.send(w, io.Key{Ch: runes[0]})
+}
+
+// send queues the event behind the input of the UI. The command handles it asynchronously, so the response only
+// tells that it was accepted.
+func (s *Server) send(w http.ResponseWriter, event io.InputEvent) {
+	select {
+	case s.Input <- event:
+		w.WriteHeader(http.StatusAccepted)
+	case <-time.After(time.Second):
+		httpError(w, http.StatusServiceUnavailable, "input queue is full")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Failed to write response: %v\n", err)
+	}
+}
+
+func httpError(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// Serve listens on the address, e.g. localhost:7070, and serves the API of a NewServer in the background. It returns
+// the address listened on.
+func Serve(addr string, input chan<- io.InputEvent) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := NewServer(input)
+	// the port is the one chosen if the address has port 0
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	server.Addr = net.JoinHostPort(host, port)
+	go func() {
+		if err := http.Serve(listener, server.Handler()); err != nil {
+			log.Printf("API server stopped: %v\n", err)
+		}
+	}()
+	return listener.Addr(), nil
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/nsf/termbox-go"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type cell bool
+
+func (c cell) Rune() rune {
+	if c {
+		return '█'
+	}
+	return ' '
+}
+
+func (c cell) FgAttribute() termbox.Attribute {
+	if c {
+		return termbox.ColorBlue
+	}
+	return termbox.ColorDefault
+}
+
+func (c cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+// still never changes a cell, so that the generations can be counted on a board that stays as it was written.
+type still struct {
+	saving bool
+	// edits counts the calls of Edited, if it is set
+	edits *int
+}
+
+func (h still) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	return nil
+}
+
+func (h still) States() map[string]grid.Cell {
+	return map[string]grid.Cell{"on": cell(true), "off": cell(false)}
+}
+
+func (h still) PatternStates() []grid.Cell {
+	return []grid.Cell{cell(false), cell(true)}
+}
+
+func (h still) CanSave() bool {
+	return h.saving
+}
+
+func (h still) Edited() {
+	if h.edits != nil {
+		*h.edits++
+	}
+}
+
+func newTestServer() (*httptest.Server, *engine.Engine, chan io.InputEvent) {
+	board := grid.NewBasicBoard(4, 3)
+	board.Initialize(cell(false))
+	e := &engine.Engine{Plane: board, UI: io.NullRenderer{}, Handler: still{saving: true}, ClockSpeed: 100 * time.Millisecond}
+	input := make(chan io.InputEvent, 1)
+	server := &Server{Input: input, Engine: func() *engine.Engine { return e }}
+	return httptest.NewServer(server.Handler()), e, input
+}
+
+func request(t *testing.T, method, url, body string, expectedCode int, result interface{}) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(body, "{") {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expectedCode {
+		t.Fatalf("Expected %s %s to answer %d but it answered %d", method, url, expectedCode, resp.StatusCode)
+	}
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCells(t *testing.T) {
+	server, e, _ := newTestServer()
+	defer server.Close()
+
+	var status Status
+	request(t, "GET", server.URL+"/status", "", http.StatusOK, &status)
+	if status.Width != 4 || status.Height != 3 || !reflect.DeepEqual(status.States, []string{"off", "on"}) {
+		t.Errorf("Expected a 4x3 board with states off and on but the status was %+v", status)
+	}
+
+	request(t, "PUT", server.URL+"/cells", `{"x": 1, "y": 1, "cells": [["on", "off", "on"], ["", "on"]]}`,
+		http.StatusNoContent, nil)
+	if e.Plane.Get(grid.Position{X: 3, Y: 1}) != cell(true) || e.Plane.Get(grid.Position{X: 2, Y: 2}) != cell(true) {
+		t.Errorf("Expected the written cells to be on")
+	}
+	var region Region
+	request(t, "GET", server.URL+"/cells?x=1&y=1&w=2&h=2", "", http.StatusOK, &region)
+	expected := [][]string{{"on", "off"}, {"off", "on"}}
+	if region.Width != 2 || region.Height != 2 || !reflect.DeepEqual(region.Cells, expected) {
+		t.Errorf("Expected the region to be %v but was %+v", expected, region)
+	}
+
+	request(t, "PUT", server.URL+"/cells", `{"x": 0, "y": 0, "cells": [["on", "maybe"]]}`, http.StatusBadRequest, nil)
+	request(t, "PUT", server.URL+"/cells", `{"x": 3, "y": 0, "cells": [["on", "on"]]}`, http.StatusBadRequest, nil)
+	if e.Plane.Get(grid.Position{X: 0, Y: 0}) != cell(false) || e.Plane.Get(grid.Position{X: 3, Y: 0}) != cell(false) {
+		t.Errorf("Expected invalid regions not to be written at all")
+	}
+	request(t, "GET", server.URL+"/cells?x=3&w=2", "", http.StatusBadRequest, nil)
+
+	var board Region
+	request(t, "GET", server.URL+"/board", "", http.StatusOK, &board)
+	if len(board.Cells) != 3 || len(board.Cells[0]) != 4 || board.Cells[1][3] != "on" {
+		t.Errorf("Expected the whole board but was %+v", board)
+	}
+}
+
+func TestBoardPNG(t *testing.T) {
+	server, e, _ := newTestServer()
+	defer server.Close()
+	e.Plane.Set(grid.Position{X: 1, Y: 2}, cell(true))
+
+	resp, err := http.Get(server.URL + "/board.png?scale=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != 8 || size.Y != 6 {
+		t.Fatalf("Expected an image of 8x6 pixels but it was %v", size)
+	}
+	blue := grid.AttributeColor(termbox.ColorBlue)
+	if r, g, b, _ := img.At(3, 5).RGBA(); uint8(r>>8) != blue.R || uint8(g>>8) != blue.G || uint8(b>>8) != blue.B {
+		t.Errorf("Expected the pixels of the live cell to be blue but were %d, %d, %d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestControl(t *testing.T) {
+	server, e, input := newTestServer()
+	defer server.Close()
+
+	var status Status
+	request(t, "POST", server.URL+"/step?n=3", "", http.StatusOK, &status)
+	if status.Generation != 3 || e.Generation != 3 {
+		t.Errorf("Expected 3 generations to be stepped but the status was %+v", status)
+	}
+	request(t, "PUT", server.URL+"/speed", `{"clock_speed_ms": 40}`, http.StatusOK, &status)
+	if e.ClockSpeed != 40*time.Millisecond || status.ClockSpeedMs != 40 {
+		t.Errorf("Expected the clock speed to be 40ms but was %v", e.ClockSpeed)
+	}
+
+	// the engine is paused, so only resuming sends a Pause, which the command toggles the clock with
+	request(t, "POST", server.URL+"/pause", "", http.StatusNoContent, nil)
+	request(t, "POST", server.URL+"/resume", "", http.StatusAccepted, nil)
+	if event := <-input; event != (io.Pause{}) {
+		t.Errorf("Expected resuming to send a Pause but it sent %v", event)
+	}
+	e.Playing = true
+	request(t, "POST", server.URL+"/step", "", http.StatusConflict, nil)
+
+	request(t, "POST", server.URL+"/click", `{"x": 2, "y": 1}`, http.StatusAccepted, nil)
+	if event := <-input; event != (io.Click{Position: grid.Position{X: 2, Y: 1}}) {
+		t.Errorf("Expected a click on 2, 1 but it sent %v", event)
+	}
+	request(t, "POST", server.URL+"/key", `{"key": "c"}`, http.StatusAccepted, nil)
+	if event := <-input; event != (io.Key{Ch: 'c'}) {
+		t.Errorf("Expected the key c but it sent %v", event)
+	}
+	request(t, "POST", server.URL+"/save", "", http.StatusAccepted, nil)
+	if event := <-input; event != (io.Save{}) {
+		t.Errorf("Expected a save but it sent %v", event)
+	}
+	request(t, "GET", server.URL+"/save", "", http.StatusMethodNotAllowed, nil)
+	e.Handler = still{}
+	request(t, "POST", server.URL+"/save", "", http.StatusNotImplemented, nil)
+	select {
+	case event := <-input:
+		t.Errorf("Expected a command that can't save not to be sent a save but it sent %v", event)
+	default:
+	}
+}
+
+func TestRLE(t *testing.T) {
+	glider := "#N Glider\nx = 3, y = 3, rule = B3/S23\nbob$2bo$3o!\n"
+	rows, err := ReadRLE(strings.NewReader(glider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]int{{0, 1, 0}, {0, 0, 1}, {1, 1, 1}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected the glider %v but read %v", expected, rows)
+	}
+	var out strings.Builder
+	if err := WriteRLE(&out, expected, 2); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "x = 3, y = 3\nbo$2bo$3o!\n" {
+		t.Errorf("Expected the glider to be written without its trailing dead cells but was %q", out.String())
+	}
+
+	rows, err = ReadRLE(strings.NewReader("x = 4, y = 4\n.A2$2B$2.C!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = [][]int{{0, 1, 0, 0}, {0, 0, 0, 0}, {2, 2, 0, 0}, {0, 0, 3, 0}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected the multistate pattern %v padded to its width but read %v", expected, rows)
+	}
+	out.Reset()
+	if err := WriteRLE(&out, expected, 4); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "x = 4, y = 4\n.A2$2B$2.C!\n" {
+		t.Errorf("Expected the multistate pattern to be written with its tags but was %q", out.String())
+	}
+
+	for _, invalid := range []string{"bo$o!", "x = 2, y = 1\nbz!", "x = 2, y = 1\nbo", "x = 100000, y = 100000\n!",
+		"x = 1, y = 1\n999999o999999o!"} {
+		if _, err := ReadRLE(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestPattern(t *testing.T) {
+	server, e, _ := newTestServer()
+	defer server.Close()
+	e.Plane.Set(grid.Position{X: 2, Y: 0}, cell(true))
+	edits := 0
+	e.Handler = still{saving: true, edits: &edits}
+
+	request(t, "PUT", server.URL+"/pattern?x=1&y=0", "x = 3, y = 2\nbo$obo!", http.StatusNoContent, nil)
+	if edits != 1 {
+		t.Errorf("Expected the handler to be told of the edit once but it was told %d times", edits)
+	}
+	// the dead cells of the pattern are written too
+	for position, expected := range map[grid.Position]cell{{X: 2, Y: 0}: true, {X: 1, Y: 1}: true,
+		{X: 3, Y: 1}: true, {X: 1, Y: 0}: false, {X: 3, Y: 0}: false, {X: 2, Y: 1}: false} {
+		if e.Plane.Get(position) != expected {
+			t.Errorf("Expected the cell at %v to be %v after loading the pattern", position, expected)
+		}
+	}
+	request(t, "PUT", server.URL+"/pattern?x=2", "x = 3, y = 1\n3o!", http.StatusBadRequest, nil)
+	request(t, "PUT", server.URL+"/pattern", "x = 1, y = 1\nB!", http.StatusBadRequest, nil)
+	if e.Plane.Get(grid.Position{X: 0, Y: 0}) != cell(false) {
+		t.Errorf("Expected invalid patterns not to be loaded at all")
+	}
+
+	resp, err := http.Get(server.URL + "/pattern?x=1&y=0&w=3&h=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	rows, err := ReadRLE(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]int{{0, 1, 0}, {1, 0, 1}, {0, 0, 0}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected the saved pattern to be %v but was %v", expected, rows)
+	}
+}
+
+func TestRefusesOtherSites(t *testing.T) {
+	server, _, input := newTestServer()
+	defer server.Close()
+
+	for name, header := range map[string][2]string{
+		"a page of another origin": {"Origin", "http://example.com"},
+		"a rebound host name":      {"Host", "example.com" + strings.TrimPrefix(server.URL, "http://127.0.0.1")},
+		"a body that isn't JSON":   {"Content-Type", "text/plain"},
+	} {
+		req, err := http.NewRequest("POST", server.URL+"/click", strings.NewReader(`{"x": 1, "y": 1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if header[0] == "Host" {
+			req.Host = header[1]
+		} else {
+			req.Header.Set(header[0], header[1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 400 {
+			t.Errorf("Expected a click from %s to be refused but it answered %s", name, resp.Status)
+		}
+	}
+	select {
+	case event := <-input:
+		t.Errorf("Expected no input to be sent but it sent %v", event)
+	default:
+	}
+
+	s := &Server{Addr: "localhost:7070"}
+	for host, expected := range map[string]bool{"localhost:7070": true, "127.0.0.1:7070": true, "[::1]:7070": true,
+		"localhost:8080": false, "example.com:7070": false} {
+		if s.allowedHost(host) != expected {
+			t.Errorf("Expected the host %s to be allowed %t", host, expected)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"sort"
+)
+
+// Region is a rectangle of cells with its top left corner at X, Y, as rows of state names. Cells without a named
+// state are read as their rune. Written regions may have rows of different lengths, and an empty name leaves the cell
+// as it is, so that patterns other than rectangles can be loaded.
+type Region struct {
+	Generation int        `json:"generation"`
+	X          int        `json:"x"`
+	Y          int        `json:"y"`
+	Width      int        `json:"width"`
+	Height     int        `json:"height"`
+	Cells      [][]string `json:"cells"`
+}
+
+// cellName returns the name of the state of the cell, or its rune if the state has no name.
+func cellName(names map[grid.Cell]string, cell grid.Cell) string {
+	// cells without named states need not be comparable, so they must not be looked up
+	if len(names) > 0 {
+		if name, ok := names[cell]; ok {
+			return name
+		}
+	}
+	return string(cell.Rune())
+}
+
+func readRegion(e *engine.Engine, rect grid.Rectangle) Region {
+	names := map[grid.Cell]string{}
+	if handler, ok := e.Handler.(StatesHandler); ok {
+		for name, cell := range handler.States() {
+			names[cell] = name
+		}
+	}
+	region := Region{
+		X:      rect.Corner1.X,
+		Y:      rect.Corner1.Y,
+		Width:  rect.Corner2.X - rect.Corner1.X + 1,
+		Height: rect.Corner2.Y - rect.Corner1.Y + 1,
+	}
+	var cells [][]grid.Cell
+	region.Generation, cells = readCells(e, rect)
+	for _, cellRow := range cells {
+		row := make([]string, 0, len(cellRow))
+		for _, cell := range cellRow {
+			row = append(row, cellName(names, cell))
+		}
+		region.Cells = append(region.Cells, row)
+	}
+	return region
+}
+
+// readCells returns the generation and the rows of cells of the rectangle, read between two generations.
+func readCells(e *engine.Engine, rect grid.Rectangle) (int, [][]grid.Cell) {
+	var generation int
+	cells := [][]grid.Cell{}
+	e.Edit(func() {
+		generation = e.Generation
+		for y := rect.Corner1.Y; y <= rect.Corner2.Y; y++ {
+			row := make([]grid.Cell, 0, rect.Corner2.X-rect.Corner1.X+1)
+			for x := rect.Corner1.X; x <= rect.Corner2.X; x++ {
+				row = append(row, e.Plane.Get(grid.Position{X: x, Y: y}))
+			}
+			cells = append(cells, row)
+		}
+	})
+	return generation, cells
+}
+
+// writeRegion sets the cells of the region, all of them or none if any is unknown or off the board.
+func writeRegion(e *engine.Engine, states map[string]grid.Cell, region Region) error {
+	bounds := e.Plane.Bounds()
+	updates := []engine.CellUpdate{}
+	for j, row := range region.Cells {
+		for i, name := range row {
+			if name == "" {
+				continue
+			}
+			cell, ok := states[name]
+			if !ok {
+				return fmt.Errorf("unknown state %q, expected one of %v", name, stateNames(states))
+			}
+			position := grid.Position{X: region.X + i, Y: region.Y + j}
+			if !bounds.Contains(position) {
+				return fmt.Errorf("cell %d, %d is not on the board", position.X, position.Y)
+			}
+			updates = append(updates, engine.CellUpdate{State: cell, Position: position})
+		}
+	}
+	apply(e, updates)
+	return nil
+}
+
+// apply sets the cells of the updates between two generations, tells the handler if it listens, and draws them.
+func apply(e *engine.Engine, updates []engine.CellUpdate) {
+	e.Edit(func() {
+		for _, update := range updates {
+			e.Set(update.Position, update.State)
+		}
+		if edited, ok := e.Handler.(EditedHandler); ok {
+			edited.Edited()
+		}
+		e.UI.Draw()
+	})
+}
+
+// stateNames returns the names of the states in order.
+func stateNames(states map[string]grid.Cell) []string {
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
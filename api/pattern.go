@@ -0,0 +1,313 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	goio "io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PatternHandler may be implemented by an UpdateHandler to number its states in the order of Golly, so that patterns
+// can be loaded and saved as RLE. State 0 is the background, which the cells a pattern leaves out are set to. The cells
+// of the states must be comparable.
+type PatternHandler interface {
+	PatternStates() []grid.Cell
+}
+
+// PatternCellHandler may be implemented by a PatternHandler whose cells hold more than their state, such as how long a
+// fire has left to burn, to number the cells that are not equal to any of the PatternStates. It returns -1 for cells
+// with no state.
+type PatternCellHandler interface {
+	PatternState(cell grid.Cell) int
+}
+
+// maxPatternSize is the largest RLE file loaded, which is plenty for any board that fits on a screen.
+const maxPatternSize = 1 << 20
+
+// ReadRLE reads a pattern in Golly's run length encoded format and returns its rows of state numbers, all as wide as
+// the pattern. Background cells are b or ., live cells are o, and the states of multistate patterns are A to X for 1
+// to 24. Each is preceded by an optional count, rows are ended by $ and the pattern by !.
+func ReadRLE(r goio.Reader) ([][]int, error) {
+	scanner := bufio.NewScanner(r)
+	width, height := -1, -1
+	var body strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case width < 0:
+			var err error
+			if width, height, err = readRLEHeader(line); err != nil {
+				return nil, err
+			}
+		default:
+			body.WriteString(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if width < 0 {
+		return nil, fmt.Errorf("RLE pattern has no x = W, y = H header")
+	}
+
+	rows := [][]int{{}}
+	// count is the pending run length, and total the cells and rows read, which are limited like the header
+	count, total := 0, 0
+	repeat := func() int {
+		n := count
+		if n == 0 {
+			n = 1
+		}
+		count = 0
+		total += n
+		return n
+	}
+	for _, c := range body.String() {
+		switch {
+		case c >= '0' && c <= '9':
+			count = count*10 + int(c-'0')
+			if count > maxPatternSize {
+				return nil, fmt.Errorf("RLE pattern has more than %d cells", maxPatternSize)
+			}
+		case c == 'b' || c == '.' || c == 'o' || c >= 'A' && c <= 'X':
+			state := 0
+			if c == 'o' {
+				state = 1
+			} else if c >= 'A' && c <= 'X' {
+				state = int(c-'A') + 1
+			}
+			n := repeat()
+			if total > maxPatternSize {
+				return nil, fmt.Errorf("RLE pattern has more than %d cells", maxPatternSize)
+			}
+			for ; n > 0; n-- {
+				rows[len(rows)-1] = append(rows[len(rows)-1], state)
+			}
+		case c == '$':
+			n := repeat()
+			if total > maxPatternSize {
+				return nil, fmt.Errorf("RLE pattern has more than %d cells", maxPatternSize)
+			}
+			for ; n > 0; n-- {
+				rows = append(rows, []int{})
+			}
+		case c == '!':
+			return padRows(rows, width, height), nil
+		case c == ' ' || c == '\t':
+		default:
+			return nil, fmt.Errorf("RLE pattern has unsupported character %q", c)
+		}
+	}
+	return nil, fmt.Errorf("RLE pattern must end with !")
+}
+
+// readRLEHeader reads the width and height of a pattern from a header such as x = 3, y = 3, rule = B3/S23.
+func readRLEHeader(line string) (int, int, error) {
+	width, height := -1, -1
+	for _, field := range strings.Split(line, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return 0, 0, fmt.Errorf("invalid RLE header %q", line)
+		}
+		var value *int
+		switch strings.TrimSpace(parts[0]) {
+		case "x":
+			value = &width
+		case "y":
+			value = &height
+		default:
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid RLE header %q", line)
+		}
+		*value = n
+	}
+	if width < 0 || height < 0 {
+		return 0, 0, fmt.Errorf("RLE header %q must give x and y", line)
+	}
+	if width > maxPatternSize || height > maxPatternSize || width*height > maxPatternSize {
+		return 0, 0, fmt.Errorf("RLE pattern of %dx%d cells is too big", width, height)
+	}
+	return width, height, nil
+}
+
+// padRows fills the rows with background cells up to the size of the header, or of the longest row if it is bigger.
+func padRows(rows [][]int, width, height int) [][]int {
+	for len(rows) < height {
+		rows = append(rows, []int{})
+	}
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, 0)
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// WriteRLE writes the rows of state numbers of a pattern in Golly's run length encoded format, see ReadRLE. Patterns
+// with more than two states use the multistate tags.
+func WriteRLE(w goio.Writer, rows [][]int, states int) error {
+	width := 0
+	if len(rows) > 0 {
+		width = len(rows[0])
+	}
+	tag := func(state int) string {
+		if states <= 2 {
+			return []string{"b", "o"}[state]
+		}
+		if state == 0 {
+			return "."
+		}
+		return string(rune('A' + state - 1))
+	}
+	run := func(n int, t string) string {
+		if n == 1 {
+			return t
+		}
+		return strconv.Itoa(n) + t
+	}
+
+	// runs of background cells at the ends of rows, and of empty rows at the end, are left out
+	tokens := []string{}
+	endedRows := 0
+	for _, row := range rows {
+		end := len(row)
+		for end > 0 && row[end-1] == 0 {
+			end--
+		}
+		if end == 0 {
+			endedRows++
+			continue
+		}
+		if endedRows > 0 {
+			tokens = append(tokens, run(endedRows, "$"))
+		}
+		endedRows = 1
+		for i := 0; i < end; {
+			j := i
+			for j < end && row[j] == row[i] {
+				j++
+			}
+			tokens = append(tokens, run(j-i, tag(row[i])))
+			i = j
+		}
+	}
+	tokens = append(tokens, "!")
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "x = %d, y = %d\n", width, len(rows))
+	line := 0
+	for _, token := range tokens {
+		// Golly keeps lines within 70 characters
+		if line+len(token) > 70 {
+			out.WriteString("\n")
+			line = 0
+		}
+		out.WriteString(token)
+		line += len(token)
+	}
+	out.WriteString("\n")
+	return out.Flush()
+}
+
+// pattern saves the region given by the query as RLE on GET, and loads an RLE pattern with its top left corner at the
+// x and y of the query on PUT.
+func (s *Server) pattern(w http.ResponseWriter, r *http.Request, e *engine.Engine) {
+	handler, ok := e.Handler.(PatternHandler)
+	if !ok {
+		httpError(w, http.StatusNotImplemented, "this simulation has no numbered states for patterns")
+		return
+	}
+	states := handler.PatternStates()
+	bounds := e.Plane.Bounds()
+	if r.Method == http.MethodPut {
+		corner := bounds.Corner1
+		for _, param := range []struct {
+			name  string
+			value *int
+		}{{"x", &corner.X}, {"y", &corner.Y}} {
+			if value := r.URL.Query().Get(param.name); value != "" {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					httpError(w, http.StatusBadRequest, "%s must be a number", param.name)
+					return
+				}
+				*param.value = n
+			}
+		}
+		rows, err := ReadRLE(goio.LimitReader(r.Body, maxPatternSize))
+		if err != nil {
+			httpError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		updates := []engine.CellUpdate{}
+		for j, row := range rows {
+			for i, state := range row {
+				if state >= len(states) {
+					httpError(w, http.StatusBadRequest, "pattern has state %d, but this simulation has %d states",
+						state, len(states))
+					return
+				}
+				position := grid.Position{X: corner.X + i, Y: corner.Y + j}
+				if !bounds.Contains(position) {
+					httpError(w, http.StatusBadRequest, "pattern does not fit on the board at %d, %d", corner.X,
+						corner.Y)
+					return
+				}
+				updates = append(updates, engine.CellUpdate{State: states[state], Position: position})
+			}
+		}
+		apply(e, updates)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	rect, err := queryRegion(r, bounds)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	_, cells := readCells(e, rect)
+	rows := make([][]int, len(cells))
+	for j, cellRow := range cells {
+		for i, cell := range cellRow {
+			state := stateNumber(states, cell)
+			if numbered, ok := handler.(PatternCellHandler); ok && state < 0 {
+				state = numbered.PatternState(cell)
+			}
+			if state < 0 || state >= len(states) {
+				httpError(w, http.StatusInternalServerError, "cell %d, %d has no pattern state",
+					rect.Corner1.X+i, rect.Corner1.Y+j)
+				return
+			}
+			rows[j] = append(rows[j], state)
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := WriteRLE(w, rows, len(states)); err != nil {
+		httpError(w, http.StatusInternalServerError, "%v", err)
+	}
+}
+
+// stateNumber returns the number of the state of the cell, or -1 if it has none.
+func stateNumber(states []grid.Cell, cell grid.Cell) int {
+	for i, state := range states {
+		if state == cell {
+			return i
+		}
+	}
+	return -1
+}
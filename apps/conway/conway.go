@@ -1,13 +1,19 @@
 package conway
 
 import (
+	"flag"
 	"fmt"
+	"github.com/jpbetz/cellularautomata/analysis"
+	"github.com/jpbetz/cellularautomata/bitlife"
 	"github.com/jpbetz/cellularautomata/engine"
 	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/hashlife"
 	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
 	"github.com/nsf/termbox-go"
 	"log"
 	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -19,29 +25,151 @@ type ConwayCommand struct {
 }
 
 func (c *ConwayCommand) Help() string {
-	return "Conway's Game of Life"
+	return `Conway's Game of Life, or any other Life-like rule. Click a cell to toggle it. The status bar tells when the
+board has stabilized, and whether it is still, oscillating or moving.
+
+Press c to count the still lifes, oscillators and spaceships on the board, which are written to ` + censusFile + `.
+
+With the hashlife backend the board is a window onto an unbounded universe. Press + and - to double or halve the
+number of generations per step, i and o to zoom in and out, and s to save the universe. It can't run rules with B0.
+
+Options:
+  -rule=RULE      Rule in B/S notation, e.g. B36/S23 for HighLife (default B3/S23).
+  -backend=B      basic (default) updates the board a cell at a time, bitboard packs 64 cells to a word and updates
+                  them together, which is much faster for large boards, and hashlife runs Gosper's HashLife on an
+                  unbounded universe, which can advance regular patterns by billions of generations per step.
+  -width=W        Width of the board (default 80).
+  -height=H       Height of the board (default 80).
+  -step=N         Advance 2^N generations per step, hashlife only (default 0).
+  -zoom=N         Show 2^N by 2^N cells per board cell, hashlife only (default 0).
+  -load=FILE      Load a universe from a Golly macrocell (.mc) file, including its rule, hashlife only.
+  -save=FILE      Macrocell file to save the universe to (default saves/conway.mc).
+` + metrics.Usage
 }
 
 func (c *ConwayCommand) Run(args []string) int {
-	conwayMain(c.UI)
-	return 0
+	flags := flag.NewFlagSet("conway", flag.ContinueOnError)
+	ruleString := flags.String("rule", "B3/S23", "")
+	backend := flags.String("backend", "basic", "")
+	width := flags.Int("width", 80, "")
+	height := flags.Int("height", 80, "")
+	step := flags.Int("step", 0, "")
+	zoom := flags.Int("zoom", 0, "")
+	load := flags.String("load", "", "")
+	save := flags.String("save", "saves/conway.mc", "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	rule, err := bitlife.ParseRule(*ruleString)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if *backend != "basic" && *backend != "bitboard" && *backend != "hashlife" {
+		fmt.Printf("backend %q must be basic, bitboard or hashlife\n", *backend)
+		return 1
+	}
+	var settings *hashlifeSettings
+	if *backend != "hashlife" {
+		if *step != 0 || *zoom != 0 || *load != "" {
+			fmt.Println("-step, -zoom and -load need the hashlife backend")
+			return 1
+		}
+	} else {
+		if err := hashlife.CheckRule(rule); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		settings = &hashlifeSettings{universe: hashlife.NewUniverse(rule), zoom: *zoom, saveFile: *save}
+		if *load != "" {
+			universe, err := loadMacrocell(*load)
+			if err != nil {
+				fmt.Println(err)
+				return 1
+			}
+			settings.universe, settings.loaded = universe, true
+		}
+		settings.universe.SetStepExp(*step)
+		rule = settings.universe.Rule
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		conwayMain(c.UI, rule, *backend, *width, *height, settings, sink)
+	})
+}
+
+// hashlifeSettings holds the options of the hashlife backend.
+type hashlifeSettings struct {
+	universe *hashlife.Universe
+	// whether the universe was loaded from a file rather than started with the example
+	loaded   bool
+	zoom     int
+	saveFile string
+}
+
+func loadMacrocell(filename string) (*hashlife.Universe, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	universe, err := hashlife.ReadMacrocell(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return universe, nil
+}
+
+func saveMacrocell(filename string, universe *hashlife.Universe) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0775); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := hashlife.WriteMacrocell(f, universe); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
 func (c *ConwayCommand) Synopsis() string {
 	return "Conway's Game of Life"
 }
 
-func conwayMain(ui io.Renderer) {
+func conwayMain(ui io.Renderer, rule bitlife.Rule, backend string, width, height int, settings *hashlifeSettings,
+	sink *metrics.Sink) {
 	f := setupLogging("logs/conway.log")
 	defer f.Close()
 
 	ui.Run()
 
-	board := grid.NewBasicBoard(80, 80)
+	var board grid.Plane
+	var universeView *hashlife.View
+	if backend == "bitboard" {
+		board = bitlife.NewBoard(width, height, Alive, Off)
+	} else if backend == "hashlife" {
+		universeView = hashlife.NewView(settings.universe, width, height, Alive, Off)
+		board = universeView
+	} else {
+		basicBoard := grid.NewBasicBoard(width, height)
+		basicBoard.Initialize(Life{Alive: false})
+		board = basicBoard
+	}
 	view := &io.View{Plane: board, Offset: grid.Origin}
 	ui.SetView(view)
-	board.Initialize(Life{Alive: false})
-	game := NewGameOfLife(board, ui)
+	game := NewGameOfLife(board, ui, rule)
+	sink.Observe(game.Engine)
+	if settings == nil || !settings.loaded {
+		game.PlaceExample()
+	}
+	if universeView != nil {
+		// the example is placed before zooming out so that it is a glider at any zoom level
+		universeView.SetZoom(settings.zoom)
+		game.Redraw()
+	}
 	eventClock := game.StartClock()
 	game.Playing = true
 
@@ -50,24 +178,56 @@ func conwayMain(ui io.Renderer) {
 	go func() {
 		for {
 			in := <-ui.Input()
-			switch event := in.(type) {
-			case io.Quit:
+			if _, ok := in.(io.Quit); ok {
 				done <- true
 				return
-			case io.Click:
-				cell := game.Toggle(game.Plane, event.Position)
-				if cell != nil {
-					ui.Draw()
-				}
-			case io.Pause:
-				if game.Playing {
-					eventClock.Stop()
-					game.Playing = false
-				} else {
-					eventClock = game.StartClock()
-					game.Playing = true
-				}
 			}
+			// clicks and keys change the plane and the universe, which the clock must not step meanwhile
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					cell := game.Toggle(game.Plane, event.Position)
+					if cell != nil {
+						ui.Draw()
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				case io.Key:
+					if event.Ch == 'c' {
+						game.TakeCensus(censusFile)
+						return
+					}
+					if universeView == nil {
+						return
+					}
+					switch event.Ch {
+					case '+', '=':
+						settings.universe.SetStepExp(settings.universe.StepExp() + 1)
+					case '-':
+						settings.universe.SetStepExp(settings.universe.StepExp() - 1)
+					case 'i':
+						universeView.SetZoom(universeView.Zoom - 1)
+					case 'o':
+						universeView.SetZoom(universeView.Zoom + 1)
+					}
+					game.Redraw()
+				case io.Save:
+					if universeView == nil {
+						return
+					}
+					if err := saveMacrocell(settings.saveFile, settings.universe); err != nil {
+						log.Printf("Failed to save universe: %v\n", err)
+					} else {
+						log.Printf("Saved universe to %s\n", settings.saveFile)
+					}
+				}
+			})
 		}
 	}()
 
@@ -107,8 +267,16 @@ func (life Life) BgAttribute() termbox.Attribute {
 	return termbox.ColorDefault
 }
 
+// Neighborhood is the neighborhood of the Game of Life rule.
+var Neighborhood = grid.Moore(1)
+
+// GameOfLife updates a grid.BasicBoard a cell at a time, or steps a bitlife.Board or the universe of a hashlife.View
+// all at once.
 type GameOfLife struct {
 	*engine.Engine
+	Rule bitlife.Rule
+	// Detector finds when the board repeats, except on a hashlife view, which may step many generations at once
+	Detector *analysis.Detector
 }
 
 func asLife(cell grid.Cell) Life {
@@ -122,16 +290,27 @@ func asLife(cell grid.Cell) Life {
 var Alive = Life{Alive: true}
 var Off = Life{Alive: false}
 
-func NewGameOfLife(plane grid.Plane, ui io.Renderer) *GameOfLife {
+func NewGameOfLife(plane grid.Plane, ui io.Renderer, rule bitlife.Rule) *GameOfLife {
 	game := &GameOfLife{
 		Engine: &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 250},
+		Rule:   rule,
 	}
 	game.Engine.Handler = game
-	game.initialize()
+	switch plane.(type) {
+	case *bitlife.Board, *hashlife.View:
+		game.Engine.PlaneHandler = game
+	}
+	if _, ok := plane.(*hashlife.View); !ok {
+		game.Detector = analysis.NewDetector(func(cell grid.Cell) bool {
+			return asLife(cell).Alive
+		})
+	}
+	game.showStatus()
 	return game
 }
 
-func (g *GameOfLife) initialize() {
+// PlaceExample places a glider in the top left corner of the board.
+func (g *GameOfLife) PlaceExample() {
 	example := [][]grid.Cell{
 		{Off, Off, Off, Off, Off, Off},
 		{Off, Off, Off, Off, Off, Off},
@@ -146,10 +325,105 @@ func (g *GameOfLife) initialize() {
 			g.Set(grid.Position{i, j}, example[i][j])
 		}
 	}
-	g.UI.SetStatus("Conway's game of life")
 }
 
-func (g *GameOfLife) UpdateCell(plane grid.Plane, position grid.Position) []engine.CellUpdate {
+func (g *GameOfLife) showStatus() {
+	status := "Conway's game of life"
+	if g.Rule != bitlife.Conway {
+		status = fmt.Sprintf("Life-like rule %s", g.Rule)
+	}
+	if view, ok := g.Plane.(*hashlife.View); ok {
+		u := view.Universe
+		status = fmt.Sprintf("%s, generation %d, population %d, 2^%d generations per step, zoom 2^%d", status,
+			u.Generation, u.Population(), u.StepExp(), view.Zoom)
+	}
+	if g.Detector != nil {
+		if result := g.Detector.Result(); result.Kind != analysis.Unknown {
+			status = fmt.Sprintf("%s, %s", status, result)
+		}
+	}
+	g.UI.SetStatus(status)
+}
+
+// Redraw draws the cells of a hashlife view that changed after the view moved or zoomed, or the universe was edited.
+func (g *GameOfLife) Redraw() {
+	if view, ok := g.Plane.(*hashlife.View); ok {
+		for _, position := range view.Changes() {
+			g.UI.Set(position, view.Get(position))
+		}
+		g.showStatus()
+		g.UI.Draw()
+	}
+}
+
+var censusFile = "saves/census.json"
+
+// TakeCensus counts the objects on the board, shows how many were found and writes them to the file as JSON. Only
+// objects of Conway's Game of Life are recognized, under other rules every object is unknown.
+func (g *GameOfLife) TakeCensus(filename string) {
+	catalog := analysis.ConwayCatalog
+	if g.Rule != bitlife.Conway {
+		catalog = analysis.NewCatalog(g.Rule)
+	}
+	census := analysis.TakeCensus(g.Plane, func(cell grid.Cell) bool {
+		return asLife(cell).Alive
+	}, catalog)
+	log.Printf("Generation %d census:\n%s", g.Generation, census)
+
+	objects := 0
+	for _, count := range census {
+		objects += count
+	}
+	status := fmt.Sprintf("Census: %d objects", objects)
+	if names := census.Names(); len(names) > 0 {
+		status = fmt.Sprintf("%s, mostly %s", status, names[0])
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0775); err != nil {
+		log.Printf("Failed to create directory %v\n", err)
+	}
+	f, err := os.Create(filename)
+	if err == nil {
+		err = census.WriteJSON(f)
+		f.Close()
+	}
+	if err != nil {
+		log.Printf("Failed to write census: %v\n", err)
+		status = fmt.Sprintf("%s, failed to write %s", status, filename)
+	}
+	g.UI.SetStatus(status)
+}
+
+func (g *GameOfLife) EndGeneration(plane grid.Plane) {
+	if g.Detector != nil {
+		kind := g.Detector.Result().Kind
+		if result := g.Detector.Observe(plane); result.Kind != kind {
+			log.Printf("Generation %d: %s\n", g.Generation, result)
+		}
+	}
+	g.showStatus()
+}
+
+func (g *GameOfLife) IsAlive(cell grid.Cell) bool {
+	return asLife(cell).Alive
+}
+
+// States are the cells the control API reads and writes by name.
+func (g *GameOfLife) States() map[string]grid.Cell {
+	return map[string]grid.Cell{"alive": Alive, "dead": Off}
+}
+
+// PatternStates numbers the cells for RLE patterns, dead as b and alive as o.
+func (g *GameOfLife) PatternStates() []grid.Cell {
+	return []grid.Cell{Off, Alive}
+}
+
+// CanSave returns whether the universe can be saved, which only the hashlife backend can do.
+func (g *GameOfLife) CanSave() bool {
+	_, ok := g.Plane.(*hashlife.View)
+	return ok
+}
+
+func (g *GameOfLife) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
 
 	bounds := plane.Bounds()
 	if !bounds.Contains(position) {
@@ -159,28 +433,47 @@ func (g *GameOfLife) UpdateCell(plane grid.Plane, position grid.Position) []engi
 	cell := asLife(plane.Get(position))
 
 	neighbors := 0
-	for _, neighbor := range plane.GetNeighbors(position) {
+	for _, neighbor := range plane.GetNeighbors(position, Neighborhood) {
 		neighbor := asLife(neighbor)
 		if neighbor.Alive {
 			neighbors += 1
 		}
 	}
-	if cell.Alive {
-		if neighbors >= 2 && neighbors <= 3 {
-			return []engine.CellUpdate{{Alive, position}}
-		} else {
-			return []engine.CellUpdate{{Off, position}}
-		}
-	} else if !cell.Alive && neighbors == 3 {
-		return []engine.CellUpdate{{Alive, position}}
+	if next := g.Rule.Next(cell.Alive, neighbors); next != cell.Alive {
+		return []engine.CellUpdate{{Life{Alive: next}, position}}
 	}
 	return []engine.CellUpdate{}
 }
 
+func (g *GameOfLife) StepPlane(plane grid.Plane) []grid.Position {
+	if view, ok := plane.(*hashlife.View); ok {
+		view.Universe.Step()
+		return view.Changes()
+	}
+	board := plane.(*bitlife.Board)
+	board.Step(g.Rule)
+	changes := []grid.Position{}
+	board.Changes(func(p grid.Position) {
+		changes = append(changes, p)
+	})
+	return changes
+}
+
+// Edited forgets the repeats found before cells were written through the control API, as Toggle does for clicks.
+func (g *GameOfLife) Edited() {
+	if g.Detector != nil {
+		g.Detector.Reset()
+		g.showStatus()
+	}
+}
+
 func (g *GameOfLife) Toggle(plane grid.Plane, position grid.Position) grid.Cell {
 	if !plane.Bounds().Contains(position) {
 		return nil
 	}
+	if g.Detector != nil {
+		g.Detector.Reset()
+	}
 	cell := asLife(plane.Get(position))
 	if cell.Alive {
 		g.Set(position, Life{Alive: false})
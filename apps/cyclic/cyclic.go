@@ -0,0 +1,264 @@
+package cyclic
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"github.com/nsf/termbox-go"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CyclicCommand struct {
+	UI io.Renderer
+}
+
+func (c *CyclicCommand) Help() string {
+	return `The cyclic cellular automaton. Each cell holds one of N states arranged in a cycle, and advances to the next
+state when at least T of its neighbors already hold it. Starting from random noise, the board organizes itself into
+growing spirals. Click a cell to advance it.
+
+Options:
+  -states=N        Number of states (default 14).
+  -threshold=T     Number of neighbors in the next state a cell needs to advance (default 1).
+  -neighborhood=K  Neighborhood type: ` + strings.Join(grid.NeighborhoodNames, ", ") + ` (default vonneumann).
+                   Hexagonal neighborhoods run on a hexagonal board.
+  -range=R         Neighborhood range (default 1).
+  -width=W         Width of the board (default 60).
+  -height=H        Height of the board (default 40).
+  -seed=N          Seed for the random start, so that runs with the same seed are identical. The seed of every run
+                   is written to the log (default from the clock).
+` + metrics.Usage + `
+
+Try -states=3 -threshold=3 -neighborhood=moore for the 313 rule or -states=8 -threshold=5 -neighborhood=moore
+-range=2 for spiraling amoebas.`
+}
+
+func (c *CyclicCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("cyclic", flag.ContinueOnError)
+	states := flags.Int("states", 14, "")
+	threshold := flags.Int("threshold", 1, "")
+	neighborhoodName := flags.String("neighborhood", "vonneumann", "")
+	r := flags.Int("range", 1, "")
+	width := flags.Int("width", 60, "")
+	height := flags.Int("height", 40, "")
+	seed := flags.Int64("seed", 0, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	neighborhood, err := grid.ParseNeighborhood(*neighborhoodName, *r)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if *states < 2 {
+		fmt.Println("states must be at least 2")
+		return 1
+	}
+	if *threshold < 1 || *threshold > len(neighborhood.Offsets) {
+		fmt.Printf("threshold must be between 1 and the size of the neighborhood, %d\n", len(neighborhood.Offsets))
+		return 1
+	}
+	rule := Rule{States: *states, Threshold: *threshold, Neighborhood: neighborhood}
+	return withMetrics(func(sink *metrics.Sink) {
+		cyclicMain(c.UI, rule, *width, *height, engine.ChooseSeed(*seed), sink)
+	})
+}
+
+func (c *CyclicCommand) Synopsis() string {
+	return "Cyclic cellular automaton"
+}
+
+func cyclicMain(ui io.Renderer, rule Rule, width, height int, seed int64, sink *metrics.Sink) {
+	f := setupLogging("logs/cyclic.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	ui.Run()
+
+	var board grid.Plane
+	if strings.HasPrefix(rule.Neighborhood.Name, "hexagonal") {
+		board = grid.NewHexBoard(width, height)
+	} else {
+		board = grid.NewBasicBoard(width, height)
+	}
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	game := NewCyclic(board, ui, rule, seed)
+	sink.Observe(game.Engine)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					cell := game.Advance(game.Plane, event.Position)
+					if cell != nil {
+						ui.Draw()
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+var attributes = []termbox.Attribute{
+	termbox.ColorRed,
+	termbox.ColorYellow,
+	termbox.ColorGreen,
+	termbox.ColorCyan,
+	termbox.ColorBlue,
+	termbox.ColorMagenta,
+}
+
+// Cell holds one of the states of the cycle, which are spread around the color wheel.
+type Cell struct {
+	State  int
+	States int
+}
+
+func (c Cell) Rune() rune {
+	return '█'
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	return attributes[c.State*len(attributes)/c.States]
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+func (c Cell) Color() grid.Color {
+	return grid.Rainbow.At(float64(c.State) / float64(c.States))
+}
+
+// Rule is a cyclic rule: a cell advances to the next of States states when at least Threshold of its neighbors hold
+// that state.
+type Rule struct {
+	States       int
+	Threshold    int
+	Neighborhood grid.Neighborhood
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%d states, threshold %d, %s", r.States, r.Threshold, r.Neighborhood)
+}
+
+type Cyclic struct {
+	*engine.Engine
+	Rule Rule
+}
+
+func asCell(cell grid.Cell) Cell {
+	cyclicCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected cyclic cell")
+	}
+	return cyclicCell
+}
+
+func NewCyclic(plane grid.Plane, ui io.Renderer, rule Rule, seed int64) *Cyclic {
+	game := &Cyclic{
+		Engine: &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100, Seed: seed},
+		Rule:   rule,
+	}
+	game.Engine.Handler = game
+	game.initialize()
+	return game
+}
+
+// initialize fills the board with random states.
+func (g *Cyclic) initialize() {
+	random := g.InitialRandom()
+	bounds := g.Plane.Bounds()
+	for x := bounds.Corner1.X; x <= bounds.Corner2.X; x++ {
+		for y := bounds.Corner1.Y; y <= bounds.Corner2.Y; y++ {
+			g.Set(grid.Position{x, y}, Cell{State: random.Intn(g.Rule.States), States: g.Rule.States})
+		}
+	}
+	g.UI.SetStatus(fmt.Sprintf("Cyclic CA, %s", g.Rule))
+}
+
+// States names the states of the cycle for the control API by their number, "0" to one less than the number of states.
+func (g *Cyclic) States() map[string]grid.Cell {
+	states := map[string]grid.Cell{}
+	for i, cell := range g.PatternStates() {
+		states[strconv.Itoa(i)] = cell
+	}
+	return states
+}
+
+// PatternStates numbers the states in the order of the cycle.
+func (g *Cyclic) PatternStates() []grid.Cell {
+	states := make([]grid.Cell, g.Rule.States)
+	for i := range states {
+		states[i] = Cell{State: i, States: g.Rule.States}
+	}
+	return states
+}
+
+func (g *Cyclic) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	if !plane.Bounds().Contains(position) {
+		return []engine.CellUpdate{}
+	}
+
+	cell := asCell(plane.Get(position))
+	next := (cell.State + 1) % g.Rule.States
+	count := 0
+	for _, neighbor := range plane.GetNeighbors(position, g.Rule.Neighborhood) {
+		if asCell(neighbor).State == next {
+			count++
+		}
+	}
+	if count < g.Rule.Threshold {
+		return []engine.CellUpdate{}
+	}
+	cell.State = next
+	return []engine.CellUpdate{{cell, position}}
+}
+
+// Advance moves the cell on to the next state.
+func (g *Cyclic) Advance(plane grid.Plane, position grid.Position) grid.Cell {
+	if !plane.Bounds().Contains(position) {
+		return nil
+	}
+	cell := asCell(plane.Get(position))
+	g.Set(position, Cell{State: (cell.State + 1) % g.Rule.States, States: g.Rule.States})
+	return cell
+}
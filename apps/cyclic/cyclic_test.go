@@ -0,0 +1,41 @@
+package cyclic
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"testing"
+)
+
+func TestUpdateCell(t *testing.T) {
+	game := &Cyclic{Rule: Rule{States: 3, Threshold: 2, Neighborhood: grid.Moore(1)}}
+	center := grid.Position{X: 1, Y: 1}
+	neighbors := []grid.Position{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}}
+	cases := []struct {
+		state, neighborState, neighborCount int
+		next                                int
+	}{
+		{0, 1, 1, 0},
+		{0, 1, 2, 1},
+		{0, 1, 3, 1},
+		{0, 2, 3, 0},
+		// the last state advances to the first
+		{2, 0, 1, 2},
+		{2, 0, 2, 0},
+		{2, 1, 3, 2},
+	}
+	for _, c := range cases {
+		board := grid.NewBasicBoard(3, 3)
+		// the other cells hold the state the center is in, which it never advances to
+		board.Initialize(Cell{State: c.state, States: 3})
+		for _, position := range neighbors[:c.neighborCount] {
+			board.Set(position, Cell{State: c.neighborState, States: 3})
+		}
+		next := c.state
+		if updates := game.UpdateCell(board, center, nil); len(updates) > 0 {
+			next = asCell(updates[0].State).State
+		}
+		if next != c.next {
+			t.Errorf("Expected state %d with %d neighbors in state %d to become %d but was %d", c.state,
+				c.neighborCount, c.neighborState, c.next, next)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package forestfire
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"github.com/nsf/termbox-go"
+	"log"
+	"os"
+	"time"
+)
+
+type ForestFireCommand struct {
+	UI io.Renderer
+}
+
+func (c *ForestFireCommand) Help() string {
+	return `The forest fire model of Drossel and Schwabl. Trees grow on empty ground, burning trees set their neighbors
+alight and burn down, and now and then lightning strikes a tree. Click a cell to set it on fire.
+
+Options:
+  -p=P             Chance that a tree grows on an empty cell in each generation (default 0.02).
+  -f=F             Chance that lightning strikes a tree in each generation (default 0.0002).
+  -density=D       Fraction of cells that start as trees (default 0.5).
+  -neighborhood=N  Neighbors a fire spreads to, vonneumann (default) or moore.
+  -width=W         Width of the board (default 60).
+  -height=H        Height of the board (default 40).
+  -seed=N          Seed for the random numbers, so that runs with the same seed are identical. The seed of every
+                   run is written to the log (default from the clock).
+` + metrics.Usage
+}
+
+func (c *ForestFireCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("forestfire", flag.ContinueOnError)
+	growth := flags.Float64("p", 0.02, "")
+	lightning := flags.Float64("f", 0.0002, "")
+	density := flags.Float64("density", 0.5, "")
+	neighborhoodName := flags.String("neighborhood", "vonneumann", "")
+	width := flags.Int("width", 60, "")
+	height := flags.Int("height", 40, "")
+	seed := flags.Int64("seed", 0, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	var neighborhood grid.Neighborhood
+	switch *neighborhoodName {
+	case "vonneumann":
+		neighborhood = grid.VonNeumann(1)
+	case "moore":
+		neighborhood = grid.Moore(1)
+	default:
+		fmt.Printf("neighborhood %q must be vonneumann or moore\n", *neighborhoodName)
+		return 1
+	}
+	if *growth < 0 || *growth > 1 || *lightning < 0 || *lightning > 1 {
+		fmt.Println("p and f must be between 0 and 1")
+		return 1
+	}
+	params := Params{Growth: *growth, Lightning: *lightning, Neighborhood: neighborhood}
+	return withMetrics(func(sink *metrics.Sink) {
+		forestFireMain(c.UI, params, *density, *width, *height, engine.ChooseSeed(*seed), sink)
+	})
+}
+
+func (c *ForestFireCommand) Synopsis() string {
+	return "Forest fire model"
+}
+
+func forestFireMain(ui io.Renderer, params Params, density float64, width, height int, seed int64, sink *metrics.Sink) {
+	f := setupLogging("logs/forestfire.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	ui.Run()
+
+	board := grid.NewBasicBoard(width, height)
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	board.Initialize(Cell{State: Empty})
+	game := NewForestFire(board, ui, params, density, seed)
+	sink.Observe(game.Engine)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					cell := game.Ignite(game.Plane, event.Position)
+					if cell != nil {
+						ui.Draw()
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+type State int
+
+const (
+	Empty State = iota
+	Tree
+	Burning
+)
+
+var TreeColor = grid.Color{R: 0x2e, G: 0xb8, B: 0x4a}
+var FireColor = grid.Color{R: 0xff, G: 0x7a, B: 0x1a}
+
+type Cell struct {
+	State State
+}
+
+func (c Cell) Rune() rune {
+	switch c.State {
+	case Tree:
+		return '♣'
+	case Burning:
+		return '▲'
+	default:
+		return ' '
+	}
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	switch c.State {
+	case Tree:
+		return termbox.ColorGreen
+	case Burning:
+		return termbox.ColorRed
+	default:
+		return termbox.ColorDefault
+	}
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+func (c Cell) Color() grid.Color {
+	switch c.State {
+	case Tree:
+		return TreeColor
+	case Burning:
+		return FireColor
+	default:
+		return grid.Color{R: 0x0e, G: 0x0e, B: 0x0e}
+	}
+}
+
+// Params are the chances of growth and lightning, and the neighbors fire spreads to.
+type Params struct {
+	Growth       float64
+	Lightning    float64
+	Neighborhood grid.Neighborhood
+}
+
+type ForestFire struct {
+	*engine.Engine
+	Params Params
+}
+
+func asCell(cell grid.Cell) Cell {
+	forestCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected forest fire cell")
+	}
+	return forestCell
+}
+
+func NewForestFire(plane grid.Plane, ui io.Renderer, params Params, density float64, seed int64) *ForestFire {
+	game := &ForestFire{
+		Engine: &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100, Seed: seed},
+		Params: params,
+	}
+	game.Engine.Handler = game
+	game.initialize(density)
+	return game
+}
+
+// initialize plants trees at random across the board.
+func (g *ForestFire) initialize(density float64) {
+	random := g.InitialRandom()
+	bounds := g.Plane.Bounds()
+	for x := bounds.Corner1.X; x <= bounds.Corner2.X; x++ {
+		for y := bounds.Corner1.Y; y <= bounds.Corner2.Y; y++ {
+			if random.Float64() < density {
+				g.Set(grid.Position{x, y}, Cell{State: Tree})
+			}
+		}
+	}
+	g.UI.SetStatus(fmt.Sprintf("Forest fire p=%g f=%g", g.Params.Growth, g.Params.Lightning))
+}
+
+// States names the states for the control API.
+func (g *ForestFire) States() map[string]grid.Cell {
+	return map[string]grid.Cell{"empty": Cell{State: Empty}, "tree": Cell{State: Tree}, "burning": Cell{State: Burning}}
+}
+
+// PatternStates numbers the states in the order of State, empty as the background.
+func (g *ForestFire) PatternStates() []grid.Cell {
+	return []grid.Cell{Cell{State: Empty}, Cell{State: Tree}, Cell{State: Burning}}
+}
+
+func (g *ForestFire) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	if !plane.Bounds().Contains(position) {
+		return []engine.CellUpdate{}
+	}
+
+	var next State
+	switch asCell(plane.Get(position)).State {
+	case Burning:
+		next = Empty
+	case Empty:
+		if random.Float64() >= g.Params.Growth {
+			return []engine.CellUpdate{}
+		}
+		next = Tree
+	case Tree:
+		burning := false
+		for _, neighbor := range plane.GetNeighbors(position, g.Params.Neighborhood) {
+			if asCell(neighbor).State == Burning {
+				burning = true
+				break
+			}
+		}
+		if !burning && random.Float64() >= g.Params.Lightning {
+			return []engine.CellUpdate{}
+		}
+		next = Burning
+	}
+	return []engine.CellUpdate{{Cell{State: next}, position}}
+}
+
+// Ignite sets a tree on fire.
+func (g *ForestFire) Ignite(plane grid.Plane, position grid.Position) grid.Cell {
+	if !plane.Bounds().Contains(position) {
+		return nil
+	}
+	cell := asCell(plane.Get(position))
+	if cell.State != Tree {
+		return nil
+	}
+	g.Set(position, Cell{State: Burning})
+	return cell
+}
@@ -0,0 +1,264 @@
+package generations
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"github.com/nsf/termbox-go"
+	"log"
+	"os"
+	"time"
+)
+
+type GenerationsCommand struct {
+	UI io.Renderer
+}
+
+func (c *GenerationsCommand) Help() string {
+	return `Generations rules extend Life-like rules with dying states: cells that do not survive pass through C-2
+refractory states before they are dead.
+
+Options:
+  -rule=S/B/C   Survival counts, birth counts and number of states, e.g. /2/3 for Brian's Brain (default),
+                345/2/4 for Star Wars or 3457/357/5 for Belzhab Sediment.
+  -seed=N       Seed for the random numbers, so that runs with the same seed are identical. The seed of every run
+                is written to the log (default from the clock).
+` + metrics.Usage
+}
+
+func (c *GenerationsCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("generations", flag.ContinueOnError)
+	ruleString := flags.String("rule", "/2/3", "")
+	seed := flags.Int64("seed", 0, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	rule, err := ParseRule(*ruleString)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		generationsMain(c.UI, rule, engine.ChooseSeed(*seed), sink)
+	})
+}
+
+func (c *GenerationsCommand) Synopsis() string {
+	return "Generations rules such as Brian's Brain and Star Wars"
+}
+
+func generationsMain(ui io.Renderer, rule Rule, seed int64, sink *metrics.Sink) {
+	f := setupLogging("logs/generations.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	ui.Run()
+
+	board := grid.NewBasicBoard(80, 80)
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	board.Initialize(Cell{State: Dead, States: rule.States})
+	game := NewGenerations(board, ui, rule, seed)
+	sink.Observe(game.Engine)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					cell := game.Toggle(game.Plane, event.Position)
+					if cell != nil {
+						ui.Draw()
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+const Dead = 0
+const Alive = 1
+
+const ActiveRune = '█'
+const EmptyRune = ' '
+
+var AliveColor = grid.Color{R: 0xff, G: 0xf9, B: 0x33}
+var DyingColor = grid.Color{R: 0xff, G: 0x33, B: 0x58}
+var FadedColor = grid.Color{R: 0x33, G: 0x1a, B: 0x40}
+
+type Cell struct {
+	State  int
+	States int
+}
+
+func (c Cell) Rune() rune {
+	if c.State == Dead {
+		return EmptyRune
+	}
+	return ActiveRune
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	switch c.State {
+	case Dead:
+		return termbox.ColorDefault
+	case Alive:
+		return termbox.ColorYellow
+	default:
+		return termbox.ColorRed
+	}
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+// Color fades dying cells from red towards the background as they approach the dead state.
+func (c Cell) Color() grid.Color {
+	switch c.State {
+	case Dead:
+		return grid.Color{R: 0x0e, G: 0x0e, B: 0x0e}
+	case Alive:
+		return AliveColor
+	default:
+		dyingStates := c.States - 2
+		if dyingStates <= 1 {
+			return DyingColor
+		}
+		return grid.Gradient(DyingColor, FadedColor, float64(c.State-2)/float64(dyingStates-1))
+	}
+}
+
+// Neighborhood is the neighborhood of all Generations rules, which count up to 8 live neighbors.
+var Neighborhood = grid.Moore(1)
+
+type Generations struct {
+	*engine.Engine
+	Rule Rule
+}
+
+func asCell(cell grid.Cell) Cell {
+	generationsCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected Generations cell")
+	}
+	return generationsCell
+}
+
+func NewGenerations(plane grid.Plane, ui io.Renderer, rule Rule, seed int64) *Generations {
+	game := &Generations{
+		Engine: &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100, Seed: seed},
+		Rule:   rule,
+	}
+	game.Engine.Handler = game
+	game.initialize()
+	return game
+}
+
+// initialize seeds a random soup in the top left corner of the board.
+func (g *Generations) initialize() {
+	random := g.InitialRandom()
+	for i := 0; i < 30; i++ {
+		for j := 0; j < 30; j++ {
+			if random.Intn(3) == 0 {
+				g.Set(grid.Position{i + 5, j + 5}, Cell{State: Alive, States: g.Rule.States})
+			}
+		}
+	}
+	g.UI.SetStatus(fmt.Sprintf("Generations %s", g.Rule))
+}
+
+// IsAlive reports cells in the live state, not the dying ones, for the population in the stats of a generation.
+func (g *Generations) IsAlive(cell grid.Cell) bool {
+	return asCell(cell).State == Alive
+}
+
+// States names the states of the rule for the control API, the dying ones by their number, e.g. "dying 2".
+func (g *Generations) States() map[string]grid.Cell {
+	states := map[string]grid.Cell{}
+	for i, cell := range g.PatternStates() {
+		switch i {
+		case Dead:
+			states["dead"] = cell
+		case Alive:
+			states["alive"] = cell
+		default:
+			states[fmt.Sprintf("dying %d", i)] = cell
+		}
+	}
+	return states
+}
+
+// PatternStates numbers the states as Golly does for Generations rules, dead, alive, then dying.
+func (g *Generations) PatternStates() []grid.Cell {
+	states := make([]grid.Cell, g.Rule.States)
+	for i := range states {
+		states[i] = Cell{State: i, States: g.Rule.States}
+	}
+	return states
+}
+
+func (g *Generations) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	if !plane.Bounds().Contains(position) {
+		return []engine.CellUpdate{}
+	}
+
+	cell := asCell(plane.Get(position))
+	liveNeighbors := 0
+	for _, neighbor := range plane.GetNeighbors(position, Neighborhood) {
+		if asCell(neighbor).State == Alive {
+			liveNeighbors++
+		}
+	}
+	next := g.Rule.Next(cell.State, liveNeighbors)
+	if next == cell.State {
+		return []engine.CellUpdate{}
+	}
+	cell.State = next
+	return []engine.CellUpdate{{cell, position}}
+}
+
+func (g *Generations) Toggle(plane grid.Plane, position grid.Position) grid.Cell {
+	if !plane.Bounds().Contains(position) {
+		return nil
+	}
+	cell := asCell(plane.Get(position))
+	if cell.State == Dead {
+		g.Set(position, Cell{State: Alive, States: g.Rule.States})
+	} else {
+		g.Set(position, Cell{State: Dead, States: g.Rule.States})
+	}
+	return cell
+}
@@ -0,0 +1,76 @@
+package generations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rule is a Generations rule. Live cells that do not survive pass through States-2 dying states before they are dead,
+// and dying cells neither survive nor count as live neighbors.
+type Rule struct {
+	Survive [9]bool
+	Birth   [9]bool
+	States  int
+}
+
+// ParseRule parses a rule in S/B/C notation, e.g. "/2/3" for Brian's Brain or "345/2/4" for Star Wars.
+func ParseRule(rule string) (Rule, error) {
+	parts := strings.Split(strings.TrimSpace(rule), "/")
+	if len(parts) != 3 {
+		return Rule{}, fmt.Errorf("rule %q must have the form S/B/C", rule)
+	}
+	var r Rule
+	if err := parseCounts(parts[0], &r.Survive); err != nil {
+		return Rule{}, fmt.Errorf("rule %q has invalid survival counts: %v", rule, err)
+	}
+	if err := parseCounts(parts[1], &r.Birth); err != nil {
+		return Rule{}, fmt.Errorf("rule %q has invalid birth counts: %v", rule, err)
+	}
+	states, err := strconv.Atoi(parts[2])
+	if err != nil || states < 2 {
+		return Rule{}, fmt.Errorf("rule %q must have at least 2 states", rule)
+	}
+	r.States = states
+	return r, nil
+}
+
+func parseCounts(counts string, result *[9]bool) error {
+	for _, c := range counts {
+		if c < '0' || c > '8' {
+			return fmt.Errorf("neighbor count %q is not between 0 and 8", c)
+		}
+		result[c-'0'] = true
+	}
+	return nil
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s/%s/%d", formatCounts(r.Survive), formatCounts(r.Birth), r.States)
+}
+
+func formatCounts(counts [9]bool) string {
+	result := ""
+	for i, set := range counts {
+		if set {
+			result += strconv.Itoa(i)
+		}
+	}
+	return result
+}
+
+// Next returns the state that follows the given state when it has liveNeighbors neighbors in the live state.
+func (r Rule) Next(state int, liveNeighbors int) int {
+	switch state {
+	case Dead:
+		if r.Birth[liveNeighbors] {
+			return Alive
+		}
+		return Dead
+	case Alive:
+		if r.Survive[liveNeighbors] {
+			return Alive
+		}
+	}
+	return (state + 1) % r.States
+}
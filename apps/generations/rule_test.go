@@ -0,0 +1,42 @@
+package generations
+
+import (
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("345/2/4")
+	if err != nil {
+		t.Fatalf("Expected rule to parse, but got: %v", err)
+	}
+	if rule.States != 4 || !rule.Survive[3] || !rule.Survive[5] || rule.Survive[2] || !rule.Birth[2] || rule.Birth[3] {
+		t.Errorf("Unexpected rule: %#v", rule)
+	}
+	if rule.String() != "345/2/4" {
+		t.Errorf("Expected 345/2/4 but found %s", rule.String())
+	}
+
+	for _, invalid := range []string{"", "3/2", "3/2/1", "9/2/3", "3/x/3"} {
+		if _, err := ParseRule(invalid); err == nil {
+			t.Errorf("Expected rule %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestBriansBrain(t *testing.T) {
+	rule, _ := ParseRule("/2/3")
+	transitions := []struct {
+		state, neighbors, next int
+	}{
+		{Dead, 2, Alive},
+		{Dead, 3, Dead},
+		{Alive, 2, 2},
+		{2, 2, Dead},
+	}
+	for _, transition := range transitions {
+		if next := rule.Next(transition.state, transition.neighbors); next != transition.next {
+			t.Errorf("Expected state %d with %d neighbors to become %d but found %d",
+				transition.state, transition.neighbors, transition.next, next)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package greenberghastings
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"github.com/nsf/termbox-go"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+type GreenbergHastingsCommand struct {
+	UI io.Renderer
+}
+
+func (c *GreenbergHastingsCommand) Help() string {
+	return `The Greenberg-Hastings model of excitable media, such as heart muscle or nerve tissue. A resting cell becomes
+excited when at least T of its neighbors are excited, and an excited cell then passes through refractory states, in
+which it cannot be excited, before it rests again. Starting from random noise, waves of excitation spread and collide,
+and curl up into spirals where they break. Click a cell to excite it.
+
+Options:
+  -states=N        Number of states: resting, excited and N-2 refractory states (default 8).
+  -threshold=T     Number of excited neighbors a resting cell needs to become excited (default 1).
+  -neighborhood=K  Neighborhood type: ` + strings.Join(grid.NeighborhoodNames, ", ") + ` (default moore).
+                   Hexagonal neighborhoods run on a hexagonal board.
+  -range=R         Neighborhood range (default 1).
+  -density=D       Fraction of cells that start excited or refractory rather than resting (default 0.3).
+  -width=W         Width of the board (default 60).
+  -height=H        Height of the board (default 40).
+  -seed=N          Seed for the random start, so that runs with the same seed are identical. The seed of every run
+                   is written to the log (default from the clock).
+` + metrics.Usage
+}
+
+func (c *GreenbergHastingsCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("greenberghastings", flag.ContinueOnError)
+	states := flags.Int("states", 8, "")
+	threshold := flags.Int("threshold", 1, "")
+	neighborhoodName := flags.String("neighborhood", "moore", "")
+	r := flags.Int("range", 1, "")
+	density := flags.Float64("density", 0.3, "")
+	width := flags.Int("width", 60, "")
+	height := flags.Int("height", 40, "")
+	seed := flags.Int64("seed", 0, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	neighborhood, err := grid.ParseNeighborhood(*neighborhoodName, *r)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if *states < 3 {
+		fmt.Println("states must be at least 3")
+		return 1
+	}
+	if *threshold < 1 || *threshold > len(neighborhood.Offsets) {
+		fmt.Printf("threshold must be between 1 and the size of the neighborhood, %d\n", len(neighborhood.Offsets))
+		return 1
+	}
+	rule := Rule{States: *states, Threshold: *threshold, Neighborhood: neighborhood}
+	return withMetrics(func(sink *metrics.Sink) {
+		greenbergHastingsMain(c.UI, rule, *density, *width, *height, engine.ChooseSeed(*seed), sink)
+	})
+}
+
+func (c *GreenbergHastingsCommand) Synopsis() string {
+	return "Greenberg-Hastings excitable media"
+}
+
+func greenbergHastingsMain(ui io.Renderer, rule Rule, density float64, width, height int, seed int64,
+	sink *metrics.Sink) {
+	f := setupLogging("logs/greenberghastings.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	ui.Run()
+
+	var board grid.Plane
+	if strings.HasPrefix(rule.Neighborhood.Name, "hexagonal") {
+		board = grid.NewHexBoard(width, height)
+	} else {
+		board = grid.NewBasicBoard(width, height)
+	}
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	game := NewGreenbergHastings(board, ui, rule, density, seed)
+	sink.Observe(game.Engine)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					cell := game.Excite(game.Plane, event.Position)
+					if cell != nil {
+						ui.Draw()
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+const Resting = 0
+const Excited = 1
+
+var RestingColor = grid.Color{R: 0x0e, G: 0x0e, B: 0x0e}
+var ExcitedColor = grid.Color{R: 0xff, G: 0xf9, B: 0x33}
+var RefractoryColor = grid.Color{R: 0xff, G: 0x33, B: 0x58}
+var RecoveredColor = grid.Color{R: 0x2a, G: 0x0e, B: 0x14}
+
+type Cell struct {
+	State  int
+	States int
+}
+
+func (c Cell) Rune() rune {
+	switch c.State {
+	case Resting:
+		return ' '
+	case Excited:
+		return '█'
+	default:
+		return grid.ShadeRune(1 - float64(c.State-1)/float64(c.States-1))
+	}
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	switch c.State {
+	case Resting:
+		return termbox.ColorDefault
+	case Excited:
+		return termbox.ColorYellow
+	default:
+		return termbox.ColorRed
+	}
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+// Color fades refractory cells towards the background as they recover.
+func (c Cell) Color() grid.Color {
+	switch c.State {
+	case Resting:
+		return RestingColor
+	case Excited:
+		return ExcitedColor
+	default:
+		refractoryStates := c.States - 2
+		if refractoryStates <= 1 {
+			return RefractoryColor
+		}
+		return grid.Gradient(RefractoryColor, RecoveredColor, float64(c.State-2)/float64(refractoryStates-1))
+	}
+}
+
+// Rule is a Greenberg-Hastings rule with States states: resting, excited and States-2 refractory states. A resting
+// cell becomes excited when at least Threshold of its neighbors are excited.
+type Rule struct {
+	States       int
+	Threshold    int
+	Neighborhood grid.Neighborhood
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%d states, threshold %d, %s", r.States, r.Threshold, r.Neighborhood)
+}
+
+// Next returns the state following state for a cell with the given number of excited neighbors.
+func (r Rule) Next(state, excited int) int {
+	if state == Resting {
+		if excited >= r.Threshold {
+			return Excited
+		}
+		return Resting
+	}
+	return (state + 1) % r.States
+}
+
+type GreenbergHastings struct {
+	*engine.Engine
+	Rule Rule
+}
+
+func asCell(cell grid.Cell) Cell {
+	ghCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected Greenberg-Hastings cell")
+	}
+	return ghCell
+}
+
+func NewGreenbergHastings(plane grid.Plane, ui io.Renderer, rule Rule, density float64,
+	seed int64) *GreenbergHastings {
+	game := &GreenbergHastings{
+		Engine: &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100, Seed: seed},
+		Rule:   rule,
+	}
+	game.Engine.Handler = game
+	game.initialize(density)
+	return game
+}
+
+// initialize fills the board with noise: each cell is resting, or with probability density in any of the other
+// states.
+func (g *GreenbergHastings) initialize(density float64) {
+	random := g.InitialRandom()
+	bounds := g.Plane.Bounds()
+	for x := bounds.Corner1.X; x <= bounds.Corner2.X; x++ {
+		for y := bounds.Corner1.Y; y <= bounds.Corner2.Y; y++ {
+			state := Resting
+			if random.Float64() < density {
+				state = 1 + random.Intn(g.Rule.States-1)
+			}
+			g.Set(grid.Position{x, y}, Cell{State: state, States: g.Rule.States})
+		}
+	}
+	g.UI.SetStatus(fmt.Sprintf("Greenberg-Hastings, %s", g.Rule))
+}
+
+// States names the states for the control API, the refractory ones by their number, e.g. "refractory 2".
+func (g *GreenbergHastings) States() map[string]grid.Cell {
+	states := map[string]grid.Cell{}
+	for i, cell := range g.PatternStates() {
+		switch i {
+		case Resting:
+			states["resting"] = cell
+		case Excited:
+			states["excited"] = cell
+		default:
+			states[fmt.Sprintf("refractory %d", i)] = cell
+		}
+	}
+	return states
+}
+
+// PatternStates numbers the states in the order a cell goes through them, resting, excited, then refractory.
+func (g *GreenbergHastings) PatternStates() []grid.Cell {
+	states := make([]grid.Cell, g.Rule.States)
+	for i := range states {
+		states[i] = Cell{State: i, States: g.Rule.States}
+	}
+	return states
+}
+
+func (g *GreenbergHastings) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	if !plane.Bounds().Contains(position) {
+		return []engine.CellUpdate{}
+	}
+
+	cell := asCell(plane.Get(position))
+	excited := 0
+	if cell.State == Resting {
+		for _, neighbor := range plane.GetNeighbors(position, g.Rule.Neighborhood) {
+			if asCell(neighbor).State == Excited {
+				excited++
+			}
+		}
+	}
+	next := g.Rule.Next(cell.State, excited)
+	if next == cell.State {
+		return []engine.CellUpdate{}
+	}
+	cell.State = next
+	return []engine.CellUpdate{{cell, position}}
+}
+
+// Excite makes the cell excited.
+func (g *GreenbergHastings) Excite(plane grid.Plane, position grid.Position) grid.Cell {
+	if !plane.Bounds().Contains(position) {
+		return nil
+	}
+	cell := asCell(plane.Get(position))
+	g.Set(position, Cell{State: Excited, States: g.Rule.States})
+	return cell
+}
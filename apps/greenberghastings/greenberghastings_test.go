@@ -0,0 +1,46 @@
+package greenberghastings
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"testing"
+)
+
+func TestNext(t *testing.T) {
+	rule := Rule{States: 4, Threshold: 2, Neighborhood: grid.Moore(1)}
+	cases := []struct {
+		state, excited, next int
+	}{
+		{Resting, 0, Resting},
+		{Resting, 1, Resting},
+		{Resting, 2, Excited},
+		{Excited, 8, 2},
+		{2, 0, 3},
+		{3, 5, Resting},
+	}
+	for _, c := range cases {
+		if next := rule.Next(c.state, c.excited); next != c.next {
+			t.Errorf("Expected state %d with %d excited neighbors to become %d but was %d", c.state, c.excited, c.next, next)
+		}
+	}
+}
+
+func TestNoiseSustainsWaves(t *testing.T) {
+	board := grid.NewBasicBoard(60, 40)
+	rule := Rule{States: 8, Threshold: 1, Neighborhood: grid.Moore(1)}
+	game := NewGreenbergHastings(board, io.NullRenderer{}, rule, 0.3, 1)
+	for i := 0; i < 500; i++ {
+		game.Step()
+	}
+	excited := 0
+	for x := 0; x < 60; x++ {
+		for y := 0; y < 40; y++ {
+			if asCell(board.Get(grid.Position{x, y})).State == Excited {
+				excited++
+			}
+		}
+	}
+	if excited == 0 {
+		t.Errorf("Expected waves to keep going from the default noise but the board came to rest")
+	}
+}
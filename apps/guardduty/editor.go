@@ -0,0 +1,179 @@
+package guardduty
+
+import (
+	"fmt"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/nsf/termbox-go"
+)
+
+const waypointOverlay = "waypoints"
+
+// waypointEditor tracks the state of waypoint mode, where clicks edit the waypoint loop of the selected guard
+// instead of toggling barriers. Clicking an empty cell appends a waypoint, clicking a waypoint picks it up, clicking
+// elsewhere moves the picked waypoint there and clicking the picked waypoint again deletes it.
+type waypointEditor struct {
+	enabled  bool
+	selected int
+	picked   *Waypoint
+}
+
+type overlayCell struct {
+	rune  rune
+	color termbox.Attribute
+}
+
+func (c overlayCell) Rune() rune {
+	return c.rune
+}
+
+func (c overlayCell) FgAttribute() termbox.Attribute {
+	return c.color
+}
+
+func (c overlayCell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+var routeMarker = overlayCell{'·', termbox.ColorGreen}
+var waypointMarker = overlayCell{'◆', termbox.ColorYellow}
+var pickedMarker = overlayCell{'◆', termbox.ColorMagenta}
+var otherWaypointMarker = overlayCell{'◇', termbox.ColorCyan}
+
+// waypoints returns the guard's waypoint loop, starting at its next waypoint.
+func (guard *Guard) waypoints() []*Waypoint {
+	waypoints := make([]*Waypoint, 0)
+	start := guard.nextWaypoint
+	if start == nil {
+		return waypoints
+	}
+	current := start
+	for ; current.next != start; current = current.next {
+		waypoints = append(waypoints, current)
+	}
+	return append(waypoints, current)
+}
+
+// setWaypoints replaces the guard's waypoint loop. The guard heads to the first waypoint next.
+func (guard *Guard) setWaypoints(waypoints []*Waypoint) {
+	for i, waypoint := range waypoints {
+		waypoint.next = waypoints[(i+1)%len(waypoints)]
+	}
+	if len(waypoints) > 0 {
+		guard.nextWaypoint = waypoints[0]
+	} else {
+		guard.nextWaypoint = nil
+	}
+	guard.replan = true
+}
+
+func (g *GuardDuty) selectedGuard() *Guard {
+	if len(g.guards) == 0 {
+		return nil
+	}
+	return g.guards[g.editor.selected%len(g.guards)]
+}
+
+func (g *GuardDuty) ToggleWaypointMode() bool {
+	g.editor.enabled = !g.editor.enabled
+	g.editor.picked = nil
+	g.refreshWaypointOverlay()
+	return g.editor.enabled
+}
+
+func (g *GuardDuty) SelectNextGuard() {
+	if len(g.guards) == 0 {
+		return
+	}
+	g.editor.selected = (g.editor.selected + 1) % len(g.guards)
+	g.editor.picked = nil
+	g.refreshWaypointOverlay()
+}
+
+func (g *GuardDuty) EditWaypoint(position grid.Position) {
+	if !g.Plane.Bounds().Contains(position) {
+		return
+	}
+	if guard, ok := asCell(g.Plane.Get(position)).Unit.(*Guard); ok {
+		g.editor.selected = guard.id
+		g.editor.picked = nil
+		g.refreshWaypointOverlay()
+		return
+	}
+	guard := g.selectedGuard()
+	if guard == nil {
+		return
+	}
+
+	waypoints := guard.waypoints()
+	clicked := -1
+	for i, waypoint := range waypoints {
+		if waypoint.position == position {
+			clicked = i
+		}
+	}
+
+	switch {
+	case clicked >= 0 && waypoints[clicked] == g.editor.picked:
+		waypoints = append(waypoints[:clicked], waypoints[clicked+1:]...)
+		g.editor.picked = nil
+	case clicked >= 0:
+		g.editor.picked = waypoints[clicked]
+	case g.editor.picked != nil:
+		g.editor.picked.position = position
+		g.editor.picked = nil
+	default:
+		waypoints = append(waypoints, &Waypoint{position: position})
+	}
+	guard.setWaypoints(waypoints)
+	g.refreshWaypointOverlay()
+}
+
+// refreshWaypointOverlay draws the waypoints of all guards and the route loop of the selected guard while in
+// waypoint mode, and clears the overlay otherwise.
+func (g *GuardDuty) refreshWaypointOverlay() {
+	if !g.editor.enabled {
+		g.UI.SetOverlay(waypointOverlay, nil)
+		g.UI.Draw()
+		return
+	}
+	overlay := make(map[grid.Position]grid.Cell)
+	selected := g.selectedGuard()
+	for _, guard := range g.guards {
+		if guard == selected {
+			continue
+		}
+		for _, waypoint := range guard.waypoints() {
+			overlay[waypoint.position] = otherWaypointMarker
+		}
+	}
+
+	if selected != nil {
+		waypoints := selected.waypoints()
+		for i, waypoint := range waypoints {
+			next := waypoints[(i+1)%len(waypoints)]
+			start, goal := asCell(g.Plane.Get(waypoint.position)), asCell(g.Plane.Get(next.position))
+			if path, ok := grid.FindPath(start, goal, costHuristic); ok {
+				for _, node := range path.Nodes {
+					overlay[node.(Cell).Position] = routeMarker
+				}
+			}
+		}
+		for _, waypoint := range waypoints {
+			if waypoint == g.editor.picked {
+				overlay[waypoint.position] = pickedMarker
+			} else {
+				overlay[waypoint.position] = waypointMarker
+			}
+		}
+		g.UI.SetStatus(fmt.Sprintf("Waypoint mode: guard %d, %d waypoints", selected.id, len(waypoints)))
+	}
+
+	// keep the guards themselves visible
+	for position := range overlay {
+		if asCell(g.Plane.Get(position)).Unit != nil {
+			delete(overlay, position)
+		}
+	}
+	g.UI.SetOverlay(waypointOverlay, overlay)
+	g.UI.Draw()
+}
@@ -1,16 +1,20 @@
 package guardduty
 
 import (
+	"flag"
 	"fmt"
 	"github.com/google/flatbuffers/go"
 	"github.com/jpbetz/cellularautomata/engine"
 	"github.com/jpbetz/cellularautomata/flatbuffers/region"
 	"github.com/jpbetz/cellularautomata/grid"
 	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
 	"github.com/nsf/termbox-go"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"time"
 )
 
@@ -19,19 +23,30 @@ type GuardDutyCommand struct {
 }
 
 func (c *GuardDutyCommand) Help() string {
-	return "Guard Duty creates a simple waypoint circle that a guard walks around, using A* to navigate."
+	return "Guard Duty creates simple waypoint circles that guards walk around, using cooperative A* to navigate without colliding. " +
+		"Guards watch a vision cone ahead of them and chase intruders they spot.\n\n" +
+		"Clicks toggle barriers. Press w to enter waypoint mode, where clicks append, move or delete the waypoints of the " +
+		"selected guard, and g to select the next guard. Press i to send in an intruder that tries to sneak past the guards.\n\n" +
+		"The stats written by -metrics-out include the routes planned and their lengths.\n\n" +
+		"Options:\n" + metrics.Usage
 }
 
 func (c *GuardDutyCommand) Run(args []string) int {
-	guardDutyMain(c.UI)
-	return 0
+	flags := flag.NewFlagSet("guardduty", flag.ContinueOnError)
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		guardDutyMain(c.UI, sink)
+	})
 }
 
 func (c *GuardDutyCommand) Synopsis() string {
 	return "Guard Duty"
 }
 
-func guardDutyMain(ui io.Renderer) {
+func guardDutyMain(ui io.Renderer, sink *metrics.Sink) {
 	f := setupLogging("logs/guardduty.log")
 	defer f.Close()
 
@@ -53,42 +68,73 @@ func guardDutyMain(ui io.Renderer) {
 	}
 
 	game := NewGuardDuty(board, ui)
+	sink.Observe(game.Engine)
 	eventClock := game.StartClock()
 	game.Playing = true
 
+	wasPlaying := false
 	done := make(chan bool)
 	go func() {
 		for {
 			in := <-ui.Input()
-			switch event := in.(type) {
-			case io.Quit:
+			if _, ok := in.(io.Quit); ok {
 				done <- true
 				return
-			case io.Click:
-				cell := board.Get(event.Position).(Cell)
-				if cell.State == Barrier {
-					cell.State = Empty
-				} else {
-					cell.State = Barrier
-				}
-				game.Set(event.Position, cell)
-				game.UI.Draw()
-			case io.Pause:
-				if game.Playing {
-					eventClock.Stop()
-					game.Playing = false
-				} else {
-					eventClock = game.StartClock()
-					game.Playing = true
-				}
-			case io.Save:
-				log.Printf("Writing log file: %s\n", saveDataFile)
-				buf := game.Save(board.Cells, board.W, board.H)
-				if err := ioutil.WriteFile(saveDataFile, buf, 0664); err != nil {
-					log.Printf("Failed to write file %v\n", err)
-				}
-				log.Printf("Wrote log file: %s\n", saveDataFile)
 			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					if game.editor.enabled {
+						game.EditWaypoint(event.Position)
+						return
+					}
+					cell := board.Get(event.Position).(Cell)
+					if cell.State == Barrier {
+						cell.State = Empty
+					} else {
+						cell.State = Barrier
+					}
+					game.Set(event.Position, cell)
+					game.UI.Draw()
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				case io.Key:
+					switch event.Ch {
+					case 'w':
+						// the board holds still while waypoints are edited
+						if game.ToggleWaypointMode() {
+							wasPlaying = game.Playing
+							if game.Playing {
+								eventClock.Stop()
+								game.Playing = false
+							}
+						} else if wasPlaying {
+							eventClock = game.StartClock()
+							game.Playing = true
+						}
+					case 'g':
+						game.SelectNextGuard()
+					case 'i':
+						game.SpawnIntruder()
+					}
+				case io.Save:
+					log.Printf("Writing log file: %s\n", saveDataFile)
+					buf := game.Save(board.Cells, board.W, board.H)
+					if err := os.MkdirAll(filepath.Dir(saveDataFile), 0775); err != nil {
+						log.Printf("Failed to create directory %v\n", err)
+					}
+					if err := ioutil.WriteFile(saveDataFile, buf, 0664); err != nil {
+						log.Printf("Failed to write file %v\n", err)
+					}
+					log.Printf("Wrote log file: %s\n", saveDataFile)
+				}
+			})
 		}
 	}()
 
@@ -112,9 +158,23 @@ type Waypoint struct {
 	next     *Waypoint
 }
 
+type GuardMode int
+
+const (
+	Patrol GuardMode = iota
+	Pursuit
+)
+
 type Guard struct {
+	id                int
 	nextWaypoint      *Waypoint
 	nextWaypointRoute *grid.Path
+	// set when the waypoints were edited and the current route is stale
+	replan bool
+	facing grid.Orientation
+	mode   GuardMode
+	// last position the intruder being pursued was seen at
+	target grid.Position
 }
 
 type CellState int
@@ -147,15 +207,18 @@ func (s Cell) Id() grid.NodeId {
 	return s.Position
 }
 
+// Neighborhood is the set of positions guards and intruders can step to, since they only move orthogonally.
+var Neighborhood = grid.VonNeumann(1)
+
 func (s Cell) GetNeighbors() []grid.Neighbor {
 	if s.Plane == nil {
 		panic("Cell has no plane.")
 	}
-	neighbors := s.Plane.GetNeighbors(s.Position)
+	neighbors := s.Plane.GetNeighbors(s.Position, Neighborhood)
 	results := make([]grid.Neighbor, 0, len(neighbors))
 	for _, neighbor := range neighbors {
 		neighborCell := asCell(neighbor)
-		if neighborCell.State != Barrier && (neighborCell.Position.X == s.Position.X || neighborCell.Position.Y == s.Position.Y) {
+		if neighborCell.State != Barrier {
 			results = append(results, CellNeighbor{neighborCell})
 		}
 	}
@@ -163,8 +226,11 @@ func (s Cell) GetNeighbors() []grid.Neighbor {
 }
 
 func (s Cell) Rune() rune {
-	if s.Unit != nil {
+	switch s.Unit.(type) {
+	case *Guard:
 		return ''
+	case *Intruder:
+		return ''
 	}
 	switch s.State {
 	case Empty:
@@ -175,8 +241,11 @@ func (s Cell) Rune() rune {
 }
 
 func (s Cell) FgAttribute() termbox.Attribute {
-	if s.Unit != nil {
+	switch s.Unit.(type) {
+	case *Guard:
 		return termbox.ColorRed
+	case *Intruder:
+		return termbox.ColorMagenta
 	}
 	switch s.State {
 	case Empty:
@@ -202,11 +271,26 @@ func asCell(cell grid.Cell) Cell {
 
 type GuardDuty struct {
 	*engine.Engine
+	reservations *grid.ReservationTable
+	guards       []*Guard
+	intruders    []*Intruder
+	editor       waypointEditor
+	// positions currently seen by any guard
+	vision map[grid.Position]bool
+	spawns chan bool
+	// routes planned in the current generation, and their total number of steps
+	replans    int
+	pathLength int
 }
 
+// number of time steps each guard reserves ahead when it plans a route
+const reservationWindow = 16
+
 func NewGuardDuty(plane grid.Plane, ui io.Renderer) *GuardDuty {
 	game := &GuardDuty{
-		Engine: &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100},
+		Engine:       &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100},
+		reservations: grid.NewReservationTable(),
+		spawns:       make(chan bool, 1),
 	}
 	game.Engine.Handler = game
 	game.initialize()
@@ -227,12 +311,19 @@ func (g *GuardDuty) initialize() {
 		file = initialDataFile
 	}
 
+	var example []Cell
+	var w, h int
 	buf, err := ioutil.ReadFile(file)
-	if err != nil {
+	if os.IsNotExist(err) {
+		log.Println("Initial file not found. Generating default board.")
+		bounds := g.Plane.Bounds()
+		example, w, h = defaultBoard(bounds.Corner2.X+1, bounds.Corner2.Y+1)
+	} else if err != nil {
 		panic(fmt.Sprintf("Unable to read file: %s: %#v", file, err))
+	} else {
+		example, w, h = g.Load(buf)
 	}
 
-	example, w, h := g.Load(buf)
 	for i := 0; i < len(example); i++ {
 		example := example[i]
 		p := grid.Position{i % w, i / w}
@@ -242,9 +333,55 @@ func (g *GuardDuty) initialize() {
 		if p != current.Position {
 			panic("p != current.Position")
 		}
+		switch unit := current.Unit.(type) {
+		case *Guard:
+			g.reservations.Reserve(p, g.Generation, unit)
+			g.guards = append(g.guards, unit)
+		case *Intruder:
+			g.reservations.Reserve(p, g.Generation, unit)
+			g.intruders = append(g.intruders, unit)
+		}
 		g.Set(p, current)
 	}
-	g.UI.SetStatus(fmt.Sprintf("GuardDuty (%d, %d)", w, h))
+	sort.Slice(g.guards, func(i, j int) bool {
+		return g.guards[i].id < g.guards[j].id
+	})
+	g.EndGeneration(g.Plane)
+	g.refreshGoalOverlay()
+	g.UI.SetStatus(fmt.Sprintf("GuardDuty (%d, %d), %d guards, %d intruders", w, h, len(g.guards), len(g.intruders)))
+}
+
+// defaultBoard generates a walled board with a block in the middle and two guards, one circling the block and one
+// crossing between opposite corners, plus an intruder trying to sneak across, for when no data file is available.
+func defaultBoard(w, h int) ([]Cell, int, int) {
+	cells := make([]Cell, w*h)
+	for i := range cells {
+		x, y := i%w, i/w
+		border := x == 0 || y == 0 || x == w-1 || y == h-1
+		block := x > w/3 && x < w-w/3-1 && y > h/3 && y < h-h/3-1
+		if border || block {
+			cells[i] = B
+		} else {
+			cells[i] = O
+		}
+	}
+
+	loops := [][]grid.Position{
+		{{w / 4, h / 4}, {w - w/4 - 1, h / 4}, {w - w/4 - 1, h - h/4 - 1}, {w / 4, h - h/4 - 1}},
+		{{2, 2}, {w - 3, h - 3}},
+	}
+	for id, loop := range loops {
+		waypoints := make([]*Waypoint, len(loop))
+		for i, position := range loop {
+			waypoints[i] = &Waypoint{position: position}
+		}
+		guard := &Guard{id: id}
+		guard.setWaypoints(waypoints)
+		cells[loop[0].X+w*loop[0].Y] = Cell{State: Empty, Unit: guard}
+	}
+	entry, goal := defaultIntruderRoute(w, h)
+	cells[entry.X+w*entry.Y] = Cell{State: Empty, Unit: &Intruder{goal: goal}}
+	return cells, w, h
 }
 
 func (g *GuardDuty) Load(buf []byte) ([]Cell, int, int) {
@@ -268,15 +405,56 @@ func (g *GuardDuty) Load(buf []byte) ([]Cell, int, int) {
 	}
 	log.Printf("Loaded %d tiles\n", plane.TilesLength())
 
-	guard := &region.GuardUnit{}
-	basicBoard.Guard(guard)
+	guardUnits := make([]*region.GuardUnit, basicBoard.GuardsLength())
+	for i := range guardUnits {
+		guardUnits[i] = &region.GuardUnit{}
+		basicBoard.Guards(guardUnits[i], i)
+	}
+	if len(guardUnits) == 0 {
+		if guard := basicBoard.Guard(nil); guard != nil {
+			log.Print("Loading single guard from legacy save\n")
+			guardUnits = append(guardUnits, guard)
+		}
+	}
+
+	for id, guardUnit := range guardUnits {
+		guardPosition, guard := loadGuard(id, guardUnit)
+		guardIdx := guardPosition.X + (planeW * guardPosition.Y)
+		log.Printf("Loading guard %d at idx: %d, x,y = %d, %d", id, guardIdx, guardPosition.X, guardPosition.Y)
+		if cells[guardIdx].Unit != nil {
+			panic(fmt.Sprintf("Multiple units at (%d, %d)", guardPosition.X, guardPosition.Y))
+		}
+		cells[guardIdx] = Cell{
+			State: Empty,
+			Unit:  guard,
+		}
+	}
+
+	for i := 0; i < basicBoard.IntrudersLength(); i++ {
+		intruderUnit := &region.IntruderUnit{}
+		basicBoard.Intruders(intruderUnit, i)
+		position, goal := intruderUnit.Position(nil), intruderUnit.Goal(nil)
+		log.Printf("Loaded intruder at (%d, %d) with goal (%d, %d)\n", position.X(), position.Y(), goal.X(), goal.Y())
+		intruderIdx := int(position.X()) + (planeW * int(position.Y()))
+		if cells[intruderIdx].Unit != nil {
+			panic(fmt.Sprintf("Multiple units at (%d, %d)", position.X(), position.Y()))
+		}
+		cells[intruderIdx] = Cell{
+			State: Empty,
+			Unit:  &Intruder{goal: grid.Position{int(goal.X()), int(goal.Y())}},
+		}
+	}
+	return cells, planeW, planeH
+}
+
+func loadGuard(id int, guardUnit *region.GuardUnit) (grid.Position, *Guard) {
 	guardPosition := &region.Position{}
-	guard.Position(guardPosition)
-	log.Printf("Loaded guard at (%d, %d)\n", guardPosition.X(), guardPosition.Y())
-	waypoints := make([]*Waypoint, guard.WaypointsLength())
-	for i := 0; i < guard.WaypointsLength(); i++ {
+	guardUnit.Position(guardPosition)
+	log.Printf("Loaded guard %d at (%d, %d)\n", id, guardPosition.X(), guardPosition.Y())
+	waypoints := make([]*Waypoint, guardUnit.WaypointsLength())
+	for i := 0; i < guardUnit.WaypointsLength(); i++ {
 		position := &region.Position{}
-		guard.Waypoints(position, i)
+		guardUnit.Waypoints(position, i)
 		log.Printf("Loaded waypoint at (%d, %d)\n", position.X(), position.Y())
 		waypoint := &Waypoint{
 			position: grid.Position{int(position.X()), int(position.Y())},
@@ -284,20 +462,33 @@ func (g *GuardDuty) Load(buf []byte) ([]Cell, int, int) {
 		if i > 0 {
 			waypoints[i-1].next = waypoint
 		}
-		if i == guard.WaypointsLength()-1 {
+		if i == guardUnit.WaypointsLength()-1 {
 			waypoint.next = waypoints[0]
 		}
 		waypoints[i] = waypoint
 	}
 	log.Printf("Loaded waypoints: %#v\n", waypoints)
 
-	guardIdx := int(guardPosition.X()) + (planeW * int(guardPosition.Y()))
-	log.Printf("Loading guard at idx: %d, x,y = %d, %d", guardIdx, guardPosition.X(), guardPosition.Y())
-	cells[guardIdx] = Cell{
-		State: Empty,
-		Unit:  &Guard{nextWaypoint: waypoints[0]},
+	guard := &Guard{id: id}
+	if len(waypoints) > 0 {
+		guard.nextWaypoint = waypoints[0]
 	}
-	return cells, planeW, planeH
+	return grid.Position{int(guardPosition.X()), int(guardPosition.Y())}, guard
+}
+
+type guardPlacement struct {
+	guard    *Guard
+	position grid.Position
+}
+
+type intruderPlacement struct {
+	intruder *Intruder
+	position grid.Position
+}
+
+// CanSave tells the control API that a save event writes the log file.
+func (g *GuardDuty) CanSave() bool {
+	return true
 }
 
 func (g *GuardDuty) Save(cells []grid.Cell, w, h int) []byte {
@@ -306,8 +497,8 @@ func (g *GuardDuty) Save(cells []grid.Cell, w, h int) []byte {
 	// tiles
 	log.Printf("Saving %d cells", len(cells))
 	tileEnds := make([]flatbuffers.UOffsetT, len(cells))
-	var guard *Guard
-	var guardPosition grid.Position
+	guards := make([]guardPlacement, 0)
+	intruders := make([]intruderPlacement, 0)
 	for i, gridCell := range cells {
 		cell := gridCell.(Cell)
 		var state int
@@ -319,15 +510,19 @@ func (g *GuardDuty) Save(cells []grid.Cell, w, h int) []byte {
 		region.TileStart(builder)
 		region.TileAddTileType(builder, int32(state))
 		tileEnds[i] = region.TileEnd(builder)
-		var ok bool
-		if cell.Unit != nil {
-			guard, ok = cell.Unit.(*Guard)
-			if !ok {
-				panic(fmt.Sprintf("Failed to convert cell unit to guard: %v", cell.Unit))
-			}
-			guardPosition = cell.Position
+		switch unit := cell.Unit.(type) {
+		case nil:
+		case *Guard:
+			guards = append(guards, guardPlacement{unit, cell.Position})
+		case *Intruder:
+			intruders = append(intruders, intruderPlacement{unit, cell.Position})
+		default:
+			panic(fmt.Sprintf("Unsupported cell unit: %v", cell.Unit))
 		}
 	}
+	sort.Slice(guards, func(i, j int) bool {
+		return guards[i].guard.id < guards[j].guard.id
+	})
 
 	// plane tiles vector
 	log.Printf("Writing %d tiles", len(tileEnds))
@@ -344,15 +539,59 @@ func (g *GuardDuty) Save(cells []grid.Cell, w, h int) []byte {
 	region.PlaneAddTiles(builder, tilesVectorEnd)
 	planeEnd := region.PlaneEnd(builder)
 
-	// waypoint positions
-	start := guard.nextWaypoint
+	// guard units
+	guardEnds := make([]flatbuffers.UOffsetT, len(guards))
+	for i, placement := range guards {
+		guardEnds[i] = saveGuard(builder, placement.guard, placement.position)
+	}
+
+	// guard units vector
+	log.Printf("Writing %d guards", len(guardEnds))
+	region.BasicBoardStartGuardsVector(builder, len(guardEnds))
+	for i := len(guardEnds) - 1; i >= 0; i-- {
+		builder.PrependUOffsetT(guardEnds[i])
+	}
+	guardsVectorEnd := builder.EndVector(len(guardEnds))
+
+	// intruder units
+	intruderEnds := make([]flatbuffers.UOffsetT, len(intruders))
+	for i, placement := range intruders {
+		goal := placement.intruder.goal
+		log.Printf("Writing intruder position (%d, %d)", int32(placement.position.X), int32(placement.position.Y))
+		region.IntruderUnitStart(builder)
+		region.IntruderUnitAddPosition(builder, region.CreatePosition(builder, int32(placement.position.X), int32(placement.position.Y)))
+		region.IntruderUnitAddGoal(builder, region.CreatePosition(builder, int32(goal.X), int32(goal.Y)))
+		intruderEnds[i] = region.IntruderUnitEnd(builder)
+	}
+
+	// intruder units vector
+	region.BasicBoardStartIntrudersVector(builder, len(intruderEnds))
+	for i := len(intruderEnds) - 1; i >= 0; i-- {
+		builder.PrependUOffsetT(intruderEnds[i])
+	}
+	intrudersVectorEnd := builder.EndVector(len(intruderEnds))
+
+	// basic board
+	region.BasicBoardStart(builder)
+	region.BasicBoardAddPlane(builder, planeEnd)
+	region.BasicBoardAddGuards(builder, guardsVectorEnd)
+	region.BasicBoardAddIntruders(builder, intrudersVectorEnd)
+	basicBoardEnd := region.BasicBoardEnd(builder)
+
+	builder.Finish(basicBoardEnd)
+	return builder.Bytes[builder.Head():]
+}
 
+func saveGuard(builder *flatbuffers.Builder, guard *Guard, guardPosition grid.Position) flatbuffers.UOffsetT {
+	// waypoint positions
 	waypoints := make([]grid.Position, 0)
-	current := start
-	for ; current.next != start; current = current.next {
+	if start := guard.nextWaypoint; start != nil {
+		current := start
+		for ; current.next != start; current = current.next {
+			waypoints = append(waypoints, current.position)
+		}
 		waypoints = append(waypoints, current.position)
 	}
-	waypoints = append(waypoints, current.position)
 
 	// waypoint positions vector
 	region.GuardUnitStartWaypointsVector(builder, len(waypoints))
@@ -363,25 +602,14 @@ func (g *GuardDuty) Save(cells []grid.Cell, w, h int) []byte {
 	waypointsVectorEnd := builder.EndVector(len(waypoints))
 
 	// guard unit
-	log.Printf("Writing guard position (%d, %d)", int32(guardPosition.X), int32(guardPosition.Y))
-	guardUnitPosition := region.CreatePosition(builder, int32(guardPosition.X), int32(guardPosition.Y))
-
+	log.Printf("Writing guard %d position (%d, %d)", guard.id, int32(guardPosition.X), int32(guardPosition.Y))
 	region.GuardUnitStart(builder)
-	region.GuardUnitAddPosition(builder, guardUnitPosition)
+	region.GuardUnitAddPosition(builder, region.CreatePosition(builder, int32(guardPosition.X), int32(guardPosition.Y)))
 	region.GuardUnitAddWaypoints(builder, waypointsVectorEnd)
-	guardEnd := region.GuardUnitEnd(builder)
-
-	// basic board
-	region.BasicBoardStart(builder)
-	region.BasicBoardAddPlane(builder, planeEnd)
-	region.BasicBoardAddGuard(builder, guardEnd)
-	basicBoardEnd := region.BasicBoardEnd(builder)
-
-	builder.Finish(basicBoardEnd)
-	return builder.Bytes[builder.Head():]
+	return region.GuardUnitEnd(builder)
 }
 
-func (g *GuardDuty) UpdateCell(plane grid.Plane, position grid.Position) []engine.CellUpdate {
+func (g *GuardDuty) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
 	if !plane.Bounds().Contains(position) {
 		return []engine.CellUpdate{}
 	}
@@ -390,47 +618,112 @@ func (g *GuardDuty) UpdateCell(plane grid.Plane, position grid.Position) []engin
 	if cell.Unit != nil {
 		switch unit := cell.Unit.(type) {
 		case *Guard:
-			guard := unit
-			if guard.nextWaypointRoute == nil && guard.nextWaypoint != nil {
-				g.UI.SetStatus(fmt.Sprintf("Next Waypoint: %v", guard.nextWaypoint.position))
-				path, ok := findPath(cell, asCell(plane.Get(guard.nextWaypoint.position)))
-				if ok {
-					guard.nextWaypointRoute = path
-				}
-			}
-			if guard.nextWaypointRoute != nil {
-				var route = guard.nextWaypointRoute.Nodes
-				if len(route) > 0 {
-					var tail grid.Node
-					tail, guard.nextWaypointRoute.Nodes = route[len(route)-1], route[:len(route)-1]
-					nextPosition := tail.(Cell).Position
-					nextCell := asCell(plane.Get(nextPosition))
-
-					if nextCell.State == Barrier {
-						guard.nextWaypointRoute = nil
-					} else {
-						nextCell.Unit = cell.Unit
-						cell.Unit = nil
-						return []engine.CellUpdate{
-							{cell, cell.Position},
-							{nextCell, nextCell.Position},
-						}
-					}
-				} else {
-					guard.nextWaypointRoute = nil
+			return g.updateGuard(plane, cell, unit)
+		case *Intruder:
+			return g.updateIntruder(plane, cell, unit)
+		}
+	}
+	return []engine.CellUpdate{}
+}
 
-					guard.nextWaypoint = guard.nextWaypoint.next
-				}
+// updateGuard moves the guard one step along its patrol route, or towards the intruder it is pursuing.
+func (g *GuardDuty) updateGuard(plane grid.Plane, cell Cell, guard *Guard) []engine.CellUpdate {
+	now := g.Generation
+	if guard.replan || guard.mode == Pursuit {
+		// pursuit follows a moving target, so it plans again every step
+		g.reservations.Release(guard, now+1)
+		guard.nextWaypointRoute = nil
+		guard.replan = false
+	}
+	if guard.nextWaypointRoute != nil && len(guard.nextWaypointRoute.Nodes) == 0 {
+		guard.nextWaypointRoute = nil
+		if guard.nextWaypoint != nil && cell.Position == guard.nextWaypoint.position {
+			guard.nextWaypoint = guard.nextWaypoint.next
+		}
+	}
+	if guard.nextWaypointRoute == nil {
+		if guard.mode == Pursuit {
+			guard.nextWaypointRoute = g.planRoute(cell, asCell(plane.Get(guard.target)), guard)
+		} else if guard.nextWaypoint != nil {
+			g.UI.SetStatus(fmt.Sprintf("Guard %d next waypoint: %v", guard.id, guard.nextWaypoint.position))
+			guard.nextWaypointRoute = g.planRoute(cell, asCell(plane.Get(guard.nextWaypoint.position)), guard)
+		}
+	}
+	return g.followRoute(plane, cell, guard, &guard.nextWaypointRoute)
+}
+
+// followRoute moves the unit in the cell one step along its route. Every unit holds a reservation for its position at
+// the next time step, either from its route or by waiting in place, so routes planned by other units never enter it.
+func (g *GuardDuty) followRoute(plane grid.Plane, cell Cell, unit Unit, route **grid.Path) []engine.CellUpdate {
+	now := g.Generation
+	if *route != nil && len((*route).Nodes) > 0 {
+		nodes := (*route).Nodes
+		var tail grid.Node
+		tail, (*route).Nodes = nodes[len(nodes)-1], nodes[:len(nodes)-1]
+		nextPosition := tail.Id().(grid.Position)
+		if nextPosition == cell.Position {
+			// waiting for another unit to pass
+			return []engine.CellUpdate{}
+		}
+		nextCell := asCell(plane.Get(nextPosition))
+
+		if nextCell.State == Barrier || nextCell.Unit != nil || g.reservations.Owner(nextPosition, now+1) != unit {
+			g.reservations.Release(unit, now+1)
+			*route = nil
+		} else {
+			if guard, ok := unit.(*Guard); ok {
+				guard.facing = cell.Position.OrientationTo(nextPosition)
+			}
+			nextCell.Unit = cell.Unit
+			cell.Unit = nil
+			return []engine.CellUpdate{
+				{cell, cell.Position},
+				{nextCell, nextCell.Position},
 			}
 		}
 	}
+	g.reservations.Reserve(cell.Position, now+1, unit)
 	return []engine.CellUpdate{}
 }
 
-func costHuristic(p1, p2 grid.Node) float64 {
-	return p1.(Cell).Position.DistanceTo(p2.(Cell).Position)
+// planRoute finds a route from start to goal that avoids the reservations of the other units and reserves it. The
+// route covers at most reservationWindow steps, after which the unit plans again.
+func (g *GuardDuty) planRoute(start, goal grid.Node, unit Unit) *grid.Path {
+	now := g.Generation
+	g.reservations.Prune(now)
+	g.reservations.Release(unit, now+1)
+	path, ok := grid.FindCooperativePath(start, goal, now, reservationWindow, g.reservations, unit, costHuristic)
+	if !ok {
+		return nil
+	}
+	steps := g.reservations.ReservePath(path, now, reservationWindow, unit)
+	if steps == 0 {
+		// not even the starting position could be reserved, so the unit has no route and plans again later
+		return nil
+	}
+	nodes := path.Nodes
+	// drop the starting position and any steps that could not be reserved
+	first := len(nodes) - steps
+	if first < 0 {
+		first = 0
+	}
+	route := &grid.Path{Nodes: nodes[first : len(nodes)-1]}
+	g.replans++
+	g.pathLength += len(route.Nodes)
+	return route
 }
 
-func findPath(start, goal Cell) (*grid.Path, bool) {
-	return grid.FindPath(start, goal, costHuristic)
+// GenerationMetrics reports the routes planned in the generation, for the -metrics-out flag.
+func (g *GuardDuty) GenerationMetrics() map[string]float64 {
+	values := map[string]float64{
+		"replans":     float64(g.replans),
+		"path_length": float64(g.pathLength),
+		"intruders":   float64(len(g.intruders)),
+	}
+	g.replans, g.pathLength = 0, 0
+	return values
+}
+
+func costHuristic(p1, p2 grid.Node) float64 {
+	return p1.Id().(grid.Position).DistanceTo(p2.Id().(grid.Position))
 }
@@ -0,0 +1,217 @@
+package guardduty
+
+import (
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/nsf/termbox-go"
+	"log"
+	"math"
+)
+
+const visionOverlay = "vision"
+const goalOverlay = "goals"
+
+const visionRadius = 8
+
+var visionHalfAngle = math.Pi / 4
+
+// extra cost for an intruder to step onto a position a guard can see
+const watchedCost = 20
+
+var visionMarker = overlayCell{'░', termbox.ColorYellow}
+var goalMarker = overlayCell{'⚑', termbox.ColorMagenta}
+
+// Intruder tries to sneak to its goal without being seen by the guards.
+type Intruder struct {
+	goal  grid.Position
+	route *grid.Path
+}
+
+// stealthNode wraps a cell to make positions watched by guards expensive, so intruders prefer to stay out of sight.
+type stealthNode struct {
+	Cell
+	vision map[grid.Position]bool
+}
+
+type stealthNeighbor struct {
+	node     stealthNode
+	distance float64
+}
+
+func (n stealthNeighbor) GetNode() grid.Node {
+	return n.node
+}
+
+func (n stealthNeighbor) GetDistance() float64 {
+	return n.distance
+}
+
+func (n stealthNode) GetNeighbors() []grid.Neighbor {
+	neighbors := n.Cell.GetNeighbors()
+	results := make([]grid.Neighbor, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		cell := neighbor.GetNode().(Cell)
+		distance := neighbor.GetDistance()
+		if n.vision[cell.Position] {
+			distance += watchedCost
+		}
+		results = append(results, stealthNeighbor{stealthNode{cell, n.vision}, distance})
+	}
+	return results
+}
+
+func defaultIntruderRoute(w, h int) (entry, goal grid.Position) {
+	return grid.Position{w - 2, h / 2}, grid.Position{1, h / 2}
+}
+
+// updateIntruder moves the intruder one step towards its goal. What the guards can see changes every step, so the
+// intruder plans again every step.
+func (g *GuardDuty) updateIntruder(plane grid.Plane, cell Cell, intruder *Intruder) []engine.CellUpdate {
+	start := stealthNode{cell, g.vision}
+	goal := stealthNode{asCell(plane.Get(intruder.goal)), g.vision}
+	intruder.route = g.planRoute(start, goal, intruder)
+	return g.followRoute(plane, cell, intruder, &intruder.route)
+}
+
+// SpawnIntruder requests a new intruder at the default entry, which enters on the next step.
+func (g *GuardDuty) SpawnIntruder() {
+	select {
+	case g.spawns <- true:
+	default:
+	}
+}
+
+func (g *GuardDuty) spawnIntruder(plane grid.Plane) {
+	bounds := plane.Bounds()
+	entry, goal := defaultIntruderRoute(bounds.Corner2.X+1, bounds.Corner2.Y+1)
+	cell := asCell(plane.Get(entry))
+	if cell.State == Barrier || cell.Unit != nil {
+		g.UI.SetStatus("Intruder entry is blocked")
+		return
+	}
+	intruder := &Intruder{goal: goal}
+	cell.Unit = intruder
+	g.reservations.Reserve(entry, g.Generation, intruder)
+	g.intruders = append(g.intruders, intruder)
+	g.Set(entry, cell)
+	g.refreshGoalOverlay()
+}
+
+// EndGeneration updates what the guards can see. Guards that spot an intruder switch to pursuit, and return to patrol
+// once they reach the position it was last seen at without spotting it again. Intruders next to a pursuing guard are
+// caught, and intruders that reach their goal escape.
+func (g *GuardDuty) EndGeneration(plane grid.Plane) {
+	for pending := true; pending; {
+		select {
+		case <-g.spawns:
+			g.spawnIntruder(plane)
+		default:
+			pending = false
+		}
+	}
+
+	positions := make(map[Unit]grid.Position)
+	bounds := plane.Bounds()
+	for x := bounds.Corner1.X; x <= bounds.Corner2.X; x++ {
+		for y := bounds.Corner1.Y; y <= bounds.Corner2.Y; y++ {
+			p := grid.Position{x, y}
+			if unit := asCell(plane.Get(p)).Unit; unit != nil {
+				positions[unit] = p
+			}
+		}
+	}
+	opaque := func(p grid.Position) bool {
+		return asCell(plane.Get(p)).State == Barrier
+	}
+
+	g.vision = make(map[grid.Position]bool)
+	for _, guard := range g.guards {
+		position := positions[guard]
+		spotted := false
+		for _, p := range grid.VisionCone(position, guard.facing, visionRadius, visionHalfAngle, bounds, opaque) {
+			g.vision[p] = true
+			if _, ok := asCell(plane.Get(p)).Unit.(*Intruder); ok {
+				if guard.mode == Patrol {
+					g.UI.SetStatus(fmt.Sprintf("Guard %d spotted an intruder at %v", guard.id, p))
+				}
+				guard.mode = Pursuit
+				guard.target = p
+				spotted = true
+			}
+		}
+		if guard.mode == Pursuit && !spotted && position == guard.target {
+			g.UI.SetStatus(fmt.Sprintf("Guard %d lost the intruder", guard.id))
+			guard.mode = Patrol
+			guard.replan = true
+		}
+	}
+
+	remaining := make([]*Intruder, 0, len(g.intruders))
+	for _, intruder := range g.intruders {
+		position, ok := positions[intruder]
+		if !ok {
+			continue
+		}
+		if position == intruder.goal {
+			log.Printf("Intruder escaped at %v\n", position)
+			g.UI.SetStatus("An intruder escaped!")
+			g.removeUnit(plane, intruder, position)
+			continue
+		}
+		if guard := g.pursuerNextTo(position, positions); guard != nil {
+			log.Printf("Guard %d caught intruder at %v\n", guard.id, position)
+			g.UI.SetStatus(fmt.Sprintf("Guard %d caught an intruder", guard.id))
+			g.removeUnit(plane, intruder, position)
+			for _, guard := range g.guards {
+				if guard.mode == Pursuit {
+					guard.mode = Patrol
+					guard.replan = true
+				}
+			}
+			continue
+		}
+		remaining = append(remaining, intruder)
+	}
+	if len(remaining) != len(g.intruders) {
+		g.intruders = remaining
+		g.refreshGoalOverlay()
+	}
+	g.refreshVisionOverlay(plane)
+}
+
+func (g *GuardDuty) pursuerNextTo(position grid.Position, positions map[Unit]grid.Position) *Guard {
+	for _, guard := range g.guards {
+		p := positions[guard]
+		if guard.mode == Pursuit && math.Abs(float64(p.X-position.X))+math.Abs(float64(p.Y-position.Y)) == 1 {
+			return guard
+		}
+	}
+	return nil
+}
+
+func (g *GuardDuty) removeUnit(plane grid.Plane, unit Unit, position grid.Position) {
+	cell := asCell(plane.Get(position))
+	cell.Unit = nil
+	g.Set(position, cell)
+	g.reservations.Release(unit, 0)
+}
+
+func (g *GuardDuty) refreshVisionOverlay(plane grid.Plane) {
+	overlay := make(map[grid.Position]grid.Cell, len(g.vision))
+	for p := range g.vision {
+		// keep units and barriers visible
+		if cell := asCell(plane.Get(p)); cell.Unit == nil && cell.State != Barrier {
+			overlay[p] = visionMarker
+		}
+	}
+	g.UI.SetOverlay(visionOverlay, overlay)
+}
+
+func (g *GuardDuty) refreshGoalOverlay() {
+	overlay := make(map[grid.Position]grid.Cell, len(g.intruders))
+	for _, intruder := range g.intruders {
+		overlay[intruder.goal] = goalMarker
+	}
+	g.UI.SetOverlay(goalOverlay, overlay)
+}
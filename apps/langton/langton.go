@@ -1,10 +1,12 @@
 package langton
 
 import (
+	"flag"
 	"fmt"
 	"github.com/jpbetz/cellularautomata/engine"
 	"github.com/jpbetz/cellularautomata/grid"
 	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
 	"github.com/nsf/termbox-go"
 	"log"
 	"os"
@@ -16,19 +18,39 @@ type LangtonCommand struct {
 }
 
 func (c *LangtonCommand) Help() string {
-	return "Langton's Ants simulates an ant that walks a route that depends on state of ground cells."
+	return `Langton's Ants simulates an ant that walks a route that depends on state of ground cells.
+
+Options:
+  -conflicts=P  What happens when ants write to the same square in one generation: merge (default) keeps both the
+                color flipped by an ant leaving and the ant arriving, first or last keeps the write of the first or
+                last ant, and error stops the simulation.
+  -debug        Log conflicting writes and mark them on the board.
+` + metrics.Usage
 }
 
 func (c *LangtonCommand) Run(args []string) int {
-	langtonMain(c.UI)
-	return 0
+	flags := flag.NewFlagSet("langton", flag.ContinueOnError)
+	conflicts := flags.String("conflicts", "merge", "")
+	debug := flags.Bool("debug", false, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	policy, err := engine.ParseConflictPolicy(*conflicts)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		langtonMain(c.UI, policy, *debug, sink)
+	})
 }
 
 func (c *LangtonCommand) Synopsis() string {
 	return "Langton's Ants"
 }
 
-func langtonMain(ui io.Renderer) {
+func langtonMain(ui io.Renderer, policy engine.ConflictPolicy, debug bool, sink *metrics.Sink) {
 	f := setupLogging("logs/langton.log")
 	defer f.Close()
 
@@ -39,6 +61,9 @@ func langtonMain(ui io.Renderer) {
 	ui.SetView(view)
 	board.Initialize(Square{})
 	game := NewAnts(board, ui)
+	sink.Observe(game.Engine)
+	game.Conflicts = policy
+	game.Debug = debug
 	eventClock := game.StartClock()
 	game.Playing = true
 
@@ -47,21 +72,24 @@ func langtonMain(ui io.Renderer) {
 	go func() {
 		for {
 			in := <-ui.Input()
-			switch in.(type) {
-			case io.Quit:
+			if _, ok := in.(io.Quit); ok {
 				done <- true
 				return
-			case io.Click:
-
-			case io.Pause:
-				if game.Playing {
-					eventClock.Stop()
-					game.Playing = false
-				} else {
-					eventClock = game.StartClock()
-					game.Playing = true
-				}
 			}
+			game.Edit(func() {
+				switch in.(type) {
+				case io.Click:
+
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
 		}
 	}()
 
@@ -86,6 +114,9 @@ type Ant struct {
 	orientation grid.Orientation
 }
 
+// ants are the ants facing each way, shared by every square so that squares holding the same ant are equal.
+var ants = []*Ant{{grid.Up}, {grid.Right}, {grid.Down}, {grid.Left}}
+
 type Square struct {
 	White bool
 	Ant   *Ant
@@ -140,7 +171,7 @@ func asSquare(cell grid.Cell) Square {
 	return life
 }
 
-var AntStart = Square{Ant: &Ant{}}
+var AntStart = Square{Ant: ants[grid.Up]}
 var Default = Square{}
 
 func NewAnts(plane grid.Plane, ui io.Renderer) *Ants {
@@ -159,7 +190,39 @@ func (g *Ants) initialize() {
 	g.UI.SetStatus("Langton's Ants")
 }
 
-func (g *Ants) UpdateCell(plane grid.Plane, position grid.Position) []engine.CellUpdate {
+// States names the squares for the control API, e.g. "white" or "ant left on black".
+func (g *Ants) States() map[string]grid.Cell {
+	states := map[string]grid.Cell{}
+	for _, cell := range g.PatternStates() {
+		square := asSquare(cell)
+		color := "black"
+		if square.White {
+			color = "white"
+		}
+		if square.Ant == nil {
+			states[color] = square
+		} else {
+			states[fmt.Sprintf("ant %s on %s", orientationNames[square.Ant.orientation], color)] = square
+		}
+	}
+	return states
+}
+
+var orientationNames = []string{"up", "right", "down", "left"}
+
+// PatternStates numbers the black and white squares, then the squares with ants facing up, right, down and left on
+// black, then the same on white.
+func (g *Ants) PatternStates() []grid.Cell {
+	states := []grid.Cell{Square{}, Square{White: true}}
+	for _, white := range []bool{false, true} {
+		for _, ant := range ants {
+			states = append(states, Square{White: white, Ant: ant})
+		}
+	}
+	return states
+}
+
+func (g *Ants) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
 
 	if !plane.Bounds().Contains(position) {
 		return []engine.CellUpdate{}
@@ -183,10 +246,24 @@ func (g *Ants) UpdateCell(plane grid.Plane, position grid.Position) []engine.Cel
 		}
 
 		nextCell := asSquare(plane.Get(nextPosition))
-		updatedNextCell := Square{Ant: &Ant{nextOrientation}, White: nextCell.White}
+		updatedNextCell := Square{Ant: ants[nextOrientation], White: nextCell.White}
 
 		return []engine.CellUpdate{{updatedCell, position}, {updatedNextCell, nextPosition}}
 	} else {
 		return []engine.CellUpdate{}
 	}
 }
+
+// MergeUpdates combines an ant leaving a square, which flips its color, with an ant arriving on it. Only one ant fits
+// on a square, so when two arrive at once the first stays.
+func (g *Ants) MergeUpdates(position grid.Position, first, second grid.Cell) grid.Cell {
+	a, b := asSquare(first), asSquare(second)
+	merged := Square{White: a.White, Ant: a.Ant}
+	if a.Ant != nil {
+		merged.White = b.White
+	}
+	if merged.Ant == nil {
+		merged.Ant = b.Ant
+	}
+	return merged
+}
@@ -0,0 +1,265 @@
+package largerthanlife
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"github.com/nsf/termbox-go"
+	"log"
+	"os"
+	"time"
+)
+
+type LargerThanLifeCommand struct {
+	UI io.Renderer
+}
+
+func (c *LargerThanLifeCommand) Help() string {
+	return `Larger than Life extends Life-like rules to neighborhoods of any range. Rules are given in the LtL/HROT
+notation used by Golly. Click a cell to toggle it.
+
+Options:
+  -rule=RULE    Rule such as R5,C0,M1,S34..58,B34..45,NM for Bosco's rule (default), R10,C0,M1,S123..212,B123..170,NM
+                for Majority or R7,C0,M1,S113..225,B113..225,NM for Waffle.
+  -width=W      Width of the board (default 120).
+  -height=H     Height of the board (default 80).
+  -soup=N       Size of the random square seeded in the middle of the board (default 40).
+  -density=D    Fraction of live cells in the soup (default 0.5).
+  -seed=N       Seed for the random numbers, so that runs with the same seed are identical. The seed of every run
+                is written to the log (default from the clock).
+` + metrics.Usage
+}
+
+func (c *LargerThanLifeCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("ltl", flag.ContinueOnError)
+	ruleString := flags.String("rule", "R5,C0,M1,S34..58,B34..45,NM", "")
+	width := flags.Int("width", 120, "")
+	height := flags.Int("height", 80, "")
+	soup := flags.Int("soup", 40, "")
+	density := flags.Float64("density", 0.5, "")
+	seed := flags.Int64("seed", 0, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	rule, err := ParseRule(*ruleString)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		largerThanLifeMain(c.UI, rule, *width, *height, *soup, *density, engine.ChooseSeed(*seed), sink)
+	})
+}
+
+func (c *LargerThanLifeCommand) Synopsis() string {
+	return "Larger than Life rules with neighborhoods of any range"
+}
+
+func largerThanLifeMain(ui io.Renderer, rule Rule, width, height, soup int, density float64, seed int64,
+	sink *metrics.Sink) {
+	f := setupLogging("logs/largerthanlife.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	ui.Run()
+
+	board := grid.NewBasicBoard(width, height)
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	board.Initialize(Cell{State: Dead, States: rule.States})
+	game := NewLargerThanLife(board, ui, rule, soup, density, seed)
+	sink.Observe(game.Engine)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					cell := game.Toggle(game.Plane, event.Position)
+					if cell != nil {
+						ui.Draw()
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+const Dead = 0
+const Alive = 1
+
+const ActiveRune = '█'
+const EmptyRune = ' '
+
+var AliveColor = grid.Color{R: 0x5e, G: 0xd6, B: 0xff}
+var DyingColor = grid.Color{R: 0x8a, G: 0x4c, B: 0xff}
+var FadedColor = grid.Color{R: 0x1c, G: 0x14, B: 0x33}
+
+type Cell struct {
+	State  int
+	States int
+}
+
+func (c Cell) Rune() rune {
+	if c.State == Dead {
+		return EmptyRune
+	}
+	return ActiveRune
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	switch c.State {
+	case Dead:
+		return termbox.ColorDefault
+	case Alive:
+		return termbox.ColorCyan
+	default:
+		return termbox.ColorBlue
+	}
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+// Color fades dying cells towards the background as they approach the dead state.
+func (c Cell) Color() grid.Color {
+	switch c.State {
+	case Dead:
+		return grid.Color{R: 0x0e, G: 0x0e, B: 0x0e}
+	case Alive:
+		return AliveColor
+	default:
+		dyingStates := c.States - 2
+		if dyingStates <= 1 {
+			return DyingColor
+		}
+		return grid.Gradient(DyingColor, FadedColor, float64(c.State-2)/float64(dyingStates-1))
+	}
+}
+
+// LargerThanLife counts live neighbors with a summed area table built once per generation, so the cost of counting
+// grows with the number of rows in the neighborhood rather than the number of cells in it.
+type LargerThanLife struct {
+	*engine.Engine
+	Rule       Rule
+	rectangles []grid.Rectangle
+
+	// live cell counts, rebuilt whenever the generation changes
+	live           *grid.SummedAreaTable
+	liveGeneration int
+}
+
+func asCell(cell grid.Cell) Cell {
+	ltlCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected Larger than Life cell")
+	}
+	return ltlCell
+}
+
+func NewLargerThanLife(plane grid.Plane, ui io.Renderer, rule Rule, soup int, density float64,
+	seed int64) *LargerThanLife {
+	game := &LargerThanLife{
+		Engine:         &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100, Seed: seed},
+		Rule:           rule,
+		rectangles:     rule.Neighborhood.Rectangles(),
+		liveGeneration: -1,
+	}
+	game.Engine.Handler = game
+	game.initialize(soup, density)
+	return game
+}
+
+// initialize seeds a random soup in the middle of the board.
+func (g *LargerThanLife) initialize(soup int, density float64) {
+	random := g.InitialRandom()
+	bounds := g.Plane.Bounds()
+	center := grid.Position{(bounds.Corner1.X + bounds.Corner2.X) / 2, (bounds.Corner1.Y + bounds.Corner2.Y) / 2}
+	for x := center.X - soup/2; x < center.X-soup/2+soup; x++ {
+		for y := center.Y - soup/2; y < center.Y-soup/2+soup; y++ {
+			if random.Float64() < density {
+				g.Set(grid.Position{x, y}, Cell{State: Alive, States: g.Rule.States})
+			}
+		}
+	}
+	g.UI.SetStatus(fmt.Sprintf("Larger than Life %s", g.Rule))
+}
+
+func (g *LargerThanLife) liveCounts(plane grid.Plane) *grid.SummedAreaTable {
+	if g.liveGeneration != g.Generation {
+		g.live = grid.NewSummedAreaTable(plane, func(cell grid.Cell) int {
+			if asCell(cell).State == Alive {
+				return 1
+			}
+			return 0
+		})
+		g.liveGeneration = g.Generation
+	}
+	return g.live
+}
+
+// IsAlive lets the engine count the population, births and deaths of every generation.
+func (g *LargerThanLife) IsAlive(cell grid.Cell) bool {
+	return asCell(cell).State == Alive
+}
+
+func (g *LargerThanLife) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	if !plane.Bounds().Contains(position) {
+		return []engine.CellUpdate{}
+	}
+
+	cell := asCell(plane.Get(position))
+	liveCount := g.liveCounts(plane).NeighborhoodSum(position, g.rectangles)
+	next := g.Rule.Next(cell.State, liveCount)
+	if next == cell.State {
+		return []engine.CellUpdate{}
+	}
+	cell.State = next
+	return []engine.CellUpdate{{cell, position}}
+}
+
+func (g *LargerThanLife) Toggle(plane grid.Plane, position grid.Position) grid.Cell {
+	if !plane.Bounds().Contains(position) {
+		return nil
+	}
+	cell := asCell(plane.Get(position))
+	if cell.State == Dead {
+		g.Set(position, Cell{State: Alive, States: g.Rule.States})
+	} else {
+		g.Set(position, Cell{State: Dead, States: g.Rule.States})
+	}
+	return cell
+}
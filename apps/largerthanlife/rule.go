@@ -0,0 +1,195 @@
+package largerthanlife
+
+import (
+	"fmt"
+	"github.com/jpbetz/cellularautomata/grid"
+	"strconv"
+	"strings"
+)
+
+// Rule is a Larger than Life rule, a Life-like rule over a neighborhood of any range. Rules with more than 2 states
+// decay like Generations rules: live cells that do not survive pass through States-2 dying states, and dying cells
+// are not counted as live neighbors.
+type Rule struct {
+	Range  int
+	States int
+	// Middle is true if a cell counts itself as one of its neighbors
+	Middle bool
+	// Survive and Birth are indexed by the number of live cells in the neighborhood
+	Survive []bool
+	Birth   []bool
+	// Shape is the HROT letter of the neighborhood, M for Moore, N for von Neumann, + for cross or H for hexagonal
+	Shape        byte
+	Neighborhood grid.Neighborhood
+}
+
+var shapes = map[byte]func(r int) grid.Neighborhood{
+	'M': grid.Moore,
+	'N': grid.VonNeumann,
+	'+': grid.Cross,
+	'H': grid.Hexagonal,
+}
+
+// ParseRule parses a rule in the LtL/HROT notation used by Golly, e.g. "R5,C0,M1,S34..58,B34..45,NM" for Bosco's
+// rule. Survival and birth counts are comma separated lists of counts and ranges, where ranges are written a..b or
+// a-b. C0 and C1 both mean 2 states, and the neighborhood defaults to Moore if N is left out.
+func ParseRule(rule string) (Rule, error) {
+	r := Rule{Range: 1, States: 2, Shape: 'M'}
+	var survive, birth [][2]int
+	var list *[][2]int
+	seen := make(map[byte]bool)
+	for _, field := range strings.Split(strings.ToUpper(strings.Replace(rule, " ", "", -1)), ",") {
+		if field == "" {
+			return Rule{}, fmt.Errorf("rule %q has an empty field", rule)
+		}
+		key := field[0]
+		if key >= '0' && key <= '9' {
+			// another count or range in the list started by the last S or B
+			if list == nil {
+				return Rule{}, fmt.Errorf("rule %q has counts %q outside of an S or B list", rule, field)
+			}
+			if err := appendCounts(list, field); err != nil {
+				return Rule{}, fmt.Errorf("rule %q: %v", rule, err)
+			}
+			continue
+		}
+		if seen[key] {
+			return Rule{}, fmt.Errorf("rule %q has more than one %c field", rule, key)
+		}
+		seen[key] = true
+		value := field[1:]
+		list = nil
+		var err error
+		switch key {
+		case 'R':
+			r.Range, err = strconv.Atoi(value)
+			if err == nil && r.Range < 1 {
+				err = fmt.Errorf("range must be at least 1")
+			}
+		case 'C':
+			r.States, err = strconv.Atoi(value)
+			if r.States < 2 {
+				r.States = 2
+			}
+		case 'M':
+			if value != "0" && value != "1" {
+				err = fmt.Errorf("M must be 0 or 1")
+			}
+			r.Middle = value == "1"
+		case 'S', 'B':
+			list = &survive
+			if key == 'B' {
+				list = &birth
+			}
+			if value != "" {
+				err = appendCounts(list, value)
+			}
+		case 'N':
+			if len(value) != 1 || shapes[value[0]] == nil {
+				err = fmt.Errorf("neighborhood %q must be one of M, N, + or H", value)
+			} else {
+				r.Shape = value[0]
+			}
+		default:
+			err = fmt.Errorf("unknown field %q", field)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule %q: %v", rule, err)
+		}
+	}
+	if !seen['B'] {
+		return Rule{}, fmt.Errorf("rule %q must have birth counts", rule)
+	}
+
+	r.Neighborhood = shapes[r.Shape](r.Range)
+	if r.Middle {
+		r.Neighborhood.Offsets = append(r.Neighborhood.Offsets, grid.Origin)
+	}
+	max := len(r.Neighborhood.Offsets)
+	r.Survive = make([]bool, max+1)
+	r.Birth = make([]bool, max+1)
+	if err := setCounts(r.Survive, survive); err != nil {
+		return Rule{}, fmt.Errorf("rule %q has invalid survival counts: %v", rule, err)
+	}
+	if err := setCounts(r.Birth, birth); err != nil {
+		return Rule{}, fmt.Errorf("rule %q has invalid birth counts: %v", rule, err)
+	}
+	return r, nil
+}
+
+func appendCounts(list *[][2]int, value string) error {
+	bounds := strings.SplitN(value, "..", 2)
+	if len(bounds) == 1 {
+		bounds = strings.SplitN(value, "-", 2)
+	}
+	low, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return fmt.Errorf("invalid count %q", value)
+	}
+	high := low
+	if len(bounds) == 2 {
+		if high, err = strconv.Atoi(bounds[1]); err != nil || high < low {
+			return fmt.Errorf("invalid range %q", value)
+		}
+	}
+	*list = append(*list, [2]int{low, high})
+	return nil
+}
+
+func setCounts(counts []bool, ranges [][2]int) error {
+	for _, r := range ranges {
+		if r[1] >= len(counts) {
+			return fmt.Errorf("count %d is larger than the neighborhood, which has %d cells", r[1], len(counts)-1)
+		}
+		for i := r[0]; i <= r[1]; i++ {
+			counts[i] = true
+		}
+	}
+	return nil
+}
+
+func (r Rule) String() string {
+	middle := 0
+	if r.Middle {
+		middle = 1
+	}
+	return fmt.Sprintf("R%d,C%d,M%d,S%s,B%s,N%c", r.Range, r.States, middle, formatCounts(r.Survive),
+		formatCounts(r.Birth), r.Shape)
+}
+
+// formatCounts writes the counts as a list of ranges, e.g. 34..58 or 2,4..5.
+func formatCounts(counts []bool) string {
+	var ranges []string
+	for i := 0; i < len(counts); i++ {
+		if !counts[i] {
+			continue
+		}
+		j := i
+		for j+1 < len(counts) && counts[j+1] {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(i))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d..%d", i, j))
+		}
+		i = j
+	}
+	return strings.Join(ranges, ",")
+}
+
+// Next returns the state that follows the given state when liveCount cells of its neighborhood are in the live state.
+func (r Rule) Next(state int, liveCount int) int {
+	switch state {
+	case Dead:
+		if r.Birth[liveCount] {
+			return Alive
+		}
+		return Dead
+	case Alive:
+		if r.Survive[liveCount] {
+			return Alive
+		}
+	}
+	return (state + 1) % r.States
+}
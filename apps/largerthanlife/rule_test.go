@@ -0,0 +1,54 @@
+package largerthanlife
+
+import (
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("R5,C0,M1,S34..58,B34..45,NM")
+	if err != nil {
+		t.Fatalf("Expected rule to parse, but got: %v", err)
+	}
+	if rule.Range != 5 || rule.States != 2 || !rule.Middle || len(rule.Neighborhood.Offsets) != 121 {
+		t.Errorf("Unexpected rule: %#v", rule)
+	}
+	if !rule.Survive[34] || !rule.Survive[58] || rule.Survive[59] || !rule.Birth[45] || rule.Birth[33] {
+		t.Errorf("Unexpected counts: %#v", rule)
+	}
+	if rule.String() != "R5,C2,M1,S34..58,B34..45,NM" {
+		t.Errorf("Expected R5,C2,M1,S34..58,B34..45,NM but found %s", rule.String())
+	}
+
+	rule, err = ParseRule("R2,C3,S2,4-5,B3,N+")
+	if err != nil {
+		t.Fatalf("Expected rule to parse, but got: %v", err)
+	}
+	if rule.String() != "R2,C3,M0,S2,4..5,B3,N+" {
+		t.Errorf("Expected R2,C3,M0,S2,4..5,B3,N+ but found %s", rule.String())
+	}
+
+	for _, invalid := range []string{"", "R2,S2", "R1,S2..3,B3..9,NM", "R0,B3", "R2,B3,NX", "R2,B5..3", "3,R2,B3", "R2,R3,B3"} {
+		if _, err := ParseRule(invalid); err == nil {
+			t.Errorf("Expected rule %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	rule, _ := ParseRule("R1,C3,M0,S2..3,B3,NM")
+	transitions := []struct {
+		state, liveCount, next int
+	}{
+		{Dead, 3, Alive},
+		{Dead, 2, Dead},
+		{Alive, 2, Alive},
+		{Alive, 4, 2},
+		{2, 3, Dead},
+	}
+	for _, transition := range transitions {
+		if next := rule.Next(transition.state, transition.liveCount); next != transition.next {
+			t.Errorf("Expected state %d with %d live neighbors to become %d but found %d",
+				transition.state, transition.liveCount, transition.next, next)
+		}
+	}
+}
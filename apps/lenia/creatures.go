@@ -0,0 +1,213 @@
+package lenia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Creature is a pattern together with the parameters it lives in. Files use the same layout, as JSON with the keys
+// name, R, T, m, s, b and cells, e.g. as exported from the Lenia tutorials.
+type Creature struct {
+	Name  string      `json:"name"`
+	R     int         `json:"R"`
+	T     int         `json:"T"`
+	Mu    float64     `json:"m"`
+	Sigma float64     `json:"s"`
+	Peaks []float64   `json:"b"`
+	Cells [][]float64 `json:"cells"`
+}
+
+func (c Creature) Params() Params {
+	return Params{R: c.R, T: c.T, Mu: c.Mu, Sigma: c.Sigma, Peaks: c.Peaks}
+}
+
+// Orbium is the glider of Lenia, which moves steadily in a straight line.
+var Orbium = Creature{
+	Name: "Orbium", R: 13, T: 10, Mu: 0.15, Sigma: 0.015, Peaks: []float64{1},
+	Cells: [][]float64{
+		{0, 0, 0, 0, 0, 0, 0.1, 0.14, 0.1, 0, 0, 0.03, 0.03, 0, 0, 0.3, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0.08, 0.24, 0.3, 0.3, 0.18, 0.14, 0.15, 0.16, 0.15, 0.09, 0.2, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0.15, 0.34, 0.44, 0.46, 0.38, 0.18, 0.14, 0.11, 0.13, 0.19, 0.18, 0.45, 0, 0, 0},
+		{0, 0, 0, 0, 0.06, 0.13, 0.39, 0.5, 0.5, 0.37, 0.06, 0, 0, 0, 0.02, 0.16, 0.68, 0, 0, 0},
+		{0, 0, 0, 0.11, 0.17, 0.17, 0.33, 0.4, 0.38, 0.28, 0.14, 0, 0, 0, 0, 0, 0.18, 0.42, 0, 0},
+		{0, 0, 0.09, 0.18, 0.13, 0.06, 0.08, 0.26, 0.32, 0.32, 0.27, 0, 0, 0, 0, 0, 0, 0.82, 0, 0},
+		{0.27, 0, 0.16, 0.12, 0, 0, 0, 0.25, 0.38, 0.44, 0.45, 0.34, 0, 0, 0, 0, 0, 0.22, 0.17, 0},
+		{0, 0.07, 0.2, 0.02, 0, 0, 0, 0.31, 0.48, 0.57, 0.6, 0.57, 0, 0, 0, 0, 0, 0, 0.49, 0},
+		{0, 0.59, 0.19, 0, 0, 0, 0, 0.2, 0.57, 0.69, 0.76, 0.76, 0.49, 0, 0, 0, 0, 0, 0.36, 0},
+		{0, 0.58, 0.19, 0, 0, 0, 0, 0, 0.67, 0.83, 0.9, 0.92, 0.87, 0.12, 0, 0, 0, 0, 0.22, 0.07},
+		{0, 0, 0.46, 0, 0, 0, 0, 0, 0.7, 0.93, 1, 1, 1, 0.61, 0, 0, 0, 0, 0.18, 0.11},
+		{0, 0, 0.82, 0, 0, 0, 0, 0, 0.47, 1, 1, 0.98, 1, 0.96, 0.27, 0, 0, 0, 0.19, 0.1},
+		{0, 0, 0.46, 0, 0, 0, 0, 0, 0.25, 1, 1, 0.84, 0.92, 0.97, 0.54, 0.14, 0.04, 0.1, 0.21, 0.05},
+		{0, 0, 0, 0.4, 0, 0, 0, 0, 0.09, 0.8, 1, 0.82, 0.8, 0.85, 0.63, 0.31, 0.18, 0.19, 0.2, 0.01},
+		{0, 0, 0, 0.36, 0.1, 0, 0, 0, 0.05, 0.54, 0.86, 0.79, 0.74, 0.72, 0.6, 0.39, 0.28, 0.24, 0.13, 0},
+		{0, 0, 0, 0.01, 0.3, 0.07, 0, 0, 0.08, 0.36, 0.64, 0.7, 0.64, 0.6, 0.51, 0.39, 0.29, 0.19, 0.04, 0},
+		{0, 0, 0, 0, 0.1, 0.24, 0.14, 0.1, 0.15, 0.29, 0.45, 0.53, 0.52, 0.46, 0.4, 0.31, 0.21, 0.08, 0, 0},
+		{0, 0, 0, 0, 0, 0.08, 0.21, 0.21, 0.22, 0.29, 0.36, 0.39, 0.37, 0.33, 0.26, 0.18, 0.09, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0.03, 0.13, 0.19, 0.22, 0.24, 0.24, 0.23, 0.18, 0.13, 0.05, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0.02, 0.06, 0.08, 0.09, 0.07, 0.05, 0.01, 0, 0, 0, 0, 0},
+	},
+}
+
+// The creatures below were found by running random patches under the parameters given, and keeping the patterns they
+// settled into that neither died out nor spread. The values are rounded to two places like those of Orbium.
+
+// Ring is a thick ring that stays in place, living in a kernel of a single ring.
+var Ring = Creature{
+	Name: "Ring", R: 13, T: 10, Mu: 0.28, Sigma: 0.04, Peaks: []float64{1},
+	Cells: [][]float64{
+		{0, 0, 0, 0.01, 0.17, 0.33, 0.42, 0.4, 0.21, 0, 0, 0, 0},
+		{0, 0, 0.13, 0.44, 1, 1, 1, 1, 1, 1, 0.17, 0, 0},
+		{0, 0.13, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0.27, 0},
+		{0.01, 0.44, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0},
+		{0.17, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+		{0.33, 1, 1, 1, 1, 1, 0.73, 0.15, 0, 0.79, 1, 1, 1},
+		{0.42, 1, 1, 1, 1, 0.73, 0, 0, 0, 0, 1, 1, 1},
+		{0.4, 1, 1, 1, 1, 0.15, 0, 0, 0, 0, 0.98, 1, 1},
+		{0.21, 1, 1, 1, 1, 0, 0, 0, 0, 0, 1, 1, 1},
+		{0, 1, 1, 1, 1, 0.79, 0, 0, 0, 0.88, 1, 1, 1},
+		{0, 0.17, 1, 1, 1, 1, 1, 0.98, 1, 1, 1, 1, 0},
+		{0, 0, 0.27, 1, 1, 1, 1, 1, 1, 1, 1, 0.16, 0},
+		{0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 0, 0, 0},
+	},
+}
+
+// Eye is a ring around a smaller core, living in a kernel of two rings with the outer one half as high. It drifts
+// slowly in a straight line.
+var Eye = Creature{
+	Name: "Eye", R: 13, T: 10, Mu: 0.3, Sigma: 0.05, Peaks: []float64{1, 0.5},
+	Cells: [][]float64{
+		{0, 0, 0, 0, 0, 0.07, 0.29, 0.42, 0.4, 0.22, 0.06, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0.01, 0.57, 1, 1, 1, 1, 0.82, 0.4, 0.17, 0.03, 0, 0, 0},
+		{0, 0, 0.02, 0.77, 1, 1, 1, 1, 1, 1, 1, 1, 0.52, 0.02, 0, 0},
+		{0, 0, 0.6, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0.83, 0.29, 0},
+		{0, 0.01, 1, 1, 1, 1, 0.88, 0.84, 0.76, 0.58, 0.98, 1, 1, 1, 0.86, 0.16},
+		{0, 0.56, 1, 1, 1, 0.36, 0, 0, 0.07, 0, 0, 0, 1, 1, 0.99, 0.45},
+		{0, 1, 1, 1, 0.33, 0, 0.01, 0.92, 1, 0.64, 0, 0, 0, 1, 1, 0.63},
+		{0, 1, 1, 1, 0, 0, 1, 1, 0.86, 1, 1, 0.13, 0, 1, 1, 0.9},
+		{0.05, 1, 1, 1, 0, 0, 1, 0.42, 0, 0, 1, 0.9, 0, 1, 1, 0.91},
+		{0.12, 1, 1, 1, 0, 0, 1, 0.39, 0, 0, 1, 0.94, 0, 1, 1, 0.82},
+		{0, 1, 1, 1, 0.5, 0, 1, 1, 0.98, 1, 1, 0.34, 0.12, 1, 1, 0.51},
+		{0, 0.35, 1, 1, 1, 0, 0, 0.99, 1, 0.98, 0.58, 0, 0.59, 1, 1, 0},
+		{0, 0, 0.9, 1, 1, 1, 0.04, 0, 0, 0, 0, 0.39, 1, 1, 0.69, 0},
+		{0, 0, 0, 0.49, 1, 1, 1, 0.29, 0.09, 0.14, 1, 1, 1, 0.69, 0, 0},
+		{0, 0, 0, 0, 0.25, 0.97, 1, 1, 1, 1, 1, 0.97, 0.5, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0.46, 0.64, 0.72, 0.63, 0.38, 0.15, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0.08, 0.06, 0, 0, 0, 0, 0, 0},
+	},
+}
+
+// Crescent glides in a straight line like Orbium, though more slowly, in a kernel of two equal rings.
+var Crescent = Creature{
+	Name: "Crescent", R: 13, T: 10, Mu: 0.26, Sigma: 0.04, Peaks: []float64{1, 1},
+	Cells: [][]float64{
+		{0, 0, 0, 0, 0, 0, 0, 0.02, 0.12, 0.2, 0.23, 0.21, 0.15, 0.06, 0.01, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0.01, 0.22, 0.54, 0.74, 0.8, 0.8, 0.74, 0.65, 0.51, 0.32, 0.11, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0.05, 0.52, 1, 1, 1, 1, 1, 1, 1, 0.92, 0.76, 0.53, 0.22, 0.01, 0, 0},
+		{0, 0, 0, 0.09, 0.74, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0.87, 0.6, 0.24, 0, 0},
+		{0, 0, 0.06, 0.88, 1, 1, 1, 1, 1, 1, 0.88, 0.9, 0.97, 1, 1, 1, 0.88, 0.58, 0.15, 0},
+		{0, 0, 0.86, 1, 1, 1, 1, 1, 1, 0.78, 0.59, 0.67, 0.81, 0.94, 0.99, 1, 1, 0.83, 0.43, 0.02},
+		{0, 0.51, 1, 1, 1, 1, 1, 0.93, 0.68, 0.29, 0.2, 0.31, 0.51, 0.72, 0.88, 0.97, 1, 0.99, 0.69, 0.15},
+		{0, 0.96, 0.97, 1, 1, 1, 0.66, 0.38, 0, 0, 0, 0, 0.16, 0.42, 0.71, 0.93, 1, 1, 0.89, 0.33},
+		{0.54, 0.76, 0.79, 1, 1, 0.49, 0, 0, 0, 0.02, 0.12, 0.02, 0, 0.14, 0.58, 0.99, 1, 1, 1, 0.47},
+		{0.49, 0.42, 0.62, 0.99, 1, 0, 0, 0, 0, 0.69, 0.57, 0.32, 0, 0, 0.55, 1, 1, 1, 1, 0.53},
+		{0.09, 0.07, 0.49, 0.97, 0.26, 0, 0, 0, 0.84, 0.99, 0.69, 0.56, 0, 0, 0.7, 1, 1, 1, 1, 0.5},
+		{0, 0, 0.38, 0.93, 0.34, 0, 0, 0, 0.99, 1, 1, 0.28, 0, 0, 0.69, 1, 1, 1, 1, 0.38},
+		{0, 0, 0.24, 0.46, 0.47, 0, 0, 0.37, 0.79, 0.96, 0.91, 0, 0, 0, 0.68, 1, 1, 1, 1, 0.18},
+		{0, 0, 0, 0.24, 0.52, 0.12, 0, 0.1, 0.41, 0.52, 0, 0, 0, 0, 0.89, 1, 1, 1, 1, 0.03},
+		{0, 0, 0, 0.04, 0.47, 0.36, 0, 0, 0, 0, 0, 0, 0, 0.25, 1, 1, 1, 1, 0.59, 0},
+		{0, 0, 0, 0, 0.34, 0.51, 0.21, 0.01, 0, 0, 0, 0.01, 0.14, 1, 1, 0.99, 1, 1, 0.08, 0},
+		{0, 0, 0, 0, 0.11, 0.45, 0.5, 0.42, 0.37, 0.39, 0.44, 0.56, 1, 0.93, 0.81, 0.83, 0.97, 0.58, 0, 0},
+		{0, 0, 0, 0, 0, 0.19, 0.37, 0.43, 0.45, 0.43, 0.38, 0.43, 0.6, 0.44, 0.41, 0.68, 0.89, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0.12, 0, 0, 0.15, 0.55, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0.07, 0.02, 0, 0, 0, 0},
+	},
+}
+
+// Creatures are the creatures that can be loaded by name, keyed by lower case name.
+var Creatures = map[string]Creature{
+	"orbium":   Orbium,
+	"ring":     Ring,
+	"eye":      Eye,
+	"crescent": Crescent,
+	// two orbiums on a collision course
+	"orbium-pair": pair(Orbium, 12),
+}
+
+// pair places a creature and a copy rotated half a turn side by side, gap cells apart.
+func pair(c Creature, gap int) Creature {
+	h, w := len(c.Cells), len(c.Cells[0])
+	cells := make([][]float64, h)
+	for y := range cells {
+		cells[y] = make([]float64, 2*w+gap)
+		copy(cells[y], c.Cells[y])
+		for x := 0; x < w; x++ {
+			cells[y][w+gap+x] = c.Cells[h-1-y][w-1-x]
+		}
+	}
+	pair := c
+	pair.Name = c.Name + " pair"
+	pair.Cells = cells
+	return pair
+}
+
+// CreatureNames lists the names of the creatures in the library.
+func CreatureNames() []string {
+	names := make([]string, 0, len(Creatures))
+	for name := range Creatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadCreature returns the named creature from the library, or reads it from a file if the name ends in .json.
+func LoadCreature(name string) (Creature, error) {
+	if !strings.HasSuffix(name, ".json") {
+		c, ok := Creatures[strings.ToLower(name)]
+		if !ok {
+			return Creature{}, fmt.Errorf("unknown creature %q, expected one of %s or a .json file", name,
+				strings.Join(CreatureNames(), ", "))
+		}
+		return c, nil
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return Creature{}, err
+	}
+	var c Creature
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Creature{}, fmt.Errorf("%s: %v", name, err)
+	}
+	if c.R < 1 || c.T < 1 || c.Sigma <= 0 || len(c.Peaks) == 0 || len(c.Cells) == 0 {
+		return Creature{}, fmt.Errorf("%s: creature must have R, T, m, s, b and cells", name)
+	}
+	for _, row := range c.Cells {
+		if len(row) != len(c.Cells[0]) {
+			return Creature{}, fmt.Errorf("%s: cells must all have the same number of columns", name)
+		}
+	}
+	return c, nil
+}
+
+// Scale enlarges the creature n times, repeating each of its cells in an n by n square and multiplying its kernel
+// radius to match.
+func (c Creature) Scale(n int) Creature {
+	if n == 1 {
+		return c
+	}
+	cells := make([][]float64, len(c.Cells)*n)
+	for y := range cells {
+		row := c.Cells[y/n]
+		cells[y] = make([]float64, len(row)*n)
+		for x := range cells[y] {
+			cells[y][x] = row[x/n]
+		}
+	}
+	scaled := c
+	scaled.R *= n
+	scaled.Cells = cells
+	return scaled
+}
@@ -0,0 +1,110 @@
+package lenia
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// fft replaces x with its discrete Fourier transform, or with its inverse transform scaled by 1/len(x) when inverse
+// is true. Lengths that are not a power of two are handled with Bluestein's algorithm, so boards can be any size.
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+	if n&(n-1) == 0 {
+		radix2(x, inverse)
+	} else {
+		bluestein(x, inverse)
+	}
+	if inverse {
+		for i := range x {
+			x[i] /= complex(float64(n), 0)
+		}
+	}
+}
+
+// radix2 is the iterative Cooley-Tukey transform for lengths that are a power of two. It does not scale the inverse.
+func radix2(x []complex128, inverse bool) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], x[start+k+size/2]*w
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+// bluestein rewrites a transform of any length as a convolution, which is computed with power of two transforms. It
+// does not scale the inverse.
+func bluestein(x []complex128, inverse bool) {
+	n := len(x)
+	m := 1
+	for m < 2*n-1 {
+		m <<= 1
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	chirp := make([]complex128, n)
+	for k := range chirp {
+		// k*k grows quickly, so reduce it first to keep the angle precise
+		chirp[k] = cmplx.Rect(1, sign*math.Pi*float64((k*k)%(2*n))/float64(n))
+	}
+	a := make([]complex128, m)
+	b := make([]complex128, m)
+	for k := 0; k < n; k++ {
+		a[k] = x[k] * chirp[k]
+		b[k] = cmplx.Conj(chirp[k])
+		if k > 0 {
+			b[m-k] = b[k]
+		}
+	}
+	radix2(a, false)
+	radix2(b, false)
+	for i := range a {
+		a[i] *= b[i]
+	}
+	radix2(a, true)
+	for k := 0; k < n; k++ {
+		x[k] = a[k] / complex(float64(m), 0) * chirp[k]
+	}
+}
+
+// fft2 transforms a w by h array stored row by row, transforming the rows and then the columns.
+func fft2(data []complex128, w, h int, inverse bool) {
+	for y := 0; y < h; y++ {
+		fft(data[y*w:(y+1)*w], inverse)
+	}
+	column := make([]complex128, h)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			column[y] = data[y*w+x]
+		}
+		fft(column, inverse)
+		for y := 0; y < h; y++ {
+			data[y*w+x] = column[y]
+		}
+	}
+}
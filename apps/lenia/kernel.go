@@ -0,0 +1,128 @@
+package lenia
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Params are the parameters of a Lenia world. The kernel is made of concentric rings over radius R, with peak heights
+// given by Peaks, and the growth function is a bell curve centered on Mu with width Sigma. Each step advances time by
+// 1/T.
+type Params struct {
+	R     int
+	T     int
+	Mu    float64
+	Sigma float64
+	Peaks []float64
+}
+
+func (p Params) String() string {
+	peaks := make([]string, len(p.Peaks))
+	for i, peak := range p.Peaks {
+		peaks[i] = strconv.FormatFloat(peak, 'g', -1, 64)
+	}
+	return fmt.Sprintf("R=%d T=%d μ=%g σ=%g β=%s", p.R, p.T, p.Mu, p.Sigma, strings.Join(peaks, ","))
+}
+
+// ParsePeaks parses comma separated ring heights, which may be fractions, e.g. "1,2/3,1/3".
+func ParsePeaks(s string) ([]float64, error) {
+	var peaks []float64
+	for _, field := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(field), "/", 2)
+		peak, err := strconv.ParseFloat(parts[0], 64)
+		if err == nil && len(parts) == 2 {
+			var denominator float64
+			denominator, err = strconv.ParseFloat(parts[1], 64)
+			peak /= denominator
+		}
+		if err != nil || peak < 0 || math.IsInf(peak, 0) {
+			return nil, fmt.Errorf("peaks %q must be a comma separated list of non-negative numbers or fractions", s)
+		}
+		peaks = append(peaks, peak)
+	}
+	return peaks, nil
+}
+
+// kernelCore is the smooth bump each ring is shaped by, for r between 0 and 1.
+func kernelCore(r float64) float64 {
+	if r <= 0 || r >= 1 {
+		return 0
+	}
+	return math.Exp(4 - 1/(r*(1-r)))
+}
+
+// kernelShell returns the weight of the kernel at distance r, normalized to the kernel radius.
+func (p Params) kernelShell(r float64) float64 {
+	if r >= 1 {
+		return 0
+	}
+	scaled := float64(len(p.Peaks)) * r
+	ring := int(scaled)
+	return p.Peaks[ring] * kernelCore(scaled-float64(ring))
+}
+
+// Growth maps the weighted neighborhood sum u onto a rate of change between -1 and 1.
+func (p Params) Growth(u float64) float64 {
+	d := (u - p.Mu) / p.Sigma
+	return 2*math.Exp(-d*d/2) - 1
+}
+
+// Convolution applies the kernel of a world of w by h cells, wrapping around the edges. The kernel is transformed
+// once, after which every convolution costs two transforms of the world.
+type Convolution struct {
+	w, h   int
+	kernel []complex128
+	buffer []complex128
+}
+
+func NewConvolution(p Params, w, h int) *Convolution {
+	c := &Convolution{w: w, h: h, kernel: make([]complex128, w*h), buffer: make([]complex128, w*h)}
+	total := 0.0
+	for dy := -p.R; dy <= p.R; dy++ {
+		for dx := -p.R; dx <= p.R; dx++ {
+			weight := p.kernelShell(math.Sqrt(float64(dx*dx+dy*dy)) / float64(p.R))
+			if weight == 0 {
+				continue
+			}
+			// kernels wider than the world wrap onto themselves, just as the world does
+			x, y := ((dx%w)+w)%w, ((dy%h)+h)%h
+			c.kernel[y*w+x] += complex(weight, 0)
+			total += weight
+		}
+	}
+	for i := range c.kernel {
+		c.kernel[i] /= complex(total, 0)
+	}
+	fft2(c.kernel, w, h, false)
+	return c
+}
+
+// Apply returns the kernel weighted sum around every cell of the values, which are stored row by row.
+func (c *Convolution) Apply(values []float64) []float64 {
+	for i, v := range values {
+		c.buffer[i] = complex(v, 0)
+	}
+	fft2(c.buffer, c.w, c.h, false)
+	for i := range c.buffer {
+		c.buffer[i] *= c.kernel[i]
+	}
+	fft2(c.buffer, c.w, c.h, true)
+	result := make([]float64, len(values))
+	for i := range result {
+		result[i] = real(c.buffer[i])
+	}
+	return result
+}
+
+// Step returns the values one time step of 1/T later.
+func (p Params) Step(values []float64, c *Convolution) []float64 {
+	potential := c.Apply(values)
+	dt := 1 / float64(p.T)
+	next := make([]float64, len(values))
+	for i, v := range values {
+		next[i] = math.Max(0, math.Min(1, v+dt*p.Growth(potential[i])))
+	}
+	return next
+}
@@ -0,0 +1,293 @@
+package lenia
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"github.com/nsf/termbox-go"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+type LeniaCommand struct {
+	UI io.Renderer
+}
+
+func (c *LeniaCommand) Help() string {
+	return `Lenia is a continuous cellular automaton. Cells hold values between 0 and 1 and grow or shrink depending
+on a weighted sum over rings around them, which gives rise to smooth, lifelike creatures. The world wraps around at
+the edges. Click a cell to fill it.
+
+Options:
+  -creature=NAME  Creature to start with: ` + strings.Join(CreatureNames(), ", ") + ` or a .json file with the
+                  keys name, R, T, m, s, b and cells (default orbium).
+  -noise=N        Start with a random N by N patch instead of a creature, using the parameters of the creature.
+  -scale=N        Enlarge the creature and its kernel radius N times (default 1).
+  -R=R            Kernel radius, overriding the creature.
+  -T=T            Time resolution, each step advances time by 1/T, overriding the creature.
+  -mu=M           Center of the growth function, overriding the creature.
+  -sigma=S        Width of the growth function, overriding the creature.
+  -peaks=B        Comma separated heights of the kernel rings, e.g. 1,2/3, overriding the creature.
+  -colormap=C     viridis (default) or grayscale.
+  -width=W        Width of the world (default 60).
+  -height=H       Height of the world (default 40).
+  -seed=N         Seed for the random patch, so that runs with the same seed are identical. The seed of every run is
+                  written to the log (default from the clock).
+` + metrics.Usage
+}
+
+func (c *LeniaCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("lenia", flag.ContinueOnError)
+	creatureName := flags.String("creature", "orbium", "")
+	noise := flags.Int("noise", 0, "")
+	scale := flags.Int("scale", 1, "")
+	r := flags.Int("R", 0, "")
+	t := flags.Int("T", 0, "")
+	mu := flags.Float64("mu", 0, "")
+	sigma := flags.Float64("sigma", 0, "")
+	peaks := flags.String("peaks", "", "")
+	colormapName := flags.String("colormap", "viridis", "")
+	width := flags.Int("width", 60, "")
+	height := flags.Int("height", 40, "")
+	seed := flags.Int64("seed", 0, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	creature, err := LoadCreature(*creatureName)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if *scale < 1 {
+		fmt.Println("scale must be at least 1")
+		return 1
+	}
+	creature = creature.Scale(*scale)
+	params := creature.Params()
+	if *r > 0 {
+		params.R = *r
+	}
+	if *t > 0 {
+		params.T = *t
+	}
+	if *mu > 0 {
+		params.Mu = *mu
+	}
+	if *sigma > 0 {
+		params.Sigma = *sigma
+	}
+	if *peaks != "" {
+		if params.Peaks, err = ParsePeaks(*peaks); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	}
+	var colormap *grid.Colormap
+	switch *colormapName {
+	case "viridis":
+		colormap = &grid.Viridis
+	case "grayscale":
+		colormap = &grid.Grayscale
+	default:
+		fmt.Printf("colormap %q must be viridis or grayscale\n", *colormapName)
+		return 1
+	}
+
+	return withMetrics(func(sink *metrics.Sink) {
+		leniaMain(c.UI, params, creature.Cells, *noise, colormap, *width, *height, engine.ChooseSeed(*seed), sink)
+	})
+}
+
+func (c *LeniaCommand) Synopsis() string {
+	return "Lenia, a continuous cellular automaton"
+}
+
+func randomPatch(size int, random *rand.Rand) [][]float64 {
+	patch := make([][]float64, size)
+	for y := range patch {
+		patch[y] = make([]float64, size)
+		for x := range patch[y] {
+			patch[y][x] = random.Float64()
+		}
+	}
+	return patch
+}
+
+func leniaMain(ui io.Renderer, params Params, cells [][]float64, noise int, colormap *grid.Colormap, width, height int,
+	seed int64, sink *metrics.Sink) {
+	f := setupLogging("logs/lenia.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	ui.Run()
+
+	board := grid.NewBasicBoard(width, height)
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	board.Initialize(Cell{Value: 0, Colormap: colormap})
+	game := NewLenia(board, ui, params, colormap, seed)
+	sink.Observe(game.Engine)
+	if noise > 0 {
+		cells = randomPatch(noise, game.InitialRandom())
+	}
+	game.Place(cells)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					cell := game.Fill(game.Plane, event.Position)
+					if cell != nil {
+						ui.Draw()
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+// Cell holds a continuous value between 0 and 1, which is drawn with a colormap where renderers support color and
+// with shading characters otherwise. The colormap is shared by pointer, which keeps cells comparable.
+type Cell struct {
+	Value    float64
+	Colormap *grid.Colormap
+}
+
+func (c Cell) Rune() rune {
+	return grid.ShadeRune(c.Value)
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	return termbox.ColorWhite
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+func (c Cell) Color() grid.Color {
+	return c.Colormap.At(c.Value)
+}
+
+type Lenia struct {
+	*engine.Engine
+	Params      Params
+	Colormap    *grid.Colormap
+	convolution *Convolution
+
+	// next values, computed once per engine generation
+	next           []float64
+	nextGeneration int
+}
+
+func asCell(cell grid.Cell) Cell {
+	leniaCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected Lenia cell")
+	}
+	return leniaCell
+}
+
+func NewLenia(plane grid.Plane, ui io.Renderer, params Params, colormap *grid.Colormap, seed int64) *Lenia {
+	bounds := plane.Bounds()
+	w, h := bounds.Corner2.X-bounds.Corner1.X+1, bounds.Corner2.Y-bounds.Corner1.Y+1
+	game := &Lenia{
+		Engine:         &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 50, Seed: seed},
+		Params:         params,
+		Colormap:       colormap,
+		convolution:    NewConvolution(params, w, h),
+		nextGeneration: -1,
+	}
+	game.Engine.Handler = game
+	return game
+}
+
+// Place puts the cells in the middle of the world.
+func (g *Lenia) Place(cells [][]float64) {
+	bounds := g.Plane.Bounds()
+	top := (bounds.Corner1.Y + bounds.Corner2.Y - len(cells)) / 2
+	for y, row := range cells {
+		left := (bounds.Corner1.X + bounds.Corner2.X - len(row)) / 2
+		for x, value := range row {
+			g.Set(grid.Position{left + x, top + y}, Cell{Value: value, Colormap: g.Colormap})
+		}
+	}
+	g.UI.SetStatus(fmt.Sprintf("Lenia %s", g.Params))
+}
+
+func (g *Lenia) nextValues(plane grid.Plane) []float64 {
+	if g.nextGeneration != g.Generation {
+		bounds := plane.Bounds()
+		w := bounds.Corner2.X - bounds.Corner1.X + 1
+		values := make([]float64, w*(bounds.Corner2.Y-bounds.Corner1.Y+1))
+		for i := range values {
+			values[i] = asCell(plane.Get(grid.Position{bounds.Corner1.X + i%w, bounds.Corner1.Y + i/w})).Value
+		}
+		g.next = g.Params.Step(values, g.convolution)
+		g.nextGeneration = g.Generation
+	}
+	return g.next
+}
+
+func (g *Lenia) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	bounds := plane.Bounds()
+	if !bounds.Contains(position) {
+		return []engine.CellUpdate{}
+	}
+
+	w := bounds.Corner2.X - bounds.Corner1.X + 1
+	next := g.nextValues(plane)[(position.Y-bounds.Corner1.Y)*w+position.X-bounds.Corner1.X]
+	cell := asCell(plane.Get(position))
+	if next == cell.Value {
+		return []engine.CellUpdate{}
+	}
+	cell.Value = next
+	return []engine.CellUpdate{{cell, position}}
+}
+
+// Fill sets the cell to the highest value.
+func (g *Lenia) Fill(plane grid.Plane, position grid.Position) grid.Cell {
+	if !plane.Bounds().Contains(position) {
+		return nil
+	}
+	cell := asCell(plane.Get(position))
+	g.Set(position, Cell{Value: 1, Colormap: g.Colormap})
+	return cell
+}
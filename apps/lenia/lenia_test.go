@@ -0,0 +1,77 @@
+package lenia
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestFFTMatchesDFT(t *testing.T) {
+	for _, n := range []int{8, 6, 15} {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(float64(i*i%7), float64(i%3))
+		}
+		expected := make([]complex128, n)
+		for k := range expected {
+			for j := range x {
+				expected[k] += x[j] * cmplx.Rect(1, -2*math.Pi*float64(j*k)/float64(n))
+			}
+		}
+
+		transformed := append([]complex128(nil), x...)
+		fft(transformed, false)
+		for k := range expected {
+			if cmplx.Abs(transformed[k]-expected[k]) > 1e-9 {
+				t.Errorf("Expected transform of length %d at %d to be %v but found %v", n, k, expected[k], transformed[k])
+			}
+		}
+		fft(transformed, true)
+		for j := range x {
+			if cmplx.Abs(transformed[j]-x[j]) > 1e-9 {
+				t.Errorf("Expected inverse of length %d at %d to be %v but found %v", n, j, x[j], transformed[j])
+			}
+		}
+	}
+}
+
+func TestCreaturesSurvive(t *testing.T) {
+	// large enough that the kernel doesn't reach around the world onto the creatures
+	w, h := 64, 64
+	for _, creature := range []Creature{Orbium, Ring, Eye, Crescent} {
+		params := creature.Params()
+		convolution := NewConvolution(params, w, h)
+		values := make([]float64, w*h)
+		for y, row := range creature.Cells {
+			for x, value := range row {
+				values[(y+20)*w+x+20] = value
+			}
+		}
+		initialMass := mass(values)
+
+		for i := 0; i < 200; i++ {
+			values = params.Step(values, convolution)
+		}
+		// a creature keeps its shape as it moves, while most other patterns die out or explode
+		if m := mass(values); math.Abs(m-initialMass)/initialMass > 0.2 {
+			t.Errorf("Expected the %s to keep its mass of %.1f but it became %.1f", creature.Name, initialMass, m)
+		}
+	}
+}
+
+func TestCellsAreComparable(t *testing.T) {
+	// comparing cells as grid.Cell, as the API does, panics if they hold a slice
+	var a, b grid.Cell = Cell{Value: 0.5, Colormap: &grid.Viridis}, Cell{Value: 0.5, Colormap: &grid.Viridis}
+	if a != b {
+		t.Errorf("Expected cells of the same value and colormap to be equal")
+	}
+}
+
+func mass(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
@@ -0,0 +1,213 @@
+package margolus
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"github.com/nsf/termbox-go"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+type MargolusCommand struct {
+	UI io.Renderer
+}
+
+func (c *MargolusCommand) Help() string {
+	return `Block cellular automata on the Margolus neighborhood. The board is divided into 2x2 blocks that are each
+replaced according to the rule, and the blocks are shifted by one cell diagonally every other generation. Click a cell
+to toggle a particle, or with the sand rule to cycle it between empty, sand and wall.
+
+Options:
+  -rule=RULE    critters (default), bbm for the billiard ball model or sand.
+  -density=D    Fraction of cells that start with a particle (default 0.25).
+  -width=W      Width of the board (default 60).
+  -height=H     Height of the board (default 40).
+  -seed=N       Seed for the random start, so that runs with the same seed are identical. The seed of every run is
+                written to the log (default from the clock).
+` + metrics.Usage
+}
+
+func (c *MargolusCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("margolus", flag.ContinueOnError)
+	ruleName := flags.String("rule", "critters", "")
+	density := flags.Float64("density", 0.25, "")
+	width := flags.Int("width", 60, "")
+	height := flags.Int("height", 40, "")
+	seed := flags.Int64("seed", 0, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	rule, ok := Rules[*ruleName]
+	if !ok {
+		fmt.Printf("rule %q must be one of %s\n", *ruleName, strings.Join(RuleNames(), ", "))
+		return 1
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		margolusMain(c.UI, *ruleName, rule, *density, *width, *height, engine.ChooseSeed(*seed), sink)
+	})
+}
+
+func (c *MargolusCommand) Synopsis() string {
+	return "Block cellular automata: Critters, billiard balls and sand"
+}
+
+func margolusMain(ui io.Renderer, name string, rule Rule, density float64, width, height int, seed int64,
+	sink *metrics.Sink) {
+	f := setupLogging("logs/margolus.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	ui.Run()
+
+	board := grid.NewBasicBoard(width, height)
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	board.Initialize(Cell{Empty})
+	game := NewMargolus(board, ui, name, rule, seed)
+	sink.Observe(game.Engine)
+	game.Scatter(density)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					cell := game.Toggle(game.Plane, event.Position)
+					if cell != nil {
+						ui.Draw()
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+type Cell struct {
+	State int
+}
+
+func (c Cell) Rune() rune {
+	switch c.State {
+	case Particle:
+		return '●'
+	case Wall:
+		return '█'
+	default:
+		return ' '
+	}
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	switch c.State {
+	case Particle:
+		return termbox.ColorYellow
+	case Wall:
+		return termbox.ColorWhite
+	default:
+		return termbox.ColorDefault
+	}
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+// Margolus adapts a Rule on the states of a block to the cells of the engine.
+type Margolus struct {
+	*engine.Engine
+	Name string
+	Rule Rule
+}
+
+func asCell(cell grid.Cell) Cell {
+	margolusCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected Margolus cell")
+	}
+	return margolusCell
+}
+
+func NewMargolus(plane grid.Plane, ui io.Renderer, name string, rule Rule, seed int64) *Margolus {
+	game := &Margolus{
+		Engine: &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100, Seed: seed},
+		Name:   name,
+		Rule:   rule,
+	}
+	game.Engine.BlockHandler = game
+	game.UI.SetStatus(fmt.Sprintf("Margolus %s", name))
+	return game
+}
+
+// Scatter puts particles in random cells of the board.
+func (g *Margolus) Scatter(density float64) {
+	random := g.InitialRandom()
+	bounds := g.Plane.Bounds()
+	for x := bounds.Corner1.X; x <= bounds.Corner2.X; x++ {
+		for y := bounds.Corner1.Y; y <= bounds.Corner2.Y; y++ {
+			if random.Float64() < density {
+				g.Set(grid.Position{x, y}, Cell{Particle})
+			}
+		}
+	}
+}
+
+func (g *Margolus) UpdateBlock(block engine.Block, random *engine.Random) engine.Block {
+	var states [4]int
+	for i, cell := range block {
+		states[i] = asCell(cell).State
+	}
+	var next engine.Block
+	for i, state := range g.Rule(states, random) {
+		next[i] = Cell{state}
+	}
+	return next
+}
+
+// Toggle adds or removes a particle, and with the sand rule also places walls.
+func (g *Margolus) Toggle(plane grid.Plane, position grid.Position) grid.Cell {
+	if !plane.Bounds().Contains(position) {
+		return nil
+	}
+	cell := asCell(plane.Get(position))
+	states := 2
+	if g.Name == "sand" {
+		states = 3
+	}
+	g.Set(position, Cell{(cell.State + 1) % states})
+	return cell
+}
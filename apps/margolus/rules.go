@@ -0,0 +1,104 @@
+package margolus
+
+import (
+	"github.com/jpbetz/cellularautomata/engine"
+	"sort"
+)
+
+// The states of a cell. Walls never move and are only used by the sand rule.
+const Empty = 0
+const Particle = 1
+const Wall = 2
+
+// The indexes of the cells of a block, in the order of engine.Block.
+const (
+	TopLeft = iota
+	TopRight
+	BottomLeft
+	BottomRight
+)
+
+// Rule maps the states of a 2x2 block to their next states.
+type Rule func(block [4]int, random *engine.Random) [4]int
+
+// Rules are the built in block rules by name.
+var Rules = map[string]Rule{
+	"critters": Critters,
+	"bbm":      BilliardBall,
+	"sand":     Sand,
+}
+
+// RuleNames returns the names of the built in rules in alphabetical order.
+func RuleNames() []string {
+	names := make([]string, 0, len(Rules))
+	for name := range Rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func count(block [4]int) int {
+	n := 0
+	for _, state := range block {
+		if state == Particle {
+			n++
+		}
+	}
+	return n
+}
+
+// rotate180 swaps the diagonally opposite cells of a block.
+func rotate180(block [4]int) [4]int {
+	return [4]int{block[BottomRight], block[BottomLeft], block[TopRight], block[TopLeft]}
+}
+
+// Critters is Margolus' reversible rule: a block with exactly two live cells is unchanged, any other block is
+// inverted, and a block that had three live cells is also turned around. Gliders emerge from noise and bounce off
+// each other.
+func Critters(block [4]int, random *engine.Random) [4]int {
+	n := count(block)
+	if n == 2 {
+		return block
+	}
+	var next [4]int
+	for i, state := range block {
+		next[i] = Particle - state
+	}
+	if n == 3 {
+		next = rotate180(next)
+	}
+	return next
+}
+
+// BilliardBall is the billiard ball model of Fredkin and Toffoli: a lone particle moves to the diagonally opposite
+// corner of its block, two particles colliding head on leave along the other diagonal, and any other block is
+// unchanged, so pairs of particles next to each other form walls that reflect the others.
+func BilliardBall(block [4]int, random *engine.Random) [4]int {
+	switch {
+	case count(block) == 1:
+		return rotate180(block)
+	case block == [4]int{Particle, Empty, Empty, Particle}:
+		return [4]int{Empty, Particle, Particle, Empty}
+	case block == [4]int{Empty, Particle, Particle, Empty}:
+		return [4]int{Particle, Empty, Empty, Particle}
+	}
+	return block
+}
+
+// Sand lets particles fall into empty cells below them and slide off the top of piles into empty cells diagonally
+// below. Walls stay where they are.
+func Sand(block [4]int, random *engine.Random) [4]int {
+	for _, column := range [][2]int{{TopLeft, BottomLeft}, {TopRight, BottomRight}} {
+		top, bottom := column[0], column[1]
+		if block[top] == Particle && block[bottom] == Empty {
+			block[top], block[bottom] = Empty, Particle
+		}
+	}
+	if block[TopLeft] == Particle && block[TopRight] == Empty && block[BottomRight] == Empty {
+		block[TopLeft], block[BottomRight] = Empty, Particle
+	} else if block[TopRight] == Particle && block[TopLeft] == Empty && block[BottomLeft] == Empty {
+		block[TopRight], block[BottomLeft] = Empty, Particle
+	}
+	return block
+}
@@ -0,0 +1,63 @@
+package margolus
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"testing"
+)
+
+// binaryBlocks returns all 16 blocks of empty cells and particles.
+func binaryBlocks() [][4]int {
+	blocks := [][4]int{}
+	for bits := 0; bits < 16; bits++ {
+		blocks = append(blocks, [4]int{bits & 1, bits >> 1 & 1, bits >> 2 & 1, bits >> 3 & 1})
+	}
+	return blocks
+}
+
+func TestReversibleRules(t *testing.T) {
+	for _, name := range []string{"critters", "bbm"} {
+		seen := map[[4]int][4]int{}
+		for _, block := range binaryBlocks() {
+			next := Rules[name](block, nil)
+			if previous, ok := seen[next]; ok {
+				t.Errorf("Expected %s to be reversible but %v and %v both become %v", name, previous, block, next)
+			}
+			seen[next] = block
+		}
+	}
+}
+
+func TestSandConservesParticles(t *testing.T) {
+	for bits := 0; bits < 81; bits++ {
+		block := [4]int{bits % 3, bits / 3 % 3, bits / 9 % 3, bits / 27 % 3}
+		next := Sand(block, nil)
+		counts := map[int]int{}
+		for i := range block {
+			counts[block[i]]++
+			counts[next[i]]--
+		}
+		for state, n := range counts {
+			if n != 0 {
+				t.Errorf("Expected sand to keep the number of cells in state %d but %v became %v", state, block, next)
+			}
+		}
+		for i := range block {
+			if (block[i] == Wall) != (next[i] == Wall) {
+				t.Errorf("Expected walls to stay in place but %v became %v", block, next)
+			}
+		}
+	}
+}
+
+func TestBallTravelsDiagonally(t *testing.T) {
+	board := grid.NewBasicBoard(10, 10)
+	board.Initialize(Cell{Empty})
+	game := NewMargolus(board, io.NullRenderer{}, "bbm", BilliardBall, 1)
+	board.Set(grid.Position{5, 5}, Cell{Particle})
+	game.Step()
+	game.Step()
+	if asCell(board.Get(grid.Position{3, 3})).State != Particle {
+		t.Errorf("Expected the ball to move from 5,5 to 3,3 in two generations")
+	}
+}
@@ -0,0 +1,158 @@
+package ruletable
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/golly"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"log"
+	"os"
+	"time"
+)
+
+type RuleTableCommand struct {
+	UI io.Renderer
+}
+
+func (c *RuleTableCommand) Help() string {
+	return `Runs a rule table in the Golly .rule format. Click a cell to cycle it through the states of the rule. Rules
+with a hexagonal neighborhood run on a hexagonal board.
+
+Options:
+  -file=PATH    Path to the .rule file (default rules/WireWorld.rule).
+  -width=W      Width of the board (default 60).
+  -height=H     Height of the board (default 40).
+` + metrics.Usage
+}
+
+func (c *RuleTableCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("ruletable", flag.ContinueOnError)
+	file := flags.String("file", "rules/WireWorld.rule", "")
+	width := flags.Int("width", 60, "")
+	height := flags.Int("height", 40, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	table, err := loadRuleTable(*file)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		ruleTableMain(c.UI, table, *width, *height, sink)
+	})
+}
+
+func (c *RuleTableCommand) Synopsis() string {
+	return "Rule tables in the Golly .rule format"
+}
+
+func loadRuleTable(filename string) (*golly.RuleTable, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	table, err := golly.ParseRuleTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return table, nil
+}
+
+func ruleTableMain(ui io.Renderer, table *golly.RuleTable, width, height int, sink *metrics.Sink) {
+	f := setupLogging("logs/ruletable.log")
+	defer f.Close()
+
+	ui.Run()
+
+	var board grid.Plane
+	if table.Neighborhood.Name == golly.Hexagonal.Name {
+		hexBoard := grid.NewHexBoard(width, height)
+		hexBoard.Initialize(golly.Cell{State: 0, Table: table})
+		board = hexBoard
+	} else {
+		basicBoard := grid.NewBasicBoard(width, height)
+		basicBoard.Initialize(golly.Cell{State: 0, Table: table})
+		board = basicBoard
+	}
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	game := NewRuleTable(board, ui, table)
+	sink.Observe(game.Engine)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					cell := game.Cycle(game.Plane, event.Position)
+					if cell != nil {
+						ui.Draw()
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+type RuleTable struct {
+	*engine.Engine
+	Table *golly.RuleTable
+}
+
+func NewRuleTable(plane grid.Plane, ui io.Renderer, table *golly.RuleTable) *RuleTable {
+	game := &RuleTable{
+		Engine: &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100},
+		Table:  table,
+	}
+	game.Engine.Handler = golly.NewHandler(table)
+	ui.SetStatus(fmt.Sprintf("%s (%d states, %s)", table.Name, table.States, table.Neighborhood.Name))
+	return game
+}
+
+// Cycle advances the state of the cell at the position, wrapping back to state 0 after the last state.
+func (g *RuleTable) Cycle(plane grid.Plane, position grid.Position) grid.Cell {
+	if !plane.Bounds().Contains(position) {
+		return nil
+	}
+	cell, ok := plane.Get(position).(golly.Cell)
+	if !ok {
+		panic("Expected rule table cell")
+	}
+	cell.State = (cell.State + 1) % g.Table.States
+	g.Set(position, cell)
+	return cell
+}
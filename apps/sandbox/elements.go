@@ -0,0 +1,113 @@
+package sandbox
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/nsf/termbox-go"
+)
+
+// Element is the kind of particle in a cell.
+type Element int
+
+const (
+	Empty Element = iota
+	Sand
+	Water
+	Stone
+	Fire
+	Smoke
+	Plant
+)
+
+// Elements are the elements that can be painted, in the order of their brush keys starting at 1. Key 0 erases.
+var Elements = []Element{Sand, Water, Stone, Fire, Smoke, Plant}
+
+// FireLife and SmokeLife are the longest a fire burns and smoke lingers, in generations.
+const FireLife = 20
+const SmokeLife = 40
+
+var names = map[Element]string{
+	Empty: "eraser",
+	Sand:  "sand",
+	Water: "water",
+	Stone: "stone",
+	Fire:  "fire",
+	Smoke: "smoke",
+	Plant: "plant",
+}
+
+func (e Element) String() string {
+	return names[e]
+}
+
+// density orders the elements that move: a falling element sinks into a cell below it holding a lighter one.
+var density = map[Element]int{
+	Smoke: 0,
+	Empty: 1,
+	Water: 2,
+	Sand:  3,
+}
+
+// Moves reports whether the element falls, flows or rises, rather than staying where it is.
+func (e Element) Moves() bool {
+	_, ok := density[e]
+	return ok && e != Empty
+}
+
+var colors = map[Element]grid.Color{
+	Empty: {R: 0x0e, G: 0x0e, B: 0x0e},
+	Sand:  {R: 0xe6, G: 0xc8, B: 0x6e},
+	Water: {R: 0x33, G: 0x6b, B: 0xff},
+	Stone: {R: 0x80, G: 0x80, B: 0x80},
+	Fire:  {R: 0xff, G: 0x5a, B: 0x1e},
+	Smoke: {R: 0x50, G: 0x50, B: 0x50},
+	Plant: {R: 0x33, G: 0xcc, B: 0x33},
+}
+
+var attributes = map[Element]termbox.Attribute{
+	Empty: termbox.ColorDefault,
+	Sand:  termbox.ColorYellow,
+	Water: termbox.ColorBlue,
+	Stone: termbox.ColorWhite,
+	Fire:  termbox.ColorRed,
+	Smoke: termbox.ColorWhite,
+	Plant: termbox.ColorGreen,
+}
+
+var runes = map[Element]rune{
+	Empty: ' ',
+	Sand:  '▓',
+	Water: '~',
+	Stone: '█',
+	Fire:  '▲',
+	Smoke: '░',
+	Plant: '♣',
+}
+
+// Cell holds an element, and for fire and smoke the number of generations they have left.
+type Cell struct {
+	Element Element
+	Life    int
+}
+
+func (c Cell) Rune() rune {
+	return runes[c.Element]
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	return attributes[c.Element]
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+// Color fades fire and smoke as they burn out.
+func (c Cell) Color() grid.Color {
+	switch c.Element {
+	case Fire:
+		return grid.Gradient(colors[Smoke], colors[Fire], float64(c.Life)/FireLife)
+	case Smoke:
+		return grid.Gradient(colors[Empty], colors[Smoke], float64(c.Life)/SmokeLife)
+	}
+	return colors[c.Element]
+}
@@ -0,0 +1,330 @@
+package sandbox
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+type SandboxCommand struct {
+	UI io.Renderer
+}
+
+func (c *SandboxCommand) Help() string {
+	return `A falling sand sandbox. Sand piles up and sinks through water, water flows, stone stays put, fire burns
+plants and goes out in smoke or when it touches water, smoke rises and clears, and plants grow into water. Pick an
+element with the number keys and paint it by clicking and dragging:
+
+  ` + brushKeys() + `
+
+Options:
+  -brush=R      Radius of the brush (default 1).
+  -width=W      Width of the board (default 60).
+  -height=H     Height of the board (default 40).
+  -seed=N       Seed for the random numbers, so that runs with the same seed are identical. The seed of every run is
+                written to the log (default from the clock).
+` + metrics.Usage
+}
+
+func (c *SandboxCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("sandbox", flag.ContinueOnError)
+	brush := flags.Int("brush", 1, "")
+	width := flags.Int("width", 60, "")
+	height := flags.Int("height", 40, "")
+	seed := flags.Int64("seed", 0, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if *brush < 0 {
+		fmt.Println("brush must not be negative")
+		return 1
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		sandboxMain(c.UI, *brush, *width, *height, engine.ChooseSeed(*seed), sink)
+	})
+}
+
+func (c *SandboxCommand) Synopsis() string {
+	return "Falling sand sandbox"
+}
+
+// brushKeys lists the keys that pick each element.
+func brushKeys() string {
+	keys := []string{fmt.Sprintf("0 %s", Empty)}
+	for i, element := range Elements {
+		keys = append(keys, fmt.Sprintf("%d %s", i+1, element))
+	}
+	return strings.Join(keys, ", ")
+}
+
+func sandboxMain(ui io.Renderer, brush, width, height int, seed int64, sink *metrics.Sink) {
+	f := setupLogging("logs/sandbox.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	ui.Run()
+
+	board := grid.NewBasicBoard(width, height)
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	board.Initialize(Cell{Element: Empty})
+	game := NewSandbox(board, ui, brush, seed)
+	sink.Observe(game.Engine)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					game.Paint(event.Position)
+					ui.Draw()
+				case io.Key:
+					if event.Ch >= '0' && int(event.Ch-'0') <= len(Elements) {
+						game.SelectBrush(int(event.Ch - '0'))
+					}
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+// Neighborhood is the cells fire spreads to and is put out by.
+var Neighborhood = grid.Moore(1)
+
+// PlantGrowth is the chance per generation that a plant grows into a neighboring water cell, and Flammability the
+// chance that fire spreads to a neighboring plant.
+const PlantGrowth = 0.05
+const Flammability = 0.3
+
+// Sandbox moves particles in place, one cell at a time in a random order, so that particles falling into the same
+// cell don't overwrite each other.
+type Sandbox struct {
+	*engine.Engine
+	Brush  Element
+	Radius int
+}
+
+func asCell(cell grid.Cell) Cell {
+	sandboxCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected sandbox cell")
+	}
+	return sandboxCell
+}
+
+func NewSandbox(plane grid.Plane, ui io.Renderer, radius int, seed int64) *Sandbox {
+	game := &Sandbox{
+		Engine: &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 50, Seed: seed, InPlace: true},
+		Radius: radius,
+	}
+	game.Engine.Handler = game
+	game.SelectBrush(1)
+	return game
+}
+
+// SelectBrush picks the element painted by clicks by its key, 0 for the eraser and 1 onwards for Elements.
+func (g *Sandbox) SelectBrush(key int) {
+	if key == 0 {
+		g.Brush = Empty
+	} else {
+		g.Brush = Elements[key-1]
+	}
+	g.UI.SetStatus(fmt.Sprintf("Brush: %s | %s", g.Brush, brushKeys()))
+}
+
+// Paint fills the cells within the brush radius of the position with the brush element.
+func (g *Sandbox) Paint(position grid.Position) {
+	bounds := g.Plane.Bounds()
+	if !bounds.Contains(position) {
+		return
+	}
+	cell := newCell(g.Brush)
+	g.Set(position, cell)
+	if g.Radius > 0 {
+		for _, p := range grid.Moore(g.Radius).Positions(position, bounds) {
+			g.Set(p, cell)
+		}
+	}
+}
+
+// newCell returns a cell of the element, with fire and smoke at their full life.
+func newCell(element Element) Cell {
+	cell := Cell{Element: element}
+	switch element {
+	case Fire:
+		cell.Life = FireLife
+	case Smoke:
+		cell.Life = SmokeLife
+	}
+	return cell
+}
+
+// States names the elements for the control API, as painted by the brushes. Fire and smoke read back by name only
+// until they start to burn out.
+func (g *Sandbox) States() map[string]grid.Cell {
+	states := map[string]grid.Cell{"empty": newCell(Empty)}
+	for _, element := range Elements {
+		states[element.String()] = newCell(element)
+	}
+	return states
+}
+
+// PatternStates numbers the elements in the order of Element, empty as the background, with fire and smoke at their
+// full life.
+func (g *Sandbox) PatternStates() []grid.Cell {
+	states := []grid.Cell{newCell(Empty)}
+	for _, element := range Elements {
+		states = append(states, newCell(element))
+	}
+	return states
+}
+
+// PatternState numbers fire and smoke that have started to burn out like those of PatternStates, by their element.
+func (g *Sandbox) PatternState(cell grid.Cell) int {
+	return int(asCell(cell).Element)
+}
+
+func (g *Sandbox) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	if !plane.Bounds().Contains(position) {
+		return []engine.CellUpdate{}
+	}
+
+	cell := asCell(plane.Get(position))
+	switch cell.Element {
+	case Sand:
+		return fall(plane, position, cell, random, false)
+	case Water:
+		return fall(plane, position, cell, random, true)
+	case Smoke:
+		return rise(plane, position, cell, random)
+	case Fire:
+		return burn(plane, position, cell, random)
+	case Plant:
+		return grow(plane, position, random)
+	}
+	return []engine.CellUpdate{}
+}
+
+// shuffled returns the offsets in a random order, so particles don't drift to one side.
+func shuffled(random *engine.Random, offsets ...grid.Position) []grid.Position {
+	for i := len(offsets) - 1; i > 0; i-- {
+		j := random.Intn(i + 1)
+		offsets[i], offsets[j] = offsets[j], offsets[i]
+	}
+	return offsets
+}
+
+// moveTo swaps the cell with the first cell at the offsets that accepts it.
+func moveTo(plane grid.Plane, position grid.Position, cell Cell, offsets []grid.Position,
+	accepts func(target Cell) bool) []engine.CellUpdate {
+	for _, offset := range offsets {
+		target := grid.Position{position.X + offset.X, position.Y + offset.Y}
+		if !plane.Bounds().Contains(target) {
+			continue
+		}
+		if other := asCell(plane.Get(target)); accepts(other) {
+			return []engine.CellUpdate{{other, position}, {cell, target}}
+		}
+	}
+	return []engine.CellUpdate{}
+}
+
+// fall moves a cell down, or diagonally down if the cell below is taken, into a cell of a lighter element. Liquids
+// also flow sideways.
+func fall(plane grid.Plane, position grid.Position, cell Cell, random *engine.Random, flows bool) []engine.CellUpdate {
+	offsets := append([]grid.Position{{0, 1}}, shuffled(random, grid.Position{-1, 1}, grid.Position{1, 1})...)
+	if flows {
+		offsets = append(offsets, shuffled(random, grid.Position{-1, 0}, grid.Position{1, 0})...)
+	}
+	return moveTo(plane, position, cell, offsets, func(target Cell) bool {
+		d, ok := density[target.Element]
+		return ok && d < density[cell.Element]
+	})
+}
+
+// rise moves smoke up, or diagonally up or sideways, into empty cells, and clears it once its life is over.
+func rise(plane grid.Plane, position grid.Position, cell Cell, random *engine.Random) []engine.CellUpdate {
+	cell.Life--
+	if cell.Life <= 0 {
+		return []engine.CellUpdate{{Cell{Element: Empty}, position}}
+	}
+	offsets := append([]grid.Position{{0, -1}}, shuffled(random, grid.Position{-1, -1}, grid.Position{1, -1})...)
+	offsets = append(offsets, shuffled(random, grid.Position{-1, 0}, grid.Position{1, 0})...)
+	updates := moveTo(plane, position, cell, offsets, func(target Cell) bool {
+		return target.Element == Empty
+	})
+	if len(updates) == 0 {
+		return []engine.CellUpdate{{cell, position}}
+	}
+	return updates
+}
+
+// burn spreads fire to neighboring plants. Fire next to water is put out, and fire that has burned out turns to
+// smoke.
+func burn(plane grid.Plane, position grid.Position, cell Cell, random *engine.Random) []engine.CellUpdate {
+	updates := []engine.CellUpdate{}
+	for _, p := range Neighborhood.Positions(position, plane.Bounds()) {
+		switch asCell(plane.Get(p)).Element {
+		case Water:
+			return []engine.CellUpdate{{Cell{Element: Smoke, Life: SmokeLife}, position}}
+		case Plant:
+			if random.Float64() < Flammability {
+				updates = append(updates, engine.CellUpdate{Cell{Element: Fire, Life: FireLife}, p})
+			}
+		}
+	}
+	cell.Life--
+	if cell.Life <= 0 {
+		cell = Cell{Element: Smoke, Life: SmokeLife}
+	}
+	return append(updates, engine.CellUpdate{cell, position})
+}
+
+// grow turns a random neighboring water cell into a plant now and then.
+func grow(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	neighbors := Neighborhood.Positions(position, plane.Bounds())
+	if len(neighbors) == 0 || random.Float64() >= PlantGrowth {
+		return []engine.CellUpdate{}
+	}
+	p := neighbors[random.Intn(len(neighbors))]
+	if asCell(plane.Get(p)).Element != Water {
+		return []engine.CellUpdate{}
+	}
+	return []engine.CellUpdate{{Cell{Element: Plant}, p}}
+}
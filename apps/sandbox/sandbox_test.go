@@ -0,0 +1,85 @@
+package sandbox
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"testing"
+)
+
+func count(board grid.Plane, element Element) int {
+	n := 0
+	bounds := board.Bounds()
+	for x := bounds.Corner1.X; x <= bounds.Corner2.X; x++ {
+		for y := bounds.Corner1.Y; y <= bounds.Corner2.Y; y++ {
+			if asCell(board.Get(grid.Position{x, y})).Element == element {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestParticlesAreNotLost(t *testing.T) {
+	board := grid.NewBasicBoard(10, 10)
+	board.Initialize(Cell{Element: Empty})
+	game := NewSandbox(board, io.NullRenderer{}, 0, 1)
+	for x := 0; x < 10; x++ {
+		board.Set(grid.Position{x, 0}, Cell{Element: Sand})
+		board.Set(grid.Position{x, 1}, Cell{Element: Water})
+	}
+
+	for i := 0; i < 50; i++ {
+		game.Step()
+	}
+	if n := count(board, Sand); n != 10 {
+		t.Errorf("Expected 10 grains of sand but found %d", n)
+	}
+	if n := count(board, Water); n != 10 {
+		t.Errorf("Expected 10 cells of water but found %d", n)
+	}
+	// sand sinks through the water, which ends up on top
+	for x := 0; x < 10; x++ {
+		if element := asCell(board.Get(grid.Position{x, 9})).Element; element != Sand {
+			t.Errorf("Expected sand at the bottom at %d,9 but found %s", x, element)
+		}
+		if element := asCell(board.Get(grid.Position{x, 8})).Element; element != Water {
+			t.Errorf("Expected water on top of the sand at %d,8 but found %s", x, element)
+		}
+	}
+}
+
+func TestFireBurnsPlants(t *testing.T) {
+	board := grid.NewBasicBoard(10, 3)
+	board.Initialize(Cell{Element: Empty})
+	game := NewSandbox(board, io.NullRenderer{}, 0, 1)
+	for x := 0; x < 10; x++ {
+		board.Set(grid.Position{x, 2}, Cell{Element: Plant})
+	}
+	board.Set(grid.Position{0, 1}, Cell{Element: Fire, Life: FireLife})
+
+	for i := 0; i < 200; i++ {
+		game.Step()
+	}
+	if n := count(board, Plant); n != 0 {
+		t.Errorf("Expected the fire to burn all plants but %d are left", n)
+	}
+	if n := count(board, Fire) + count(board, Smoke); n != 0 {
+		t.Errorf("Expected the fire to burn out and the smoke to clear but found %d cells", n)
+	}
+}
+
+func TestPatternStates(t *testing.T) {
+	game := NewSandbox(grid.NewBasicBoard(1, 1), io.NullRenderer{}, 0, 1)
+	states := game.PatternStates()
+	for i, state := range states {
+		if element := asCell(state).Element; int(element) != i {
+			t.Errorf("Expected pattern state %d to be element %d but was %s", i, i, element)
+		}
+		if named := game.States()[asCell(state).Element.String()]; i > 0 && named != state {
+			t.Errorf("Expected %s to be named the same as pattern state %d but was %v", asCell(state).Element, i, named)
+		}
+	}
+	if state := game.PatternState(Cell{Element: Fire, Life: FireLife / 2}); states[state] != newCell(Fire) {
+		t.Errorf("Expected a fire burning out to be numbered as fire but was state %d", state)
+	}
+}
@@ -0,0 +1,331 @@
+package sandpile
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"github.com/nsf/termbox-go"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SandpileCommand struct {
+	UI io.Renderer
+}
+
+func (c *SandpileCommand) Help() string {
+	return `The Abelian sandpile model. Cells hold grains of sand, and a cell with 4 or more grains topples, passing one
+grain to each of its 4 neighbors. Grains that fall off the edge of the board are lost. Every generation a grain is
+dropped and the pile topples until it is stable again. The size (number of topplings) and duration (number of
+toppling steps) of the resulting avalanche are shown in the status bar. Click a cell to drop a grain on it.
+
+Options:
+  -drop=D       Where a grain is dropped every generation: center (default), X,Y for a fixed position, or none to
+                only drop grains by clicking.
+  -csv=PATH     Append the generation, size and duration of every avalanche to a CSV file, e.g. to check that the
+                sizes follow a power law.
+  -width=W      Width of the board (default 60).
+  -height=H     Height of the board (default 40).
+` + metrics.Usage
+}
+
+func (c *SandpileCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("sandpile", flag.ContinueOnError)
+	drop := flags.String("drop", "center", "")
+	csvPath := flags.String("csv", "", "")
+	width := flags.Int("width", 60, "")
+	height := flags.Int("height", 40, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	dropAt, err := parseDrop(*drop, *width, *height)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	var stats *csv.Writer
+	if *csvPath != "" {
+		f, err := os.OpenFile(*csvPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0664)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer f.Close()
+		stats = csv.NewWriter(f)
+		if info, err := f.Stat(); err == nil && info.Size() == 0 {
+			stats.Write([]string{"generation", "size", "duration"})
+		}
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		sandpileMain(c.UI, dropAt, stats, *width, *height, sink)
+	})
+}
+
+func (c *SandpileCommand) Synopsis() string {
+	return "Abelian sandpile with avalanche statistics"
+}
+
+// parseDrop returns the position grains are dropped at every generation, or nil for none.
+func parseDrop(drop string, width, height int) (*grid.Position, error) {
+	switch drop {
+	case "none":
+		return nil, nil
+	case "center":
+		return &grid.Position{width / 2, height / 2}, nil
+	}
+	parts := strings.Split(drop, ",")
+	if len(parts) == 2 {
+		x, errX := strconv.Atoi(parts[0])
+		y, errY := strconv.Atoi(parts[1])
+		if errX == nil && errY == nil && x >= 0 && x < width && y >= 0 && y < height {
+			return &grid.Position{x, y}, nil
+		}
+	}
+	return nil, fmt.Errorf("drop %q must be center, none or X,Y within the board", drop)
+}
+
+func sandpileMain(ui io.Renderer, dropAt *grid.Position, stats *csv.Writer, width, height int, sink *metrics.Sink) {
+	f := setupLogging("logs/sandpile.log")
+	defer f.Close()
+
+	ui.Run()
+
+	board := grid.NewBasicBoard(width, height)
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	board.Initialize(Cell{})
+	game := NewSandpile(board, ui, dropAt, stats)
+	sink.Observe(game.Engine)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch event := in.(type) {
+				case io.Click:
+					game.Drop(event.Position)
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+// Threshold is the number of grains at which a cell topples.
+const Threshold = 4
+
+// Neighborhood is the neighbors a toppling cell passes its grains to.
+var Neighborhood = grid.VonNeumann(1)
+
+var grainColors = []grid.Color{
+	{R: 0x0e, G: 0x0e, B: 0x0e},
+	{R: 0x33, G: 0x3f, B: 0xff},
+	{R: 0x33, G: 0xe6, B: 0xff},
+	{R: 0xff, G: 0xf9, B: 0x33},
+	{R: 0xff, G: 0x33, B: 0x58},
+}
+
+type Cell struct {
+	Grains int
+}
+
+func (c Cell) Rune() rune {
+	return grid.ShadeRune(float64(c.Grains) / Threshold)
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	switch c.Grains {
+	case 0:
+		return termbox.ColorDefault
+	case 1:
+		return termbox.ColorBlue
+	case 2:
+		return termbox.ColorCyan
+	case 3:
+		return termbox.ColorYellow
+	default:
+		return termbox.ColorRed
+	}
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+func (c Cell) Color() grid.Color {
+	if c.Grains >= len(grainColors) {
+		return grainColors[len(grainColors)-1]
+	}
+	return grainColors[c.Grains]
+}
+
+// Avalanche is the toppling caused by a dropped grain. Size counts every toppling and duration the number of steps
+// in which cells toppled.
+type Avalanche struct {
+	Size     int
+	Duration int
+}
+
+type Sandpile struct {
+	*engine.Engine
+	DropAt *grid.Position
+	stats  *csv.Writer
+
+	// grains dropped by clicks, added at the end of the generation so they don't race with toppling
+	drops chan grid.Position
+
+	avalanche   Avalanche
+	lastToppled int
+	avalanches  int
+	largest     Avalanche
+}
+
+func asCell(cell grid.Cell) Cell {
+	sandCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected sandpile cell")
+	}
+	return sandCell
+}
+
+func NewSandpile(plane grid.Plane, ui io.Renderer, dropAt *grid.Position, stats *csv.Writer) *Sandpile {
+	game := &Sandpile{
+		Engine:      &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 50},
+		DropAt:      dropAt,
+		stats:       stats,
+		drops:       make(chan grid.Position, 100),
+		lastToppled: -1,
+	}
+	game.Engine.Handler = game
+	game.UI.SetStatus("Sandpile")
+	return game
+}
+
+// MaxSubSteps bounds the toppling of a single generation. Every avalanche on a finite board ends, this only keeps a
+// huge board responsive.
+func (g *Sandpile) MaxSubSteps() int {
+	return 100000
+}
+
+func (g *Sandpile) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	if !plane.Bounds().Contains(position) {
+		return []engine.CellUpdate{}
+	}
+
+	cell := asCell(plane.Get(position))
+	grains := cell.Grains
+	if grains >= Threshold {
+		grains -= Threshold
+		g.avalanche.Size++
+		if g.lastToppled != g.SubStep {
+			g.avalanche.Duration++
+			g.lastToppled = g.SubStep
+		}
+	}
+	for _, neighbor := range plane.GetNeighbors(position, Neighborhood) {
+		if asCell(neighbor).Grains >= Threshold {
+			grains++
+		}
+	}
+	if grains == cell.Grains {
+		return []engine.CellUpdate{}
+	}
+	return []engine.CellUpdate{{Cell{Grains: grains}, position}}
+}
+
+// EndGeneration records the avalanche of the generation and drops the next grains, which topple in the next
+// generation.
+func (g *Sandpile) EndGeneration(plane grid.Plane) {
+	if g.avalanche.Size > 0 {
+		g.recordAvalanche()
+	}
+	g.avalanche = Avalanche{}
+	g.lastToppled = -1
+
+	if g.DropAt != nil {
+		g.addGrain(plane, *g.DropAt)
+	}
+	for pending := true; pending; {
+		select {
+		case p := <-g.drops:
+			g.addGrain(plane, p)
+		default:
+			pending = false
+		}
+	}
+}
+
+func (g *Sandpile) recordAvalanche() {
+	g.avalanches++
+	if g.avalanche.Size > g.largest.Size {
+		g.largest = g.avalanche
+	}
+	g.UI.SetStatus(fmt.Sprintf("Avalanche size %d, duration %d | %d avalanches, largest size %d, duration %d",
+		g.avalanche.Size, g.avalanche.Duration, g.avalanches, g.largest.Size, g.largest.Duration))
+	if g.stats == nil {
+		return
+	}
+	g.stats.Write([]string{
+		strconv.Itoa(g.Generation),
+		strconv.Itoa(g.avalanche.Size),
+		strconv.Itoa(g.avalanche.Duration),
+	})
+	g.stats.Flush()
+	if err := g.stats.Error(); err != nil {
+		log.Printf("Failed to write avalanche statistics: %v\n", err)
+	}
+}
+
+func (g *Sandpile) addGrain(plane grid.Plane, position grid.Position) {
+	if !plane.Bounds().Contains(position) {
+		return
+	}
+	cell := asCell(plane.Get(position))
+	cell.Grains++
+	g.Set(position, cell)
+}
+
+// Drop adds a grain at the position at the end of the current generation.
+func (g *Sandpile) Drop(position grid.Position) {
+	select {
+	case g.drops <- position:
+	default:
+	}
+	if !g.Playing {
+		g.UI.SetStatus("Grains are dropped once the sandpile is running")
+	}
+}
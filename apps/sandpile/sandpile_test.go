@@ -0,0 +1,38 @@
+package sandpile
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"testing"
+)
+
+func TestAvalancheRelaxesWithinAGeneration(t *testing.T) {
+	board := grid.NewBasicBoard(5, 5)
+	board.Initialize(Cell{})
+	center := grid.Position{2, 2}
+	game := NewSandpile(board, io.NullRenderer{}, nil, nil)
+	for _, p := range []grid.Position{{1, 2}, {3, 2}, {2, 1}, {2, 3}} {
+		board.Set(p, Cell{Grains: 3})
+	}
+	board.Set(center, Cell{Grains: 4})
+
+	// the center topples onto its neighbors, which topple back onto it and onto the next ring, and then the center
+	// topples once more
+	game.Step()
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			if grains := asCell(board.Get(grid.Position{x, y})).Grains; grains >= Threshold {
+				t.Errorf("Expected the pile to be stable but %v has %d grains", grid.Position{x, y}, grains)
+			}
+		}
+	}
+	if game.largest.Size != 6 || game.largest.Duration != 3 {
+		t.Errorf("Expected an avalanche of size 6 and duration 3 but found %+v", game.largest)
+	}
+	if grains := asCell(board.Get(center)).Grains; grains != 0 {
+		t.Errorf("Expected the center to be empty but found %d grains", grains)
+	}
+	if grains := asCell(board.Get(grid.Position{1, 2})).Grains; grains != 1 {
+		t.Errorf("Expected the neighbors of the center to have 1 grain but found %d", grains)
+	}
+}
@@ -0,0 +1,348 @@
+package search
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/analysis"
+	"github.com/jpbetz/cellularautomata/bitlife"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/nsf/termbox-go"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+type SearchCommand struct{}
+
+func (c *SearchCommand) Help() string {
+	return `Runs random soups of a Life-like rule until they stabilize, in parallel and without drawing them, and records
+the interesting ones: methuselahs that take long to stabilize, soups that don't stabilize, and, under Conway's rule,
+rare or unknown objects. Every soup has its own seed, and running the command with that seed and -soups=1
+reproduces it.
+
+Soups are run on a bounded board. Gliders and the other spaceships of the catalog that fly away from the rest of a
+soup are removed before they reach the edges of the board, and counted in the census, as apgsearch does. Other
+patterns that reach the edges crash into them.
+
+Options:
+  -rule=RULE          Rule in B/S notation (default B3/S23).
+  -soups=N            Number of soups (default 1000).
+  -size=N             Width and height of each soup (default 16).
+  -density=D          Fraction of live cells in a soup (default 0.5).
+  -board=N            Width and height of the board each soup runs on (default 128).
+  -max-generations=N  Generations after which a soup is given up on (default 5000).
+  -methuselah=N       Generations to stabilize that make a soup a methuselah (default 1000).
+  -workers=N          Soups run at once (default the number of CPUs).
+  -out=FILE           File the findings are appended to as JSON lines (default saves/search.jsonl).
+  -seed=N             Seed of the first soup, the others counting up from it (default from the clock).`
+}
+
+func (c *SearchCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	ruleString := flags.String("rule", "B3/S23", "")
+	soups := flags.Int("soups", 1000, "")
+	size := flags.Int("size", 16, "")
+	density := flags.Float64("density", 0.5, "")
+	board := flags.Int("board", 128, "")
+	maxGenerations := flags.Int("max-generations", 5000, "")
+	methuselah := flags.Int("methuselah", 1000, "")
+	workers := flags.Int("workers", runtime.NumCPU(), "")
+	out := flags.String("out", "saves/search.jsonl", "")
+	seed := flags.Int64("seed", 0, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	rule, err := bitlife.ParseRule(*ruleString)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if *size < 1 || *size > *board {
+		fmt.Printf("soup size %d must be between 1 and the board size %d\n", *size, *board)
+		return 1
+	}
+	options := Options{
+		Rule:           rule,
+		Size:           *size,
+		BoardSize:      *board,
+		Density:        *density,
+		MaxGenerations: *maxGenerations,
+		Methuselah:     *methuselah,
+	}
+	if err := searchMain(options, engine.ChooseSeed(*seed), *soups, *workers, *out); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
+
+func (c *SearchCommand) Synopsis() string {
+	return "Search random soups of a Life-like rule for methuselahs and rare objects"
+}
+
+func searchMain(options Options, seed int64, soups, workers int, out string) error {
+	f := setupLogging("logs/search.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	if err := os.MkdirAll(filepath.Dir(out), 0775); err != nil {
+		return err
+	}
+	results, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0664)
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+	encoder := json.NewEncoder(results)
+
+	fmt.Printf("Searching %d soups of %s from seed %d\n", soups, options.Rule, seed)
+	findings := 0
+	var writeErr error
+	total := Search(options, seed, soups, workers, func(finding Finding) {
+		if len(finding.Reasons) == 0 {
+			return
+		}
+		findings++
+		fmt.Printf("Soup %d: %s\n", finding.Seed, strings.Join(finding.Reasons, ", "))
+		if err := encoder.Encode(finding); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	fmt.Printf("\n%d of %d soups recorded in %s\n\n%s", findings, soups, out, total)
+	log.Printf("Searched %d soups, recorded %d\n", soups, findings)
+	return nil
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+// Options describe the soups of a search and what makes one interesting.
+type Options struct {
+	Rule bitlife.Rule
+	// Size is the width and height of a soup
+	Size int
+	// BoardSize is the width and height of the board a soup runs on
+	BoardSize int
+	// Density is the fraction of live cells in a soup
+	Density        float64
+	MaxGenerations int
+	// Methuselah is the number of generations to stabilize that makes a soup interesting
+	Methuselah int
+}
+
+// Common are the objects of Conway's Game of Life that turn up in most soups. The other objects of the catalog are
+// rare enough to record.
+var Common = map[string]bool{
+	"block":     true,
+	"blinker":   true,
+	"beehive":   true,
+	"glider":    true,
+	"loaf":      true,
+	"boat":      true,
+	"pond":      true,
+	"ship":      true,
+	"tub":       true,
+	"long boat": true,
+	"toad":      true,
+	"beacon":    true,
+	"barge":     true,
+}
+
+// Finding is the outcome of a soup, as recorded in the results file.
+type Finding struct {
+	Seed int64  `json:"seed"`
+	Rule string `json:"rule"`
+	Size int    `json:"size"`
+	// Generations is how long the soup took to stabilize, or how long it ran for if it didn't
+	Generations int             `json:"generations"`
+	Stabilized  bool            `json:"stabilized"`
+	Result      string          `json:"result"`
+	Census      analysis.Census `json:"census"`
+	// Reasons are why the soup is interesting, empty for the ones that are not recorded
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Soup runs a random soup on a bitlife.Board until it stabilizes.
+type Soup struct {
+	*engine.Engine
+	Rule     bitlife.Rule
+	Detector *analysis.Detector
+	// Catalog names the objects of the census, and the spaceships removed as they escape
+	Catalog *analysis.Catalog
+	// Escaped counts the spaceships removed so far
+	Escaped analysis.Census
+}
+
+const (
+	// escapeInterval is how many generations apart escaping spaceships are looked for, since it costs more than a step
+	escapeInterval = 32
+	// escapeGap is how far beyond the rest of a soup a spaceship must be to count as escaping
+	escapeGap = 8
+)
+
+type cell bool
+
+func (c cell) Rune() rune {
+	if c {
+		return '█'
+	}
+	return ' '
+}
+
+func (c cell) FgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+func (c cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+func isAlive(c grid.Cell) bool {
+	return c == cell(true)
+}
+
+// NewSoup returns the soup of the seed, with its random cells in the middle of the board.
+func NewSoup(options Options, seed int64) *Soup {
+	board := bitlife.NewBoard(options.BoardSize, options.BoardSize, cell(true), cell(false))
+	soup := &Soup{
+		Engine:   &engine.Engine{Plane: board, UI: io.NullRenderer{}, Seed: seed},
+		Rule:     options.Rule,
+		Detector: analysis.NewDetector(isAlive),
+		Catalog:  analysis.ConwayCatalog,
+		Escaped:  analysis.Census{},
+	}
+	if options.Rule != bitlife.Conway {
+		soup.Catalog = analysis.NewCatalog(options.Rule)
+	}
+	soup.Engine.Handler = soup
+	soup.Engine.PlaneHandler = soup
+
+	random := soup.InitialRandom()
+	corner := (options.BoardSize - options.Size) / 2
+	for y := corner; y < corner+options.Size; y++ {
+		for x := corner; x < corner+options.Size; x++ {
+			board.SetAlive(grid.Position{X: x, Y: y}, random.Float64() < options.Density)
+		}
+	}
+	return soup
+}
+
+func (s *Soup) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	return nil
+}
+
+// StepPlane steps the board and removes the spaceships escaping from the soup, so that they neither crash into the
+// edges nor keep the soup from repeating. It returns no changes since nothing is drawn.
+func (s *Soup) StepPlane(plane grid.Plane) []grid.Position {
+	board := plane.(*bitlife.Board)
+	board.Step(s.Rule)
+	if s.Generation%escapeInterval == 0 {
+		for _, spaceship := range s.Catalog.Escaping(board, isAlive, escapeGap) {
+			s.Escaped[s.Catalog.Match(spaceship).Name]++
+			for _, p := range spaceship {
+				board.SetAlive(p, false)
+			}
+		}
+	}
+	return nil
+}
+
+// RunSoup runs the soup of the seed until it stabilizes or runs out of generations.
+func RunSoup(options Options, seed int64) Finding {
+	soup := NewSoup(options, seed)
+	finding := Finding{Seed: seed, Rule: options.Rule.String(), Size: options.Size}
+	finding.Stabilized = soup.RunUntil(soup.Detector.Stable, options.MaxGenerations)
+	result := soup.Detector.Result()
+	finding.Result = result.String()
+	finding.Generations = soup.Generation
+	if finding.Stabilized {
+		// the cycle started a period before it was seen repeating
+		finding.Generations = result.Generation - result.Period
+	}
+
+	finding.Census = analysis.TakeCensus(soup.Plane, isAlive, soup.Catalog)
+	for name, count := range soup.Escaped {
+		finding.Census[name] += count
+	}
+
+	if !finding.Stabilized {
+		finding.Reasons = append(finding.Reasons, "unstable")
+	} else if finding.Generations >= options.Methuselah {
+		finding.Reasons = append(finding.Reasons, "methuselah")
+	}
+	if options.Rule == bitlife.Conway {
+		// under other rules every object is unknown, since the catalog is empty
+		for _, name := range finding.Census.Names() {
+			if strings.HasPrefix(name, "unknown ") {
+				finding.Reasons = append(finding.Reasons, name)
+			} else if !Common[name] {
+				finding.Reasons = append(finding.Reasons, "rare "+name)
+			}
+		}
+	}
+	return finding
+}
+
+// Search runs the soups with seeds from seed to seed+soups-1 on the workers, calls found with every finding in the
+// order of their seeds, and returns the census of all soups together.
+func Search(options Options, seed int64, soups, workers int, found func(finding Finding)) analysis.Census {
+	if workers < 1 {
+		workers = 1
+	}
+	seeds := make(chan int64)
+	findings := make(chan Finding, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for soupSeed := range seeds {
+				findings <- RunSoup(options, soupSeed)
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < soups; i++ {
+			seeds <- seed + int64(i)
+		}
+		close(seeds)
+		wg.Wait()
+		close(findings)
+	}()
+
+	// findings arrive in the order soups finish, and are held back until those with earlier seeds are in
+	total := analysis.Census{}
+	next := seed
+	pending := map[int64]Finding{}
+	for finding := range findings {
+		pending[finding.Seed] = finding
+		for {
+			f, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			for name, count := range f.Census {
+				total[name] += count
+			}
+			found(f)
+		}
+	}
+	return total
+}
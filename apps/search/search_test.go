@@ -0,0 +1,84 @@
+package search
+
+import (
+	"github.com/jpbetz/cellularautomata/bitlife"
+	"reflect"
+	"testing"
+)
+
+var testOptions = Options{
+	Rule:           bitlife.Conway,
+	Size:           8,
+	BoardSize:      48,
+	Density:        0.5,
+	MaxGenerations: 2000,
+	Methuselah:     100,
+}
+
+func TestRunSoupIsReproducible(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		first, second := RunSoup(testOptions, seed), RunSoup(testOptions, seed)
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("Expected soup %d to run the same twice but was %+v and %+v", seed, first, second)
+		}
+	}
+	if reflect.DeepEqual(RunSoup(testOptions, 1).Census, RunSoup(testOptions, 2).Census) &&
+		RunSoup(testOptions, 1).Generations == RunSoup(testOptions, 2).Generations {
+		t.Errorf("Expected soups of different seeds to differ")
+	}
+}
+
+func TestRunSoupStabilizes(t *testing.T) {
+	finding := RunSoup(testOptions, 3)
+	if !finding.Stabilized {
+		t.Fatalf("Expected soup to stabilize but was %+v", finding)
+	}
+	if finding.Generations >= testOptions.Methuselah {
+		if len(finding.Reasons) == 0 || finding.Reasons[0] != "methuselah" {
+			t.Errorf("Expected a soup taking %d generations to be a methuselah", finding.Generations)
+		}
+	}
+}
+
+func TestSearchInParallel(t *testing.T) {
+	serial := []Finding{}
+	serialTotal := Search(testOptions, 100, 16, 1, func(f Finding) {
+		serial = append(serial, f)
+	})
+	parallel := []Finding{}
+	parallelTotal := Search(testOptions, 100, 16, 4, func(f Finding) {
+		parallel = append(parallel, f)
+	})
+	if !reflect.DeepEqual(serial, parallel) {
+		t.Errorf("Expected the same findings in the same order from 1 and 4 workers")
+	}
+	if !reflect.DeepEqual(serialTotal, parallelTotal) {
+		t.Errorf("Expected the same census from 1 and 4 workers but was %v and %v", serialTotal, parallelTotal)
+	}
+	for i, f := range parallel {
+		if f.Seed != 100+int64(i) {
+			t.Errorf("Expected finding %d to be of seed %d but was %d", i, 100+i, f.Seed)
+		}
+	}
+}
+
+func TestRunSoupRemovesEscapingGliders(t *testing.T) {
+	// soup 11 sends off two gliders, which used to crash into the edges of the board and leave debris behind
+	options := testOptions
+	options.BoardSize = 96
+	finding := RunSoup(options, 11)
+	if finding.Census["glider"] != 2 || finding.Census["block"] != 3 || finding.Census["ship"] != 1 {
+		t.Errorf("Expected the gliders counted and no debris but was %+v", finding)
+	}
+	for _, reason := range finding.Reasons {
+		if reason != "methuselah" {
+			t.Errorf("Expected no rare or unknown objects but found %s", reason)
+		}
+	}
+	// the gliders are removed as they leave the soup, long before they could reach the edges of a larger board
+	options.BoardSize = 256
+	if larger := RunSoup(options, 11); larger.Generations != finding.Generations ||
+		!reflect.DeepEqual(larger.Census, finding.Census) {
+		t.Errorf("Expected the soup to run the same on a larger board but was %+v and %+v", finding, larger)
+	}
+}
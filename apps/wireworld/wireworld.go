@@ -1,10 +1,12 @@
 package wireworld
 
 import (
+	"flag"
 	"fmt"
 	"github.com/jpbetz/cellularautomata/engine"
 	"github.com/jpbetz/cellularautomata/grid"
 	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
 	"github.com/nsf/termbox-go"
 	"log"
 	"os"
@@ -16,19 +18,28 @@ type WireWorldCommand struct {
 }
 
 func (c *WireWorldCommand) Help() string {
-	return "Wire World is a cellular autonomata that simulates electronic circuits."
+	return `Wire World is a cellular autonomata that simulates electronic circuits.
+
+Options:
+` + metrics.Usage
 }
 
 func (c *WireWorldCommand) Run(args []string) int {
-	wireworldMain(c.UI)
-	return 0
+	flags := flag.NewFlagSet("wireworld", flag.ContinueOnError)
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		wireworldMain(c.UI, sink)
+	})
 }
 
 func (c *WireWorldCommand) Synopsis() string {
 	return "Wire World"
 }
 
-func wireworldMain(ui io.Renderer) {
+func wireworldMain(ui io.Renderer, sink *metrics.Sink) {
 	f := setupLogging("logs/wireworld.log")
 	defer f.Close()
 
@@ -39,6 +50,7 @@ func wireworldMain(ui io.Renderer) {
 	ui.SetView(view)
 	board.Initialize(Cell{})
 	game := NewWireworld(board, ui)
+	sink.Observe(game.Engine)
 	eventClock := game.StartClock()
 	game.Playing = true
 
@@ -46,21 +58,24 @@ func wireworldMain(ui io.Renderer) {
 	go func() {
 		for {
 			in := <-ui.Input()
-			switch in.(type) {
-			case io.Quit:
+			if _, ok := in.(io.Quit); ok {
 				done <- true
 				return
-			case io.Click:
-
-			case io.Pause:
-				if game.Playing {
-					eventClock.Stop()
-					game.Playing = false
-				} else {
-					eventClock = game.StartClock()
-					game.Playing = true
-				}
 			}
+			game.Edit(func() {
+				switch in.(type) {
+				case io.Click:
+
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
 		}
 	}()
 
@@ -125,6 +140,9 @@ func (s Cell) BgAttribute() termbox.Attribute {
 	return EmptyColor
 }
 
+// Neighborhood is the neighborhood of the WireWorld rule.
+var Neighborhood = grid.Moore(1)
+
 type Wireworld struct {
 	*engine.Engine
 }
@@ -174,7 +192,17 @@ func (g *Wireworld) initialize() {
 	g.UI.SetStatus("WireWorld")
 }
 
-func (g *Wireworld) UpdateCell(plane grid.Plane, position grid.Position) []engine.CellUpdate {
+// States names the four states of Wireworld for the control API.
+func (g *Wireworld) States() map[string]grid.Cell {
+	return map[string]grid.Cell{"empty": O, "conductor": C, "head": H, "tail": T}
+}
+
+// PatternStates numbers the cells the way Golly's WireWorld rule does.
+func (g *Wireworld) PatternStates() []grid.Cell {
+	return []grid.Cell{O, H, T, C}
+}
+
+func (g *Wireworld) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
 
 	if !plane.Bounds().Contains(position) {
 		return []engine.CellUpdate{}
@@ -190,7 +218,7 @@ func (g *Wireworld) UpdateCell(plane grid.Plane, position grid.Position) []engin
 		return []engine.CellUpdate{{cell, position}}
 	case Conductor:
 		neighboringElectronHeads := 0
-		for _, neighbor := range plane.GetNeighbors(position) {
+		for _, neighbor := range plane.GetNeighbors(position, Neighborhood) {
 			if asCell(neighbor).State == ElectronHead {
 				neighboringElectronHeads++
 			}
@@ -0,0 +1,151 @@
+package wolfram
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Rule is a one dimensional rule over States states where the next state of a cell depends on the Range cells on
+// either side of it. Rules are numbered as Wolfram does: digit i in base States of the rule number is the next state
+// for neighborhood i, where a neighborhood is either read as a base States number, most significant cell on the left,
+// or for totalistic rules is the sum of its cells.
+type Rule struct {
+	Number     *big.Int
+	Range      int
+	States     int
+	Totalistic bool
+	table      []int
+}
+
+// ElementaryRule returns the elementary rule with the given number between 0 and 255.
+func ElementaryRule(number int) (Rule, error) {
+	return NewRule(big.NewInt(int64(number)), 1, 2, false)
+}
+
+// ParseRule parses a rule number in base 10.
+func ParseRule(number string, r, states int, totalistic bool) (Rule, error) {
+	n, ok := new(big.Int).SetString(number, 10)
+	if !ok {
+		return Rule{}, fmt.Errorf("rule number %q is not an integer", number)
+	}
+	return NewRule(n, r, states, totalistic)
+}
+
+func NewRule(number *big.Int, r, states int, totalistic bool) (Rule, error) {
+	if r < 1 {
+		return Rule{}, fmt.Errorf("range must be at least 1 but was %d", r)
+	}
+	if states < 2 {
+		return Rule{}, fmt.Errorf("rules must have at least 2 states but had %d", states)
+	}
+	width := 2*r + 1
+	var neighborhoods int
+	if totalistic {
+		neighborhoods = width*(states-1) + 1
+	} else {
+		neighborhoods = 1
+		for i := 0; i < width; i++ {
+			neighborhoods *= states
+			if neighborhoods > 1<<20 {
+				return Rule{}, fmt.Errorf("range %d with %d states has too many neighborhoods, use a totalistic rule", r, states)
+			}
+		}
+	}
+
+	base := big.NewInt(int64(states))
+	limit := new(big.Int).Exp(base, big.NewInt(int64(neighborhoods)), nil)
+	if number.Sign() < 0 || number.Cmp(limit) >= 0 {
+		return Rule{}, fmt.Errorf("rule number must be between 0 and %s", new(big.Int).Sub(limit, big.NewInt(1)))
+	}
+
+	table := make([]int, neighborhoods)
+	digits := new(big.Int).Set(number)
+	digit := new(big.Int)
+	for i := range table {
+		digits.DivMod(digits, base, digit)
+		table[i] = int(digit.Int64())
+	}
+	return Rule{Number: number, Range: r, States: states, Totalistic: totalistic, table: table}, nil
+}
+
+func (r Rule) String() string {
+	kind := "rule"
+	if r.Totalistic {
+		kind = "totalistic code"
+	}
+	if r.Range == 1 && r.States == 2 && !r.Totalistic {
+		return fmt.Sprintf("Rule %s", r.Number)
+	}
+	return fmt.Sprintf("%s %s (range %d, %d states)", kind, r.Number, r.Range, r.States)
+}
+
+// Next returns the next state of the cell in the middle of the neighborhood, which holds 2*Range+1 states.
+func (r Rule) Next(neighborhood []int) int {
+	index := 0
+	for _, state := range neighborhood {
+		if r.Totalistic {
+			index += state
+		} else {
+			index = index*r.States + state
+		}
+	}
+	return r.table[index]
+}
+
+// Step applies the rule to a whole generation, treating cells beyond the ends according to the boundary.
+func (r Rule) Step(generation []int, boundary Boundary) []int {
+	next := make([]int, len(generation))
+	neighborhood := make([]int, 2*r.Range+1)
+	for i := range generation {
+		for j := range neighborhood {
+			neighborhood[j] = boundary.Get(generation, i+j-r.Range)
+		}
+		next[i] = r.Next(neighborhood)
+	}
+	return next
+}
+
+type Boundary int
+
+const (
+	// Wrap connects the ends of the generation into a ring.
+	Wrap Boundary = iota
+	// Fixed treats cells beyond the ends as state 0.
+	Fixed
+	// Reflect mirrors the generation at its ends.
+	Reflect
+)
+
+func ParseBoundary(boundary string) (Boundary, error) {
+	switch boundary {
+	case "wrap":
+		return Wrap, nil
+	case "fixed":
+		return Fixed, nil
+	case "reflect":
+		return Reflect, nil
+	default:
+		return Wrap, fmt.Errorf("boundary must be one of wrap, fixed or reflect but was %q", boundary)
+	}
+}
+
+// Get returns the state at index i, which may be beyond the ends of the generation.
+func (b Boundary) Get(generation []int, i int) int {
+	n := len(generation)
+	if i >= 0 && i < n {
+		return generation[i]
+	}
+	switch b {
+	case Fixed:
+		return 0
+	case Reflect:
+		period := 2 * n
+		i = ((i % period) + period) % period
+		if i >= n {
+			i = period - 1 - i
+		}
+		return generation[i]
+	default:
+		return generation[((i%n)+n)%n]
+	}
+}
@@ -0,0 +1,71 @@
+package wolfram
+
+import (
+	"fmt"
+	"testing"
+)
+
+func format(generation []int) string {
+	result := ""
+	for _, state := range generation {
+		result += fmt.Sprint(state)
+	}
+	return result
+}
+
+func TestRule30(t *testing.T) {
+	rule, err := ElementaryRule(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generation := []int{0, 0, 0, 0, 1, 0, 0, 0, 0}
+	expected := []string{
+		"000111000",
+		"001100100",
+		"011011110",
+	}
+	for i, e := range expected {
+		generation = rule.Step(generation, Fixed)
+		if format(generation) != e {
+			t.Errorf("Expected generation %d to be %s but found %s", i+1, e, format(generation))
+		}
+	}
+}
+
+func TestTotalisticRule(t *testing.T) {
+	// 3 color totalistic code 1599 (range 1), digits in base 3 from sum 0 to 6: 0,2,0,2,1,0,2
+	rule, err := ParseRule("1599", 1, 3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := rule.Step([]int{0, 0, 1, 0, 0}, Fixed)
+	if format(next) != "02220" {
+		t.Errorf("Expected 02220 but found %s", format(next))
+	}
+}
+
+func TestBoundaries(t *testing.T) {
+	generation := []int{1, 2, 3}
+	cases := []struct {
+		boundary Boundary
+		index    int
+		expected int
+	}{
+		{Wrap, -1, 3},
+		{Wrap, 3, 1},
+		{Fixed, -1, 0},
+		{Reflect, -1, 1},
+		{Reflect, 4, 2},
+	}
+	for _, c := range cases {
+		if actual := c.boundary.Get(generation, c.index); actual != c.expected {
+			t.Errorf("Expected boundary %d at index %d to be %d but found %d", c.boundary, c.index, c.expected, actual)
+		}
+	}
+}
+
+func TestRuleOutOfRange(t *testing.T) {
+	if _, err := ElementaryRule(256); err == nil {
+		t.Error("Expected rule 256 to be rejected")
+	}
+}
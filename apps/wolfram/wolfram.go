@@ -0,0 +1,285 @@
+package wolfram
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/metrics"
+	"github.com/nsf/termbox-go"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+)
+
+type WolframCommand struct {
+	UI io.Renderer
+}
+
+func (c *WolframCommand) Help() string {
+	return `Runs a one dimensional cellular automaton and draws it as a space-time diagram, one generation per row,
+scrolling once the board is full.
+
+Options:
+  -rule=N           Rule number, 0-255 for elementary rules (default 30).
+  -range=R          Number of cells on each side of a cell that affect it (default 1).
+  -states=K         Number of states (default 2).
+  -totalistic       Interpret the rule number as a totalistic code, where the next state depends only on the sum
+                    of the neighborhood.
+  -start=S          Initial generation: single (default) for one live cell in the middle, random, or a string of
+                    states such as 1011 that is placed in the middle.
+  -boundary=B       How cells beyond the ends are treated: wrap (default), fixed (state 0) or reflect.
+  -width=W          Number of cells per generation (default 60).
+  -height=H         Number of generations shown (default 40).
+  -seed=N           Seed for the random start, so that runs with the same seed are identical. The seed of every run
+                    is written to the log (default from the clock).
+` + metrics.Usage
+}
+
+func (c *WolframCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("wolfram", flag.ContinueOnError)
+	ruleNumber := flags.String("rule", "30", "")
+	r := flags.Int("range", 1, "")
+	states := flags.Int("states", 2, "")
+	totalistic := flags.Bool("totalistic", false, "")
+	start := flags.String("start", "single", "")
+	boundaryName := flags.String("boundary", "wrap", "")
+	width := flags.Int("width", 60, "")
+	height := flags.Int("height", 40, "")
+	seed := flags.Int64("seed", 0, "")
+	withMetrics := metrics.Flag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if *width < 1 || *height < 1 {
+		fmt.Println("width and height must be at least 1")
+		return 1
+	}
+	rule, err := ParseRule(*ruleNumber, *r, *states, *totalistic)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	boundary, err := ParseBoundary(*boundaryName)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	chosenSeed := engine.ChooseSeed(*seed)
+	random := rand.New(engine.NewRandom(chosenSeed, -1, grid.Origin))
+	initial, err := initialGeneration(*start, *width, rule.States, random)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return withMetrics(func(sink *metrics.Sink) {
+		wolframMain(c.UI, rule, boundary, initial, *height, chosenSeed, sink)
+	})
+}
+
+func (c *WolframCommand) Synopsis() string {
+	return "Elementary and other one dimensional cellular automata"
+}
+
+func initialGeneration(start string, width, states int, random *rand.Rand) ([]int, error) {
+	generation := make([]int, width)
+	switch start {
+	case "single":
+		generation[width/2] = 1
+	case "random":
+		for i := range generation {
+			generation[i] = random.Intn(states)
+		}
+	default:
+		offset := (width - len(start)) / 2
+		for i, c := range start {
+			state := int(c - '0')
+			if state < 0 || state >= states {
+				return nil, fmt.Errorf("start %q must be single, random or a string of states between 0 and %d", start, states-1)
+			}
+			if offset+i >= 0 && offset+i < width {
+				generation[offset+i] = state
+			}
+		}
+	}
+	return generation, nil
+}
+
+func wolframMain(ui io.Renderer, rule Rule, boundary Boundary, initial []int, height int, seed int64,
+	sink *metrics.Sink) {
+	f := setupLogging("logs/wolfram.log")
+	defer f.Close()
+	log.Printf("Seed: %d\n", seed)
+
+	ui.Run()
+
+	board := grid.NewBasicBoard(len(initial), height)
+	view := &io.View{Plane: board, Offset: grid.Origin}
+	ui.SetView(view)
+	board.Initialize(Cell{States: rule.States})
+	game := NewWolfram(board, ui, rule, boundary, initial)
+	sink.Observe(game.Engine)
+	eventClock := game.StartClock()
+	game.Playing = true
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			in := <-ui.Input()
+			if _, ok := in.(io.Quit); ok {
+				done <- true
+				return
+			}
+			game.Edit(func() {
+				switch in.(type) {
+				case io.Pause:
+					if game.Playing {
+						eventClock.Stop()
+						game.Playing = false
+					} else {
+						eventClock = game.StartClock()
+						game.Playing = true
+					}
+				}
+			})
+		}
+	}()
+
+	ui.Loop(done)
+}
+
+func setupLogging(filename string) *os.File {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("error opening file: %v", err))
+	}
+	log.SetOutput(f)
+	return f
+}
+
+const ActiveRune = '█'
+const EmptyRune = ' '
+
+var EmptyColor = grid.Color{R: 0x0e, G: 0x0e, B: 0x0e}
+var FullColor = grid.Color{R: 0xff, G: 0xff, B: 0xff}
+
+type Cell struct {
+	State  int
+	States int
+}
+
+func (c Cell) Rune() rune {
+	if c.State == 0 {
+		return EmptyRune
+	}
+	return ActiveRune
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	if c.State == 0 {
+		return termbox.ColorDefault
+	}
+	return termbox.ColorWhite
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+// Color shades states from the background for state 0 to white for the highest state.
+func (c Cell) Color() grid.Color {
+	return grid.Gradient(EmptyColor, FullColor, float64(c.State)/float64(c.States-1))
+}
+
+// Wolfram draws generation t in row t of the plane until the plane is full, after which every generation scrolls the
+// rows up by one and the newest generation is drawn in the bottom row.
+type Wolfram struct {
+	*engine.Engine
+	Rule     Rule
+	Boundary Boundary
+
+	// next generation, computed once per engine generation
+	next           []int
+	nextGeneration int
+}
+
+func asCell(cell grid.Cell) Cell {
+	wolframCell, ok := cell.(Cell)
+	if !ok {
+		panic("Expected Wolfram cell")
+	}
+	return wolframCell
+}
+
+func NewWolfram(plane grid.Plane, ui io.Renderer, rule Rule, boundary Boundary, initial []int) *Wolfram {
+	game := &Wolfram{
+		Engine:         &engine.Engine{Plane: plane, UI: ui, ClockSpeed: time.Millisecond * 100},
+		Rule:           rule,
+		Boundary:       boundary,
+		nextGeneration: -1,
+	}
+	game.Engine.Handler = game
+	game.initialize(initial)
+	return game
+}
+
+func (g *Wolfram) initialize(initial []int) {
+	for x, state := range initial {
+		g.Set(grid.Position{x, 0}, Cell{State: state, States: g.Rule.States})
+	}
+	g.UI.SetStatus(g.Rule.String())
+}
+
+// latestRow returns the row holding the most recent generation.
+func (g *Wolfram) latestRow() int {
+	bottom := g.Plane.Bounds().Corner2.Y
+	if g.Generation < bottom {
+		return g.Generation
+	}
+	return bottom
+}
+
+func (g *Wolfram) nextRow(plane grid.Plane) []int {
+	if g.nextGeneration != g.Generation {
+		bounds := plane.Bounds()
+		y := g.latestRow()
+		current := make([]int, bounds.Corner2.X-bounds.Corner1.X+1)
+		for i := range current {
+			current[i] = asCell(plane.Get(grid.Position{bounds.Corner1.X + i, y})).State
+		}
+		g.next = g.Rule.Step(current, g.Boundary)
+		g.nextGeneration = g.Generation
+		g.UI.SetStatus(fmt.Sprintf("%s, generation %d", g.Rule, g.Generation+1))
+	}
+	return g.next
+}
+
+func (g *Wolfram) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	bounds := plane.Bounds()
+	if !bounds.Contains(position) {
+		return []engine.CellUpdate{}
+	}
+
+	latest := g.latestRow()
+	var state int
+	switch {
+	case latest < bounds.Corner2.Y && position.Y == latest+1:
+		state = g.nextRow(plane)[position.X-bounds.Corner1.X]
+	case latest == bounds.Corner2.Y && position.Y < latest:
+		state = asCell(plane.Get(grid.Position{position.X, position.Y + 1})).State
+	case latest == bounds.Corner2.Y && position.Y == latest:
+		state = g.nextRow(plane)[position.X-bounds.Corner1.X]
+	default:
+		return []engine.CellUpdate{}
+	}
+
+	cell := asCell(plane.Get(position))
+	if cell.State == state {
+		return []engine.CellUpdate{}
+	}
+	cell.State = state
+	return []engine.CellUpdate{{cell, position}}
+}
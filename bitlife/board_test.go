@@ -0,0 +1,107 @@
+package bitlife
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/nsf/termbox-go"
+	"math/rand"
+	"testing"
+)
+
+type testCell bool
+
+func (c testCell) Rune() rune                     { return ' ' }
+func (c testCell) FgAttribute() termbox.Attribute { return termbox.ColorDefault }
+func (c testCell) BgAttribute() termbox.Attribute { return termbox.ColorDefault }
+
+func randomBoard(w, h int, random *rand.Rand) *Board {
+	board := NewBoard(w, h, testCell(true), testCell(false))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			board.SetAlive(grid.Position{x, y}, random.Intn(3) == 0)
+		}
+	}
+	return board
+}
+
+// naiveStep computes the next generation a cell at a time.
+func naiveStep(board *Board, rule Rule) map[grid.Position]bool {
+	next := map[grid.Position]bool{}
+	for x := 0; x < board.W; x++ {
+		for y := 0; y < board.H; y++ {
+			p := grid.Position{x, y}
+			live := 0
+			for _, neighbor := range board.GetNeighborPositions(p, grid.Moore(1)) {
+				if board.IsAlive(neighbor) {
+					live++
+				}
+			}
+			next[p] = rule.Next(board.IsAlive(p), live)
+		}
+	}
+	return next
+}
+
+func TestStepMatchesNaiveStep(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, ruleString := range []string{"B3/S23", "B36/S23", "B3678/S34678", "B1/S012345678", "B0/S8"} {
+		rule, err := ParseRule(ruleString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// widths on both sides of word boundaries
+		for _, w := range []int{1, 63, 64, 65, 130} {
+			board := randomBoard(w, 17, random)
+			for generation := 0; generation < 5; generation++ {
+				expected := naiveStep(board, rule)
+				changed := map[grid.Position]bool{}
+				for p, alive := range expected {
+					if alive != board.IsAlive(p) {
+						changed[p] = true
+					}
+				}
+				board.Step(rule)
+				for p, alive := range expected {
+					if board.IsAlive(p) != alive {
+						t.Fatalf("%s on a %d wide board, generation %d: expected %v alive=%t", rule, w, generation, p, alive)
+					}
+				}
+				board.Changes(func(p grid.Position) {
+					if !changed[p] {
+						t.Errorf("%s: %v was reported changed but did not change", rule, p)
+					}
+					delete(changed, p)
+				})
+				if len(changed) > 0 {
+					t.Errorf("%s: changes %v were not reported", rule, changed)
+				}
+			}
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("b36/s23")
+	if err != nil {
+		t.Fatalf("Expected rule to parse, but got: %v", err)
+	}
+	if rule.String() != "B36/S23" {
+		t.Errorf("Expected B36/S23 but found %s", rule)
+	}
+	if rule, _ := ParseRule("B3/S23"); rule != Conway {
+		t.Errorf("Expected B3/S23 to be Conway's rule but found %s", rule)
+	}
+	for _, invalid := range []string{"", "23/3", "B3", "B9/S23", "S23/B3"} {
+		if _, err := ParseRule(invalid); err == nil {
+			t.Errorf("Expected rule %q to be rejected", invalid)
+		}
+	}
+}
+
+func BenchmarkStep(b *testing.B) {
+	board := randomBoard(1024, 1024, rand.New(rand.NewSource(1)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board.Step(Conway)
+	}
+	b.ReportMetric(float64(1024*1024)*float64(b.N)/b.Elapsed().Seconds(), "cells/s")
+}
@@ -0,0 +1,68 @@
+package bitlife
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rule is a binary Life-like rule on the Moore neighborhood: a dead cell is born when its number of live neighbors is
+// in Birth, and a live cell survives when it is in Survive.
+type Rule struct {
+	Birth   [9]bool
+	Survive [9]bool
+}
+
+// Conway is the rule of Conway's Game of Life, B3/S23.
+var Conway = Rule{
+	Birth:   [9]bool{3: true},
+	Survive: [9]bool{2: true, 3: true},
+}
+
+// ParseRule parses a rule in B/S notation, e.g. "B3/S23" for Life or "B36/S23" for HighLife.
+func ParseRule(rule string) (Rule, error) {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(rule)), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "B") || !strings.HasPrefix(parts[1], "S") {
+		return Rule{}, fmt.Errorf("rule %q must have the form B.../S...", rule)
+	}
+	var r Rule
+	if err := parseCounts(parts[0][1:], &r.Birth); err != nil {
+		return Rule{}, fmt.Errorf("rule %q has invalid birth counts: %v", rule, err)
+	}
+	if err := parseCounts(parts[1][1:], &r.Survive); err != nil {
+		return Rule{}, fmt.Errorf("rule %q has invalid survival counts: %v", rule, err)
+	}
+	return r, nil
+}
+
+func parseCounts(counts string, result *[9]bool) error {
+	for _, c := range counts {
+		if c < '0' || c > '8' {
+			return fmt.Errorf("neighbor count %q is not between 0 and 8", c)
+		}
+		result[c-'0'] = true
+	}
+	return nil
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("B%s/S%s", formatCounts(r.Birth), formatCounts(r.Survive))
+}
+
+func formatCounts(counts [9]bool) string {
+	result := ""
+	for i, set := range counts {
+		if set {
+			result += strconv.Itoa(i)
+		}
+	}
+	return result
+}
+
+// Next returns whether a cell is alive in the next generation.
+func (r Rule) Next(alive bool, liveNeighbors int) bool {
+	if alive {
+		return r.Survive[liveNeighbors]
+	}
+	return r.Birth[liveNeighbors]
+}
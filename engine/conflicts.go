@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/nsf/termbox-go"
+	"log"
+	"strings"
+)
+
+// ConflictPolicy decides what is applied when the updates of a generation write to the same position more than once,
+// e.g. when two ants move into the same square.
+type ConflictPolicy int
+
+const (
+	// LastWins applies the update of the cell visited last.
+	LastWins ConflictPolicy = iota
+	// FirstWins applies the update of the cell visited first.
+	FirstWins
+	// ErrorOnConflict applies none of the updates of the generation and stops the clock, see Engine.Err.
+	ErrorOnConflict
+	// MergeConflicts combines the updates with the MergeUpdates of the handler, which must be a MergingHandler.
+	MergeConflicts
+)
+
+var conflictPolicyNames = []string{"last", "first", "error", "merge"}
+
+func (p ConflictPolicy) String() string {
+	return conflictPolicyNames[p]
+}
+
+// ParseConflictPolicy returns the policy with the name used by String, e.g. for command line flags.
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	for i, policyName := range conflictPolicyNames {
+		if name == policyName {
+			return ConflictPolicy(i), nil
+		}
+	}
+	return LastWins, fmt.Errorf("conflict policy %q must be one of %s", name, strings.Join(conflictPolicyNames, ", "))
+}
+
+// MergingHandler may be implemented by an UpdateHandler to combine conflicting updates under MergeConflicts. Updates
+// are merged in the order the cells were visited.
+type MergingHandler interface {
+	MergeUpdates(position grid.Position, first, second grid.Cell) grid.Cell
+}
+
+// Conflict is a position written to more than once in a generation, by the updates of the cells at Sources.
+type Conflict struct {
+	Position grid.Position
+	Sources  []grid.Position
+}
+
+func (c Conflict) String() string {
+	sources := make([]string, len(c.Sources))
+	for i, source := range c.Sources {
+		sources[i] = fmt.Sprintf("%d,%d", source.X, source.Y)
+	}
+	return fmt.Sprintf("%d,%d written by %s", c.Position.X, c.Position.Y, strings.Join(sources, " and "))
+}
+
+// ConflictsError is the error of a generation that was not applied because of conflicts.
+type ConflictsError struct {
+	Generation int
+	Conflicts  []Conflict
+}
+
+func (e *ConflictsError) Error() string {
+	return fmt.Sprintf("generation %d has %d conflicting updates, first at %s", e.Generation, len(e.Conflicts),
+		e.Conflicts[0])
+}
+
+// ConflictsOverlay is the overlay that highlights conflicting updates in debug mode.
+const ConflictsOverlay = "conflicts"
+
+type conflictMarker struct{}
+
+func (m conflictMarker) Rune() rune {
+	return '!'
+}
+
+func (m conflictMarker) FgAttribute() termbox.Attribute {
+	return termbox.ColorRed
+}
+
+func (m conflictMarker) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+func (m conflictMarker) Color() grid.Color {
+	return grid.Color{R: 0xff, G: 0x00, B: 0x00}
+}
+
+// resolve leaves one update per position according to the conflict policy, and returns the conflicts it found.
+// sources holds the position of the cell that made each change.
+func (e *Engine) resolve(changes []CellUpdate, sources []grid.Position) ([]CellUpdate, []Conflict, error) {
+	resolved := make([]CellUpdate, 0, len(changes))
+	// the source of the first update of each resolved position, which resolved no longer lines up with sources for
+	resolvedSources := make([]grid.Position, 0, len(changes))
+	indexes := make(map[grid.Position]int, len(changes))
+	conflicts := []Conflict{}
+	conflictIndexes := map[grid.Position]int{}
+	merger, canMerge := e.Handler.(MergingHandler)
+	for i, change := range changes {
+		index, ok := indexes[change.Position]
+		if !ok {
+			indexes[change.Position] = len(resolved)
+			resolved = append(resolved, change)
+			resolvedSources = append(resolvedSources, sources[i])
+			continue
+		}
+
+		if c, ok := conflictIndexes[change.Position]; ok {
+			conflicts[c].Sources = append(conflicts[c].Sources, sources[i])
+		} else {
+			conflictIndexes[change.Position] = len(conflicts)
+			conflicts = append(conflicts, Conflict{change.Position, []grid.Position{resolvedSources[index], sources[i]}})
+		}
+		switch e.Conflicts {
+		case LastWins:
+			resolved[index] = change
+		case MergeConflicts:
+			if !canMerge {
+				return nil, conflicts, fmt.Errorf("the merge conflict policy needs a handler that merges updates")
+			}
+			resolved[index].State = merger.MergeUpdates(change.Position, resolved[index].State, change.State)
+		}
+	}
+	if len(conflicts) > 0 && e.Conflicts == ErrorOnConflict {
+		return nil, conflicts, &ConflictsError{e.Generation, conflicts}
+	}
+	return resolved, conflicts, nil
+}
+
+// reportConflicts logs the conflicts of a generation and highlights them in the conflicts overlay.
+func (e *Engine) reportConflicts(conflicts []Conflict) {
+	if len(conflicts) == 0 {
+		if e.conflictsShown {
+			e.UI.SetOverlay(ConflictsOverlay, nil)
+			e.conflictsShown = false
+		}
+		return
+	}
+	overlay := make(map[grid.Position]grid.Cell, len(conflicts))
+	for _, conflict := range conflicts {
+		log.Printf("Generation %d: conflicting updates at %s\n", e.Generation, conflict)
+		overlay[conflict.Position] = conflictMarker{}
+	}
+	e.UI.SetOverlay(ConflictsOverlay, overlay)
+	e.conflictsShown = true
+}
+
+// fail stops the engine after a generation could not be applied.
+func (e *Engine) fail(err error) {
+	e.Err = err
+	log.Printf("Stopped: %v\n", err)
+	e.UI.SetStatus(fmt.Sprintf("Stopped: %v", err))
+	if e.eventClock != nil {
+		e.eventClock.Stop()
+	}
+	e.Playing = false
+}
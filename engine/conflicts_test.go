@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/nsf/termbox-go"
+	"reflect"
+	"testing"
+)
+
+type countCell int
+
+func (c countCell) Rune() rune                     { return ' ' }
+func (c countCell) FgAttribute() termbox.Attribute { return termbox.ColorDefault }
+func (c countCell) BgAttribute() termbox.Attribute { return termbox.ColorDefault }
+
+// converging writes the x coordinate plus one of the two cells of the top row into the cell below them.
+type converging struct{}
+
+func (h converging) UpdateCell(plane grid.Plane, position grid.Position, random *Random) []CellUpdate {
+	if position.Y != 0 {
+		return []CellUpdate{}
+	}
+	return []CellUpdate{{countCell(position.X + 1), grid.Position{0, 1}}}
+}
+
+type summing struct {
+	converging
+}
+
+func (h summing) MergeUpdates(position grid.Position, first, second grid.Cell) grid.Cell {
+	return first.(countCell) + second.(countCell)
+}
+
+func TestConflictPolicies(t *testing.T) {
+	cases := []struct {
+		policy   ConflictPolicy
+		handler  UpdateHandler
+		expected countCell
+		fails    bool
+	}{
+		{LastWins, converging{}, 2, false},
+		{FirstWins, converging{}, 1, false},
+		{MergeConflicts, summing{}, 3, false},
+		{MergeConflicts, converging{}, 0, true},
+		{ErrorOnConflict, converging{}, 0, true},
+	}
+	for _, c := range cases {
+		board := grid.NewBasicBoard(2, 2)
+		board.Initialize(countCell(0))
+		e := &Engine{Plane: board, UI: io.NullRenderer{}, Handler: c.handler, Conflicts: c.policy, Debug: true}
+		e.Step()
+		if (e.Err != nil) != c.fails {
+			t.Errorf("Expected %s with %T to fail %t but the error was %v", c.policy, c.handler, c.fails, e.Err)
+		}
+		if cell := board.Get(grid.Position{0, 1}); cell != c.expected {
+			t.Errorf("Expected %s with %T to write %d but found %v", c.policy, c.handler, c.expected, cell)
+		}
+		if c.fails && e.Generation != 0 {
+			t.Errorf("Expected a failed generation not to advance but it is %d", e.Generation)
+		}
+	}
+}
+
+func TestConflictSources(t *testing.T) {
+	board := grid.NewBasicBoard(2, 2)
+	board.Initialize(countCell(0))
+	e := &Engine{Plane: board, UI: io.NullRenderer{}, Handler: converging{}, Conflicts: ErrorOnConflict}
+	e.Step()
+	err, ok := e.Err.(*ConflictsError)
+	if !ok || len(err.Conflicts) != 1 {
+		t.Fatalf("Expected one conflict but the error was %v", e.Err)
+	}
+	conflict := err.Conflicts[0]
+	if conflict.Position != (grid.Position{0, 1}) || len(conflict.Sources) != 2 ||
+		conflict.Sources[0] != (grid.Position{0, 0}) || conflict.Sources[1] != (grid.Position{1, 0}) {
+		t.Errorf("Expected 0,1 to be written by 0,0 and 1,0 but found %s", conflict)
+	}
+}
+
+// pairs makes the two cells of each column of the top two rows write to the bottom row of the other column.
+type pairs struct{}
+
+func (h pairs) UpdateCell(plane grid.Plane, position grid.Position, random *Random) []CellUpdate {
+	if position.Y > 1 {
+		return []CellUpdate{}
+	}
+	return []CellUpdate{{countCell(1), grid.Position{1 - position.X, 2}}}
+}
+
+func TestConflictSourcesOfSeveralConflicts(t *testing.T) {
+	board := grid.NewBasicBoard(2, 3)
+	board.Initialize(countCell(0))
+	e := &Engine{Plane: board, UI: io.NullRenderer{}, Handler: pairs{}, Conflicts: ErrorOnConflict}
+	e.Step()
+	err, ok := e.Err.(*ConflictsError)
+	if !ok || len(err.Conflicts) != 2 {
+		t.Fatalf("Expected two conflicts but the error was %v", e.Err)
+	}
+	expected := []Conflict{
+		{grid.Position{1, 2}, []grid.Position{{0, 0}, {0, 1}}},
+		{grid.Position{0, 2}, []grid.Position{{1, 0}, {1, 1}}},
+	}
+	for i, conflict := range err.Conflicts {
+		if !reflect.DeepEqual(conflict, expected[i]) {
+			t.Errorf("Expected %s but found %s", expected[i], conflict)
+		}
+	}
+}
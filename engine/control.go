@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	runningMu sync.Mutex
+	running   *Engine
+)
+
+func setRunning(e *Engine) {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	running = e
+}
+
+// Running returns the engine whose clock was started last, which is the engine of the command the process runs, or
+// nil before any clock has started. Servers such as the control API use it to find the simulation to act on.
+func Running() *Engine {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	return running
+}
+
+// Edit runs f between two generations, so that f can read and change the plane while the clock is running without
+// seeing a generation half computed. Calls to Edit are serialized with each other and with the clock, and commands
+// handle their input in Edit, so that edits made by other means, such as the control API, are serialized with the UI.
+func (e *Engine) Edit(f func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	f()
+}
+
+// SetClockSpeed changes the time between generations, taking effect at once if the clock is running.
+func (e *Engine) SetClockSpeed(clockSpeed time.Duration) {
+	e.ClockSpeed = clockSpeed
+	// a stopped ticker would start again if it was reset
+	if e.eventClock != nil && e.Playing {
+		e.eventClock.Reset(clockSpeed)
+	}
+}
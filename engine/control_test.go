@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"testing"
+	"time"
+)
+
+func TestEditExcludesTheClock(t *testing.T) {
+	board := grid.NewBasicBoard(2, 2)
+	board.Initialize(countCell(0))
+	e := &Engine{Plane: board, UI: io.NullRenderer{}, Handler: converging{}, ClockSpeed: time.Millisecond}
+	clock := e.StartClock()
+	defer clock.Stop()
+	for i := 0; i < 20; i++ {
+		e.Edit(func() {
+			generation := e.Generation
+			time.Sleep(2 * time.Millisecond)
+			if e.Generation != generation {
+				t.Errorf("Expected no generation to be computed during an edit but went from %d to %d",
+					generation, e.Generation)
+			}
+		})
+	}
+	e.Edit(func() {
+		if e.Generation == 0 {
+			t.Errorf("Expected the clock to step between edits")
+		}
+	})
+}
@@ -3,6 +3,9 @@ package engine
 import (
 	"github.com/jpbetz/cellularautomata/grid"
 	"github.com/jpbetz/cellularautomata/io"
+	"math"
+	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -11,17 +14,79 @@ type CellUpdate struct {
 	Position grid.Position
 }
 
+// UpdateHandler returns the changes to a cell in the next generation. Rules that need randomness must draw it from
+// random, which is derived from the seed of the engine, the generation and the position.
 type UpdateHandler interface {
-	UpdateCell(plane grid.Plane, position grid.Position) []CellUpdate
+	UpdateCell(plane grid.Plane, position grid.Position, random *Random) []CellUpdate
+}
+
+// GenerationHandler may be implemented by an UpdateHandler that needs to act once per generation, after all cell
+// updates have been applied and before the generation is drawn.
+type GenerationHandler interface {
+	EndGeneration(plane grid.Plane)
+}
+
+// RelaxingHandler may be implemented by an UpdateHandler whose rule cascades within a generation, such as a sandpile
+// toppling until every cell is stable. After the updates of a generation are applied, the engine keeps running
+// further passes of UpdateCell over the plane as sub-steps of the same generation, until a pass changes nothing or
+// MaxSubSteps passes have run. Handlers can tell the passes apart by the SubStep of the engine.
+type RelaxingHandler interface {
+	MaxSubSteps() int
+}
+
+// Block is a 2x2 block of cells, in the order top left, top right, bottom left, bottom right.
+type Block [4]grid.Cell
+
+// BlockHandler returns the next state of a 2x2 block, for block cellular automata using the Margolus neighborhood:
+// the plane is partitioned into 2x2 blocks, and the partition is shifted by one cell diagonally every other
+// generation so that information travels between blocks. Blocks that would extend past the bounds of the plane are
+// left unchanged. Cells are compared to find the changes of a block, so they must be comparable.
+type BlockHandler interface {
+	UpdateBlock(block Block, random *Random) Block
+}
+
+// PlaneHandler advances the whole plane by a generation at once, for planes such as bitlife.Board that update many
+// cells per operation, and returns the positions that changed so they can be drawn.
+type PlaneHandler interface {
+	StepPlane(plane grid.Plane) []grid.Position
 }
 
 type Engine struct {
-	Plane      grid.Plane
-	UI         io.Renderer
-	Playing    bool
-	Handler    UpdateHandler
+	Plane   grid.Plane
+	UI      io.Renderer
+	Playing bool
+	Handler UpdateHandler
+	// BlockHandler, if set, updates the plane a block at a time instead of Handler a cell at a time
+	BlockHandler BlockHandler
+	// PlaneHandler, if set, updates the whole plane at once instead of Handler a cell at a time
+	PlaneHandler PlaneHandler
+	// InPlace visits the cells in a random order every generation and applies the updates of each cell before
+	// visiting the next, instead of applying all updates together at the end of the generation. Rules in which cells
+	// move, such as falling particles, then see where earlier cells moved to, so two particles never claim the same
+	// cell. A cell changed by an earlier cell is not visited again in the same generation, so it moves at most once.
+	InPlace bool
+	// Conflicts decides which update is applied when a generation writes to the same position more than once
+	Conflicts ConflictPolicy
+	// Debug logs conflicting updates with the cells that made them and highlights them in the conflicts overlay
+	Debug bool
+	// Err is why the engine stopped, e.g. a ConflictsError, or nil while it runs
+	Err error
+	// Observers are called with the stats of every generation
+	Observers  []Observer
 	ClockSpeed time.Duration
+	Generation int
+	// SubStep counts the passes over the plane within the current generation, see RelaxingHandler
+	SubStep    int
+	Seed       int64
 	eventClock *time.Ticker
+	// held while the clock steps the plane, and by Edit
+	mu sync.Mutex
+
+	conflictsShown bool
+	// the stats of the generation being computed, while there are observers
+	stats *Stats
+	// cells written in the generation being computed
+	updates int
 }
 
 func (e *Engine) StartClock() *time.Ticker {
@@ -32,30 +97,197 @@ func (e *Engine) StartClock() *time.Ticker {
 		}
 	}()
 	e.eventClock = eventClock
+	setRunning(e)
 	return eventClock
 }
 
 func (e *Engine) clockEvent() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Step()
+}
+
+// Step advances the plane by one generation and draws it.
+func (e *Engine) Step() {
+	start := time.Now()
+	if len(e.Observers) > 0 {
+		e.stats = &Stats{}
+	}
+	e.SubStep = 0
+	e.Err = nil
+	e.updates = 0
+	if e.PlaneHandler != nil {
+		for _, position := range e.PlaneHandler.StepPlane(e.Plane) {
+			e.updates++
+			cell := e.Plane.Get(position)
+			if e.stats != nil {
+				e.record(position, nil, cell)
+			}
+			e.UI.Set(position, cell)
+		}
+	} else if e.BlockHandler != nil {
+		e.blockPass()
+	} else if e.InPlace {
+		e.inPlacePass()
+	} else if changed := e.pass(); changed {
+		e.relax()
+	}
+	if e.Err != nil {
+		e.stats = nil
+		return
+	}
+	e.Generation++
+	if handler, ok := e.Handler.(GenerationHandler); ok {
+		handler.EndGeneration(e.Plane)
+	}
+	if e.stats != nil {
+		e.observe(start)
+	}
+	e.monitorStep(time.Since(start))
+	e.UI.Draw()
+}
+
+// RunUntil steps the engine without the clock until done returns true for the plane, which it is asked first before
+// any step, and after every generation, or until maxGenerations have run or the engine fails. It returns whether done
+// returned true, e.g. for headless runs that stop once a pattern has stabilized.
+func (e *Engine) RunUntil(done func(plane grid.Plane) bool, maxGenerations int) bool {
+	for i := 0; ; i++ {
+		if done(e.Plane) {
+			return true
+		}
+		if i == maxGenerations {
+			return false
+		}
+		e.Step()
+		if e.Err != nil {
+			return false
+		}
+	}
+}
+
+// relax runs the sub-steps of a RelaxingHandler after the first pass of a generation changed the plane.
+func (e *Engine) relax() {
+	changed := true
+	if relaxing, ok := e.Handler.(RelaxingHandler); ok {
+		for changed && e.SubStep+1 < relaxing.MaxSubSteps() {
+			e.SubStep++
+			changed = e.pass()
+		}
+	}
+}
+
+// pass updates every cell of the plane once and reports whether anything changed. Updates that write to the same
+// position are resolved by the conflict policy.
+func (e *Engine) pass() bool {
 	bounds := e.Plane.Bounds()
 	changes := []CellUpdate{}
-	for i := bounds.Corner1.X; i < bounds.Corner2.X; i++ {
-		for j := bounds.Corner1.Y; j < bounds.Corner2.Y; j++ {
-			updates := e.Handler.UpdateCell(e.Plane, grid.Position{X: i, Y: j})
+	sources := []grid.Position{}
+	for i := bounds.Corner1.X; i <= bounds.Corner2.X; i++ {
+		for j := bounds.Corner1.Y; j <= bounds.Corner2.Y; j++ {
+			position := grid.Position{X: i, Y: j}
+			updates := e.Handler.UpdateCell(e.Plane, position, e.random(position))
 			for _, update := range updates {
 				changes = append(changes, update)
+				sources = append(sources, position)
 			}
 		}
 	}
+	changes, conflicts, err := e.resolve(changes, sources)
+	if e.Debug {
+		e.reportConflicts(conflicts)
+	}
+	if err != nil {
+		e.fail(err)
+		return false
+	}
 	for _, change := range changes {
 		e.Set(change.Position, change.State)
 	}
-	e.UI.Draw()
+	return len(changes) > 0
+}
+
+// inPlacePass updates the cells of the plane one at a time in a random order and reports whether anything changed.
+func (e *Engine) inPlacePass() bool {
+	bounds := e.Plane.Bounds()
+	positions := []grid.Position{}
+	for i := bounds.Corner1.X; i <= bounds.Corner2.X; i++ {
+		for j := bounds.Corner1.Y; j <= bounds.Corner2.Y; j++ {
+			positions = append(positions, grid.Position{X: i, Y: j})
+		}
+	}
+	// the order is drawn from a position outside of any plane, so that it is independent of the numbers of the cells
+	order := rand.New(NewRandom(e.Seed, e.Generation, grid.Position{X: math.MinInt32, Y: math.MinInt32}))
+	order.Shuffle(len(positions), func(i, j int) {
+		positions[i], positions[j] = positions[j], positions[i]
+	})
+
+	changed := map[grid.Position]bool{}
+	for _, position := range positions {
+		if changed[position] {
+			continue
+		}
+		for _, update := range e.Handler.UpdateCell(e.Plane, position, e.random(position)) {
+			e.Set(update.Position, update.State)
+			changed[update.Position] = true
+		}
+	}
+	return len(changed) > 0
+}
+
+// blockPass updates every 2x2 block of the partition of the current generation and reports whether anything changed.
+func (e *Engine) blockPass() bool {
+	bounds := e.Plane.Bounds()
+	offset := e.Generation % 2
+	changes := []CellUpdate{}
+	for x := bounds.Corner1.X + offset; x < bounds.Corner2.X; x += 2 {
+		for y := bounds.Corner1.Y + offset; y < bounds.Corner2.Y; y += 2 {
+			positions := BlockPositions(grid.Position{X: x, Y: y})
+			var block Block
+			for i, position := range positions {
+				block[i] = e.Plane.Get(position)
+			}
+			next := e.BlockHandler.UpdateBlock(block, e.random(positions[0]))
+			for i, position := range positions {
+				if next[i] != block[i] {
+					changes = append(changes, CellUpdate{next[i], position})
+				}
+			}
+		}
+	}
+	for _, change := range changes {
+		e.Set(change.Position, change.State)
+	}
+	return len(changes) > 0
+}
+
+// BlockPositions returns the positions of the block with the given top left corner, in the order of a Block.
+func BlockPositions(topLeft grid.Position) [4]grid.Position {
+	return [4]grid.Position{
+		topLeft,
+		{X: topLeft.X + 1, Y: topLeft.Y},
+		{X: topLeft.X, Y: topLeft.Y + 1},
+		{X: topLeft.X + 1, Y: topLeft.Y + 1},
+	}
+}
+
+func (e *Engine) random(position grid.Position) *Random {
+	random := NewRandom(e.Seed, e.Generation, position)
+	if e.SubStep > 0 {
+		// sub-steps must not repeat the numbers of the first pass
+		random.state ^= uint64(e.SubStep)
+		random.Uint64()
+	}
+	return random
 }
 
 func (e *Engine) Set(position grid.Position, cell grid.Cell) {
 	if !e.Plane.Bounds().Contains(position) {
 		return
 	}
+	if e.stats != nil {
+		e.record(position, e.Plane.Get(position), cell)
+	}
+	e.updates++
 	e.Plane.Set(position, cell)
 	e.UI.Set(position, cell)
 }
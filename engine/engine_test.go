@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"testing"
+)
+
+// counting adds one to every cell it updates.
+type counting struct{}
+
+func (h counting) UpdateCell(plane grid.Plane, position grid.Position, random *Random) []CellUpdate {
+	return []CellUpdate{{plane.Get(position).(countCell) + 1, position}}
+}
+
+func TestStepUpdatesEveryCell(t *testing.T) {
+	for _, inPlace := range []bool{false, true} {
+		board := grid.NewBasicBoard(3, 2)
+		board.Initialize(countCell(0))
+		e := &Engine{Plane: board, UI: io.NullRenderer{}, Handler: counting{}, InPlace: inPlace}
+		e.Step()
+		// the bounds are inclusive, so the last column and row are updated too
+		for x := 0; x < 3; x++ {
+			for y := 0; y < 2; y++ {
+				if cell := board.Get(grid.Position{X: x, Y: y}); cell != countCell(1) {
+					t.Errorf("Expected the cell at %d, %d to be updated once with InPlace %t but found %v", x, y, inPlace,
+						cell)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"github.com/jpbetz/cellularautomata/monitor"
+	"sync/atomic"
+	"time"
+)
+
+// metrics of all engines of the process, for the debug server
+var (
+	generationsTotal = monitor.NewCounter("cellular_generations_total", "Generations computed.")
+	ticksPerSecond   = monitor.NewRate("cellular_ticks_per_second",
+		"Generations computed per second over the last 10 seconds.", 10*time.Second)
+	updateSeconds = monitor.NewSeconds("cellular_update_seconds_total",
+		"Time spent computing generations in the update handlers, without drawing them.")
+	cellUpdatesTotal = monitor.NewCounter("cellular_cell_updates_total", "Cells written by generations.")
+	lastCellUpdates  int64
+)
+
+func init() {
+	monitor.NewGauge("cellular_cell_updates_per_tick", "Cells written by the last generation.", func() float64 {
+		return float64(atomic.LoadInt64(&lastCellUpdates))
+	})
+}
+
+// monitorStep adds a generation that took the duration to compute to the metrics of the debug server.
+func (e *Engine) monitorStep(duration time.Duration) {
+	generationsTotal.Inc()
+	ticksPerSecond.Mark()
+	updateSeconds.Add(duration)
+	cellUpdatesTotal.Add(int64(e.updates))
+	atomic.StoreInt64(&lastCellUpdates, int64(e.updates))
+}
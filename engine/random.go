@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"math/rand"
+	"time"
+)
+
+// Random is a small, fast random number generator. The engine derives one for every cell in every generation from
+// its seed, so a stochastic rule gives the same results for the same seed no matter in what order, or in parallel,
+// the cells are updated. Random implements rand.Source64 for use with rand.New.
+type Random struct {
+	state uint64
+}
+
+// NewRandom derives a generator from a seed, a generation and a position.
+func NewRandom(seed int64, generation int, position grid.Position) *Random {
+	r := &Random{uint64(seed)}
+	for _, v := range []int{generation, position.X, position.Y} {
+		r.state ^= uint64(v)
+		r.state = r.Uint64()
+	}
+	return r
+}
+
+// Uint64 is the splitmix64 generator, which passes statistical tests despite having only 64 bits of state.
+func (r *Random) Uint64() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (r *Random) Int63() int64 {
+	return int64(r.Uint64() >> 1)
+}
+
+func (r *Random) Seed(seed int64) {
+	r.state = uint64(seed)
+}
+
+// Float64 returns a number in [0, 1).
+func (r *Random) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
+
+// Intn returns a number in [0, n).
+func (r *Random) Intn(n int) int {
+	if n <= 0 {
+		panic("invalid argument to Intn")
+	}
+	return int(r.Uint64() % uint64(n))
+}
+
+// ChooseSeed returns the seed, or a seed from the clock if it is 0. Commands log the seed they run with so that any
+// run can be repeated by passing it back in.
+func ChooseSeed(seed int64) int64 {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return seed
+}
+
+// InitialRandom returns a generator for setting up the plane before the first generation, e.g. to seed a random soup.
+func (e *Engine) InitialRandom() *rand.Rand {
+	return rand.New(NewRandom(e.Seed, -1, grid.Origin))
+}
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"testing"
+)
+
+func TestRandomIsReproducible(t *testing.T) {
+	first := NewRandom(42, 7, grid.Position{3, 4})
+	second := NewRandom(42, 7, grid.Position{3, 4})
+	for i := 0; i < 10; i++ {
+		if a, b := first.Uint64(), second.Uint64(); a != b {
+			t.Fatalf("Expected the same seed, generation and position to give the same numbers but found %d and %d", a, b)
+		}
+	}
+
+	// neighboring cells, generations and seeds must not share their numbers
+	seen := make(map[uint64]bool)
+	for _, r := range []*Random{
+		NewRandom(42, 7, grid.Position{3, 4}),
+		NewRandom(42, 7, grid.Position{4, 3}),
+		NewRandom(42, 8, grid.Position{3, 4}),
+		NewRandom(43, 7, grid.Position{3, 4}),
+	} {
+		v := r.Uint64()
+		if seen[v] {
+			t.Errorf("Expected different numbers but found %d twice", v)
+		}
+		seen[v] = true
+	}
+
+	for i := 0; i < 1000; i++ {
+		if f := first.Float64(); f < 0 || f >= 1 {
+			t.Fatalf("Expected Float64 to be in [0, 1) but found %f", f)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"time"
+)
+
+// Stats describe a generation, for graphing runs over time.
+type Stats struct {
+	Generation int
+	// Updates is the number of cells written in the generation
+	Updates int
+	// Population, Births and Deaths count live cells, if the handler is a LivenessHandler, and are 0 otherwise
+	Population int
+	Births     int
+	Deaths     int
+	// Active is the bounding box of the cells written in the generation, and ActiveArea its area, 0 if none were
+	Active     grid.Rectangle
+	ActiveArea int
+	// Duration is how long the generation took to compute, without drawing it
+	Duration time.Duration
+	// Metrics are the measurements of a MetricsHandler, by name
+	Metrics map[string]float64
+}
+
+// Observer is called after every generation with its stats. Observers are called on the goroutine of the clock.
+type Observer interface {
+	ObserveGeneration(stats Stats)
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc func(stats Stats)
+
+func (f ObserverFunc) ObserveGeneration(stats Stats) {
+	f(stats)
+}
+
+// LivenessHandler may be implemented by an UpdateHandler whose cells are alive or dead, to count the population,
+// births and deaths of every generation.
+type LivenessHandler interface {
+	IsAlive(cell grid.Cell) bool
+}
+
+// MetricsHandler may be implemented by an UpdateHandler to add its own measurements to the stats of a generation,
+// e.g. the number of routes planned. It is asked once per generation, after EndGeneration.
+type MetricsHandler interface {
+	GenerationMetrics() map[string]float64
+}
+
+// record adds a write of a cell to the stats of the generation. The previous cell is nil if it is not known, such as
+// for planes stepped by a PlaneHandler, and is then taken to be the opposite of the new one.
+func (e *Engine) record(position grid.Position, previous, cell grid.Cell) {
+	s := e.stats
+	if s.Updates == 0 {
+		s.Active = grid.Rectangle{Corner1: position, Corner2: position}
+	} else {
+		if position.X < s.Active.Corner1.X {
+			s.Active.Corner1.X = position.X
+		}
+		if position.Y < s.Active.Corner1.Y {
+			s.Active.Corner1.Y = position.Y
+		}
+		if position.X > s.Active.Corner2.X {
+			s.Active.Corner2.X = position.X
+		}
+		if position.Y > s.Active.Corner2.Y {
+			s.Active.Corner2.Y = position.Y
+		}
+	}
+	s.Updates++
+
+	if liveness, ok := e.Handler.(LivenessHandler); ok {
+		alive := liveness.IsAlive(cell)
+		wasAlive := !alive
+		if previous != nil {
+			wasAlive = liveness.IsAlive(previous)
+		}
+		if alive && !wasAlive {
+			s.Births++
+		} else if !alive && wasAlive {
+			s.Deaths++
+		}
+	}
+}
+
+// observe completes the stats of the generation and passes them to the observers.
+func (e *Engine) observe(start time.Time) {
+	s := e.stats
+	e.stats = nil
+	s.Generation = e.Generation
+	if s.Updates > 0 {
+		s.ActiveArea = (s.Active.Corner2.X - s.Active.Corner1.X + 1) * (s.Active.Corner2.Y - s.Active.Corner1.Y + 1)
+	}
+	if liveness, ok := e.Handler.(LivenessHandler); ok {
+		bounds := e.Plane.Bounds()
+		for x := bounds.Corner1.X; x <= bounds.Corner2.X; x++ {
+			for y := bounds.Corner1.Y; y <= bounds.Corner2.Y; y++ {
+				if liveness.IsAlive(e.Plane.Get(grid.Position{X: x, Y: y})) {
+					s.Population++
+				}
+			}
+		}
+	}
+	if handler, ok := e.Handler.(MetricsHandler); ok {
+		s.Metrics = handler.GenerationMetrics()
+	}
+	s.Duration = time.Since(start)
+	for _, observer := range e.Observers {
+		observer.ObserveGeneration(*s)
+	}
+}
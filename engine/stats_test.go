@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"testing"
+)
+
+// flipping flips the cells of the middle column between 0 and 1, counting 1 as alive.
+type flipping struct{}
+
+func (h flipping) UpdateCell(plane grid.Plane, position grid.Position, random *Random) []CellUpdate {
+	if position.X != 1 {
+		return []CellUpdate{}
+	}
+	return []CellUpdate{{1 - plane.Get(position).(countCell), position}}
+}
+
+func (h flipping) IsAlive(cell grid.Cell) bool {
+	return cell.(countCell) == 1
+}
+
+func (h flipping) GenerationMetrics() map[string]float64 {
+	return map[string]float64{"flips": 2}
+}
+
+func TestObservers(t *testing.T) {
+	board := grid.NewBasicBoard(3, 2)
+	board.Initialize(countCell(0))
+	board.Set(grid.Position{X: 1, Y: 0}, countCell(1))
+	board.Set(grid.Position{X: 2, Y: 1}, countCell(1))
+
+	observed := []Stats{}
+	e := &Engine{Plane: board, UI: io.NullRenderer{}, Handler: flipping{}}
+	e.Observers = append(e.Observers, ObserverFunc(func(stats Stats) {
+		observed = append(observed, stats)
+	}))
+	e.Step()
+	e.Step()
+
+	if len(observed) != 2 {
+		t.Fatalf("Expected 2 generations to be observed but was %d", len(observed))
+	}
+	stats := observed[1]
+	if stats.Generation != 2 || stats.Updates != 2 || stats.Population != 2 || stats.Births != 1 || stats.Deaths != 1 {
+		t.Errorf("Expected generation 2 with 2 updates, 1 birth, 1 death and a population of 2 but was %+v", stats)
+	}
+	active := grid.Rectangle{Corner1: grid.Position{X: 1, Y: 0}, Corner2: grid.Position{X: 1, Y: 1}}
+	if stats.Active != active || stats.ActiveArea != 2 {
+		t.Errorf("Expected the active region to be the middle column but was %v of area %d", stats.Active,
+			stats.ActiveArea)
+	}
+	if stats.Metrics["flips"] != 2 {
+		t.Errorf("Expected the metrics of the handler but was %v", stats.Metrics)
+	}
+}
+
+func TestStatsOfPlaneHandler(t *testing.T) {
+	board := grid.NewBasicBoard(3, 2)
+	board.Initialize(countCell(0))
+	observed := []Stats{}
+	e := &Engine{Plane: board, UI: io.NullRenderer{}, Handler: flipping{}, PlaneHandler: planeFlipping{}}
+	e.Observers = append(e.Observers, ObserverFunc(func(stats Stats) {
+		observed = append(observed, stats)
+	}))
+	e.Step()
+	if stats := observed[0]; stats.Updates != 2 || stats.Births != 2 || stats.Deaths != 0 || stats.Population != 2 {
+		t.Errorf("Expected the 2 changes of the plane handler to be births but was %+v", stats)
+	}
+}
+
+// planeFlipping flips the middle column of the plane all at once.
+type planeFlipping struct{}
+
+func (h planeFlipping) StepPlane(plane grid.Plane) []grid.Position {
+	changes := []grid.Position{{X: 1, Y: 0}, {X: 1, Y: 1}}
+	for _, p := range changes {
+		plane.Set(p, 1-plane.Get(p).(countCell))
+	}
+	return changes
+}
@@ -0,0 +1,132 @@
+package golly
+
+import (
+	"github.com/jpbetz/cellularautomata/engine"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/nsf/termbox-go"
+	"strconv"
+)
+
+// Cell is a cell of a rule table, holding a small integer state.
+type Cell struct {
+	State int
+	Table *RuleTable
+}
+
+var attributes = []termbox.Attribute{
+	termbox.ColorDefault,
+	termbox.ColorRed,
+	termbox.ColorYellow,
+	termbox.ColorBlue,
+	termbox.ColorGreen,
+	termbox.ColorMagenta,
+	termbox.ColorCyan,
+	termbox.ColorWhite,
+}
+
+func (c Cell) Rune() rune {
+	if c.State == 0 {
+		return ' '
+	}
+	return '█'
+}
+
+func (c Cell) FgAttribute() termbox.Attribute {
+	if c.State == 0 {
+		return termbox.ColorDefault
+	}
+	return attributes[1+(c.State-1)%(len(attributes)-1)]
+}
+
+func (c Cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+var defaultStartColor = grid.Color{R: 0xff, G: 0x33, B: 0x58}
+var defaultEndColor = grid.Color{R: 0xff, G: 0xf9, B: 0x33}
+
+// Color uses the @COLORS section of the rule, or a gradient for states it does not list.
+func (c Cell) Color() grid.Color {
+	if color, ok := c.Table.Colors[c.State]; ok {
+		return grid.Color{R: color.R, G: color.G, B: color.B}
+	}
+	if c.State == 0 {
+		return grid.Color{R: 0x0e, G: 0x0e, B: 0x0e}
+	}
+	if c.Table.States <= 2 {
+		return defaultEndColor
+	}
+	return grid.Gradient(defaultStartColor, defaultEndColor, float64(c.State-1)/float64(c.Table.States-2))
+}
+
+// Handler is an engine.UpdateHandler that applies a rule table to a plane of Cells. Cells beyond the bounds of the
+// plane are treated as state 0. Hexagonal rules can run on a grid.HexBoard, or skewed on a square plane as in Golly.
+type Handler struct {
+	Table *RuleTable
+	cache map[string]int
+}
+
+func NewHandler(table *RuleTable) *Handler {
+	return &Handler{Table: table, cache: make(map[string]int)}
+}
+
+func (h *Handler) UpdateCell(plane grid.Plane, position grid.Position, random *engine.Random) []engine.CellUpdate {
+	bounds := plane.Bounds()
+	if !bounds.Contains(position) {
+		return []engine.CellUpdate{}
+	}
+	state := h.state(plane, position)
+	hex := grid.IsHex(plane)
+	axial := grid.OffsetToAxial(position)
+	offsets := h.Table.Neighborhood.Offsets
+	neighbors := make([]int, len(offsets))
+	key := make([]byte, len(offsets)+1)
+	key[0] = byte(state)
+	for i, offset := range offsets {
+		p := grid.Position{position.X + offset.X, position.Y + offset.Y}
+		if hex {
+			p = grid.AxialToOffset(grid.Position{axial.X + offset.X, axial.Y + offset.Y})
+		}
+		if bounds.Contains(p) {
+			neighbors[i] = h.state(plane, p)
+		}
+		key[i+1] = byte(neighbors[i])
+	}
+
+	// the same neighborhoods come up over and over, so remember their results instead of matching the table again
+	next, ok := h.cache[string(key)]
+	if !ok {
+		next = h.Table.Next(state, neighbors)
+		h.cache[string(key)] = next
+	}
+	if next == state {
+		return []engine.CellUpdate{}
+	}
+	return []engine.CellUpdate{{Cell{State: next, Table: h.Table}, position}}
+}
+
+// States names the states of the table by their number for the control API, since rule tables don't name them.
+func (h *Handler) States() map[string]grid.Cell {
+	states := map[string]grid.Cell{}
+	for i, cell := range h.PatternStates() {
+		states[strconv.Itoa(i)] = cell
+	}
+	return states
+}
+
+// PatternStates numbers the states as the table does, so that patterns are those of Golly.
+func (h *Handler) PatternStates() []grid.Cell {
+	states := make([]grid.Cell, h.Table.States)
+	for i := range states {
+		states[i] = Cell{State: i, Table: h.Table}
+	}
+	return states
+}
+
+func (h *Handler) state(plane grid.Plane, position grid.Position) int {
+	cell, ok := plane.Get(position).(Cell)
+	if !ok {
+		panic("Expected rule table cell")
+	}
+	return cell.State
+}
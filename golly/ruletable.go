@@ -0,0 +1,431 @@
+package golly
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/grid"
+	goio "io"
+	"strconv"
+	"strings"
+)
+
+// RuleTable is a rule loaded from the @TABLE section of a Golly .rule file. See
+// http://golly.sourceforge.net/Help/formats.html#table for the format.
+type RuleTable struct {
+	Name         string
+	States       int
+	Neighborhood grid.Neighborhood
+	Symmetries   string
+	Colors       map[int]Color
+
+	transitions []transition
+	// symmetric orderings of the neighbors to try each transition with, unused for permute
+	orderings [][]int
+}
+
+type Color struct {
+	R, G, B uint8
+}
+
+// The neighborhoods of rule tables, with their offsets in the order transitions list the neighbors.
+var Moore = grid.Custom("Moore", grid.Position{0, -1}, grid.Position{1, -1}, grid.Position{1, 0}, grid.Position{1, 1},
+	grid.Position{0, 1}, grid.Position{-1, 1}, grid.Position{-1, 0}, grid.Position{-1, -1})
+var VonNeumann = grid.Custom("vonNeumann", grid.Position{0, -1}, grid.Position{1, 0}, grid.Position{0, 1},
+	grid.Position{-1, 0})
+
+// Hexagonal is emulated on a square grid by skewing it, so the NE and SW neighbors are dropped. See grid.Hexagonal.
+var Hexagonal = grid.Custom("hexagonal", grid.Position{0, -1}, grid.Position{1, 0}, grid.Position{1, 1},
+	grid.Position{0, 1}, grid.Position{-1, 0}, grid.Position{-1, -1})
+var OneDimensional = grid.Custom("oneDimensional", grid.Position{-1, 0}, grid.Position{1, 0})
+
+var neighborhoods = []grid.Neighborhood{Moore, VonNeumann, Hexagonal, OneDimensional}
+
+// symmetry describes a symmetry as the rotations, in steps around the neighbors, that it allows and whether each
+// rotation may also be reflected.
+type symmetry struct {
+	rotations []int
+	reflect   bool
+}
+
+var symmetries = map[string]map[string]symmetry{
+	"Moore": {
+		"none":               {[]int{0}, false},
+		"rotate4":            {[]int{0, 2, 4, 6}, false},
+		"rotate8":            {[]int{0, 1, 2, 3, 4, 5, 6, 7}, false},
+		"rotate4reflect":     {[]int{0, 2, 4, 6}, true},
+		"rotate8reflect":     {[]int{0, 1, 2, 3, 4, 5, 6, 7}, true},
+		"reflect_horizontal": {[]int{0}, true},
+	},
+	"vonNeumann": {
+		"none":               {[]int{0}, false},
+		"rotate4":            {[]int{0, 1, 2, 3}, false},
+		"rotate4reflect":     {[]int{0, 1, 2, 3}, true},
+		"reflect_horizontal": {[]int{0}, true},
+	},
+	"hexagonal": {
+		"none":           {[]int{0}, false},
+		"rotate2":        {[]int{0, 3}, false},
+		"rotate3":        {[]int{0, 2, 4}, false},
+		"rotate6":        {[]int{0, 1, 2, 3, 4, 5}, false},
+		"rotate6reflect": {[]int{0, 1, 2, 3, 4, 5}, true},
+	},
+	"oneDimensional": {
+		"none":    {[]int{0}, false},
+		"reflect": {[]int{0}, true},
+	},
+}
+
+// transition matches a cell and its neighbors. Each entry is the set of states allowed at that position. Entries that
+// came from the same variable are bound, meaning they must all hold the same state.
+type transition struct {
+	inputs  [][]bool
+	binding []int
+	// output is either a state, or when outputBinding is not -1 the state bound to that variable
+	output        int
+	outputBinding int
+}
+
+// ParseRuleTable reads a Golly .rule file. Only the @RULE, @TABLE and @COLORS sections are used.
+func ParseRuleTable(r goio.Reader) (*RuleTable, error) {
+	table := &RuleTable{Colors: make(map[int]Color)}
+	scanner := bufio.NewScanner(r)
+	section := ""
+	variables := make(map[string][]int)
+	foundTable := false
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "@") {
+			fields := strings.Fields(line)
+			section = fields[0]
+			switch section {
+			case "@RULE":
+				if len(fields) > 1 {
+					table.Name = fields[1]
+				}
+			case "@TABLE":
+				foundTable = true
+			case "@TREE":
+				return nil, fmt.Errorf("line %d: @TREE rules are not supported, only @TABLE", lineNumber)
+			}
+			continue
+		}
+
+		var err error
+		switch section {
+		case "@TABLE":
+			err = table.parseTableLine(line, variables)
+		case "@COLORS":
+			err = table.parseColorLine(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !foundTable {
+		return nil, fmt.Errorf("no @TABLE section found")
+	}
+	if table.States == 0 || table.Neighborhood.Name == "" {
+		return nil, fmt.Errorf("@TABLE must declare n_states and neighborhood")
+	}
+	return table, nil
+}
+
+func (t *RuleTable) parseTableLine(line string, variables map[string][]int) error {
+	if i := strings.Index(line, ":"); i >= 0 {
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		switch key {
+		case "n_states":
+			states, err := strconv.Atoi(value)
+			if err != nil || states < 2 || states > 256 {
+				return fmt.Errorf("n_states must be between 2 and 256 but was %q", value)
+			}
+			t.States = states
+		case "neighborhood":
+			for _, n := range neighborhoods {
+				if n.Name == value {
+					t.Neighborhood = n
+				}
+			}
+			if t.Neighborhood.Name != value {
+				return fmt.Errorf("unsupported neighborhood %q", value)
+			}
+		case "symmetries":
+			t.Symmetries = value
+		default:
+			return fmt.Errorf("unknown table setting %q", key)
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(line, "var ") {
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return fmt.Errorf("variable declaration must have the form var name={...}")
+		}
+		name := strings.TrimSpace(line[len("var "):i])
+		states, err := t.parseSet(strings.TrimSpace(line[i+1:]), variables)
+		if err != nil {
+			return err
+		}
+		variables[name] = states
+		return nil
+	}
+
+	return t.parseTransition(line, variables)
+}
+
+// parseSet parses a state, a variable name, or a {...} list of either.
+func (t *RuleTable) parseSet(entry string, variables map[string][]int) ([]int, error) {
+	if strings.HasPrefix(entry, "{") {
+		if !strings.HasSuffix(entry, "}") {
+			return nil, fmt.Errorf("unterminated list %q", entry)
+		}
+		states := make([]int, 0)
+		for _, item := range splitEntries(entry[1 : len(entry)-1]) {
+			itemStates, err := t.parseSet(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			states = append(states, itemStates...)
+		}
+		return states, nil
+	}
+	if states, ok := variables[entry]; ok {
+		return states, nil
+	}
+	state, err := strconv.Atoi(entry)
+	if err != nil {
+		return nil, fmt.Errorf("unknown variable %q", entry)
+	}
+	if state < 0 || state >= t.States {
+		return nil, fmt.Errorf("state %d is not between 0 and %d", state, t.States-1)
+	}
+	return []int{state}, nil
+}
+
+// splitEntries splits on commas and whitespace outside of {...} lists.
+func splitEntries(s string) []string {
+	entries := make([]string, 0)
+	depth := 0
+	current := ""
+	for _, c := range s {
+		switch {
+		case c == '{':
+			depth++
+			current += string(c)
+		case c == '}':
+			depth--
+			current += string(c)
+		case (c == ',' || c == ' ' || c == '\t') && depth == 0:
+			if current != "" {
+				entries = append(entries, current)
+				current = ""
+			}
+		default:
+			current += string(c)
+		}
+	}
+	if current != "" {
+		entries = append(entries, current)
+	}
+	return entries
+}
+
+func (t *RuleTable) parseTransition(line string, variables map[string][]int) error {
+	if t.States == 0 || t.Neighborhood.Name == "" {
+		return fmt.Errorf("n_states and neighborhood must be declared before transitions")
+	}
+	if t.orderings == nil && t.Symmetries != "permute" {
+		if err := t.buildOrderings(); err != nil {
+			return err
+		}
+	}
+
+	entries := splitEntries(line)
+	size := len(t.Neighborhood.Offsets) + 2
+	if len(entries) == 1 && len(line) == size {
+		// all single digit states may be written without separators
+		entries = strings.Split(line, "")
+	}
+	if len(entries) != size {
+		return fmt.Errorf("transition must have %d entries but had %d", size, len(entries))
+	}
+
+	tr := transition{
+		inputs:        make([][]bool, size-1),
+		binding:       make([]int, size-1),
+		outputBinding: -1,
+	}
+	bindings := make(map[string]int)
+	for i, entry := range entries[:size-1] {
+		states, err := t.parseSet(entry, variables)
+		if err != nil {
+			return err
+		}
+		tr.inputs[i] = make([]bool, t.States)
+		for _, state := range states {
+			tr.inputs[i][state] = true
+		}
+		tr.binding[i] = -1
+		if _, ok := variables[entry]; ok {
+			if first, ok := bindings[entry]; ok {
+				tr.binding[i] = first
+			} else {
+				bindings[entry] = i
+				tr.binding[i] = i
+			}
+		}
+	}
+
+	output := entries[size-1]
+	if first, ok := bindings[output]; ok {
+		tr.outputBinding = first
+	} else {
+		states, err := t.parseSet(output, variables)
+		if err != nil {
+			return err
+		}
+		if len(states) != 1 {
+			return fmt.Errorf("output %q must be a single state or a variable bound in the inputs", output)
+		}
+		tr.output = states[0]
+	}
+	t.transitions = append(t.transitions, tr)
+	return nil
+}
+
+func (t *RuleTable) buildOrderings() error {
+	if t.Symmetries == "" {
+		t.Symmetries = "none"
+	}
+	s, ok := symmetries[t.Neighborhood.Name][t.Symmetries]
+	if !ok {
+		return fmt.Errorf("unsupported symmetries %q for neighborhood %s", t.Symmetries, t.Neighborhood.Name)
+	}
+	n := len(t.Neighborhood.Offsets)
+	for _, rotation := range s.rotations {
+		rotated := make([]int, n)
+		for i := range rotated {
+			rotated[i] = (i + rotation) % n
+		}
+		t.orderings = append(t.orderings, rotated)
+		if s.reflect {
+			reflected := make([]int, n)
+			for i := range reflected {
+				if t.Neighborhood.Name == OneDimensional.Name {
+					// the neighbors of a line are not a ring, so reflecting reverses them end to end
+					reflected[i] = (n - 1 - i + rotation) % n
+				} else {
+					reflected[i] = (n - i + rotation) % n
+				}
+			}
+			t.orderings = append(t.orderings, reflected)
+		}
+	}
+	return nil
+}
+
+func (t *RuleTable) parseColorLine(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) != 4 {
+		// gradient lines are not supported, the default colors are used instead
+		return nil
+	}
+	values := make([]int, 4)
+	for i, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return fmt.Errorf("invalid color %q", line)
+		}
+		values[i] = value
+	}
+	t.Colors[values[0]] = Color{uint8(values[1]), uint8(values[2]), uint8(values[3])}
+	return nil
+}
+
+// Next returns the next state of a cell given its state and the states of its neighbors, in the order of the
+// neighborhood offsets. The first matching transition wins and cells that match no transition keep their state.
+func (t *RuleTable) Next(state int, neighbors []int) int {
+	for _, tr := range t.transitions {
+		if !tr.inputs[0][state] {
+			continue
+		}
+		if t.Symmetries == "permute" {
+			bound := make([]int, len(tr.inputs))
+			bound[0] = state
+			if tr.matchPermuted(neighbors, make([]bool, len(neighbors)), bound, 1) {
+				return tr.result(bound)
+			}
+			continue
+		}
+		for _, ordering := range t.orderings {
+			if bound, ok := tr.match(state, neighbors, ordering); ok {
+				return tr.result(bound)
+			}
+		}
+	}
+	return state
+}
+
+func (tr transition) result(bound []int) int {
+	if tr.outputBinding >= 0 {
+		return bound[tr.outputBinding]
+	}
+	return tr.output
+}
+
+// match checks the transition against the neighbors with transition entry i+1 matched to neighbor ordering[i].
+func (tr transition) match(state int, neighbors []int, ordering []int) ([]int, bool) {
+	bound := make([]int, len(tr.inputs))
+	bound[0] = state
+	for i, neighbor := range ordering {
+		value := neighbors[neighbor]
+		if !tr.accepts(i+1, value, bound) {
+			return nil, false
+		}
+		bound[i+1] = value
+	}
+	return bound, true
+}
+
+func (tr transition) accepts(entry, value int, bound []int) bool {
+	if !tr.inputs[entry][value] {
+		return false
+	}
+	if b := tr.binding[entry]; b >= 0 && b != entry && bound[b] != value {
+		return false
+	}
+	return true
+}
+
+// matchPermuted assigns the neighbors to transition entries in any order, backtracking when an assignment fails.
+func (tr transition) matchPermuted(neighbors []int, used []bool, bound []int, entry int) bool {
+	if entry == len(tr.inputs) {
+		return true
+	}
+	for i, value := range neighbors {
+		if used[i] || !tr.accepts(entry, value, bound) {
+			continue
+		}
+		used[i] = true
+		bound[entry] = value
+		if tr.matchPermuted(neighbors, used, bound, entry+1) {
+			return true
+		}
+		used[i] = false
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package golly
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWireWorldTable(t *testing.T) {
+	f, err := os.Open("../rules/WireWorld.rule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	table, err := ParseRuleTable(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Name != "WireWorld" || table.States != 4 || table.Neighborhood.Name != "Moore" {
+		t.Errorf("Unexpected table: %#v", table)
+	}
+
+	// neighbors are listed N, NE, E, SE, S, SW, W, NW
+	cases := []struct {
+		state     int
+		neighbors []int
+		next      int
+	}{
+		{1, []int{0, 0, 3, 0, 0, 0, 2, 0}, 2},
+		{2, []int{0, 0, 1, 0, 0, 0, 3, 0}, 3},
+		{3, []int{0, 0, 3, 0, 0, 0, 1, 0}, 1},
+		{3, []int{1, 0, 1, 0, 0, 0, 0, 0}, 1},
+		{3, []int{1, 0, 1, 0, 1, 0, 0, 0}, 3},
+		{3, []int{0, 0, 3, 0, 0, 0, 2, 0}, 3},
+		{0, []int{1, 1, 0, 0, 0, 0, 0, 0}, 0},
+	}
+	for _, c := range cases {
+		if next := table.Next(c.state, c.neighbors); next != c.next {
+			t.Errorf("Expected state %d with neighbors %v to become %d but found %d", c.state, c.neighbors, c.next, next)
+		}
+	}
+	if table.Colors[1] != (Color{51, 63, 255}) {
+		t.Errorf("Unexpected color for state 1: %v", table.Colors[1])
+	}
+}
+
+func TestSymmetriesAndBoundVariables(t *testing.T) {
+	table, err := ParseRuleTable(strings.NewReader(`
+@RULE Test
+@TABLE
+n_states:3
+neighborhood:vonNeumann
+symmetries:rotate4
+var a={1,2}
+var b={0,1,2}
+# a cell whose north and east neighbors hold the same non-zero state takes that state
+0,a,a,b,0,a
+012000`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// neighbors are listed N, E, S, W
+	cases := []struct {
+		state     int
+		neighbors []int
+		next      int
+	}{
+		{0, []int{2, 2, 1, 0}, 2},
+		{0, []int{0, 1, 1, 2}, 1},
+		{0, []int{2, 1, 1, 0}, 0},
+		{0, []int{1, 0, 1, 0}, 0},
+		{0, []int{1, 2, 0, 0}, 0},
+		{0, []int{0, 0, 1, 2}, 0},
+	}
+	for _, c := range cases {
+		if next := table.Next(c.state, c.neighbors); next != c.next {
+			t.Errorf("Expected state %d with neighbors %v to become %d but found %d", c.state, c.neighbors, c.next, next)
+		}
+	}
+}
+
+func TestOneDimensionalReflect(t *testing.T) {
+	table, err := ParseRuleTable(strings.NewReader(`
+@RULE Test
+@TABLE
+n_states:2
+neighborhood:oneDimensional
+symmetries:reflect
+# a cell whose left neighbor is alive comes alive
+0,1,0,1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// neighbors are listed left, right
+	cases := []struct {
+		neighbors []int
+		next      int
+	}{
+		{[]int{1, 0}, 1},
+		{[]int{0, 1}, 1},
+		{[]int{0, 0}, 0},
+	}
+	for _, c := range cases {
+		if next := table.Next(0, c.neighbors); next != c.next {
+			t.Errorf("Expected a dead cell with neighbors %v to become %d but found %d", c.neighbors, c.next, next)
+		}
+	}
+}
+
+func TestInvalidTables(t *testing.T) {
+	invalid := []string{
+		"@TABLE\nn_states:2\nneighborhood:Moore\n0,1,1,1,0,0,0,0,0",
+		"@TABLE\nn_states:2\nneighborhood:Moore\n0,1,1,1,0,0,0,0,0,2",
+		"@TABLE\nn_states:2\nneighborhood:Triangular\n",
+		"@TABLE\nn_states:2\nneighborhood:Moore\nsymmetries:rotate6\n0,1,1,1,0,0,0,0,0,1",
+		"@RULE NoTable\n",
+	}
+	for _, rule := range invalid {
+		if _, err := ParseRuleTable(strings.NewReader(rule)); err == nil {
+			t.Errorf("Expected rule to be rejected:\n%s", rule)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package grid
 
 import (
 	"container/heap"
+	"github.com/jpbetz/cellularautomata/monitor"
 	"math"
 )
 
@@ -60,7 +61,21 @@ func (pq *priorityQueue) Pop() interface{} {
 
 type HeuristicCostEstimateFunc func(p1, p2 Node) float64
 
+// pathExpansions counts the nodes expanded by every search, for the debug server.
+var pathExpansions = monitor.NewCounter("cellular_findpath_expansions_total",
+	"Nodes expanded by grid.FindPath and grid.FindCooperativePath.")
+
 func FindPath(start, goal Node, estimateCost HeuristicCostEstimateFunc) (path *Path, ok bool) {
+	isGoal := func(n Node) bool {
+		return n == goal
+	}
+	estimateToGoal := func(n Node) float64 {
+		return estimateCost(n, goal)
+	}
+	return findPath(start, isGoal, estimateToGoal)
+}
+
+func findPath(start Node, isGoal func(Node) bool, estimateToGoal func(Node) float64) (path *Path, ok bool) {
 	// https://en.wikipedia.org/wiki/A*_search_algorithm
 
 	closedSet := make(map[NodeId]bool)
@@ -69,7 +84,7 @@ func FindPath(start, goal Node, estimateCost HeuristicCostEstimateFunc) (path *P
 	heap.Init(&openQueue)
 	startCandidate := &priorityQueueNode{
 		node:               start,
-		toGoalScoreViaCell: estimateCost(start, goal),
+		toGoalScoreViaCell: estimateToGoal(start),
 		fromStartScore:     0,
 	}
 	heap.Push(&openQueue, startCandidate)
@@ -78,7 +93,8 @@ func FindPath(start, goal Node, estimateCost HeuristicCostEstimateFunc) (path *P
 
 	for openQueue.Len() > 0 {
 		current := heap.Pop(&openQueue).(*priorityQueueNode)
-		if current.node == goal {
+		pathExpansions.Inc()
+		if isGoal(current.node) {
 			return buildPath(cameFrom, current.node), true
 		}
 		delete(openSet, current.node.Id())
@@ -103,7 +119,7 @@ func FindPath(start, goal Node, estimateCost HeuristicCostEstimateFunc) (path *P
 				}
 				cameFrom[neighborNode.Id()] = current.node
 				neighborCandidate.fromStartScore = tentativeFromStartScore
-				neighborCandidate.toGoalScoreViaCell = tentativeFromStartScore + estimateCost(neighborNode, goal)
+				neighborCandidate.toGoalScoreViaCell = tentativeFromStartScore + estimateToGoal(neighborNode)
 				heap.Fix(&openQueue, neighborCandidate.index)
 			}
 		}
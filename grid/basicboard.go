@@ -33,23 +33,13 @@ func (b *BasicBoard) Get(p Position) Cell {
 	return b.Cells[p.Y*b.W+p.X]
 }
 
-func (b *BasicBoard) GetNeighborPositions(p Position) []Position {
-	neighbors := make([]Position, 0, 8)
-	bounds := b.Bounds()
-	x, y := p.X, p.Y
-	for i := x - 1; i <= x+1; i++ {
-		for j := y - 1; j <= y+1; j++ {
-			if !(i == x && j == y) && i >= 0 && j >= 0 && i <= bounds.Corner2.X && j <= bounds.Corner2.Y {
-				neighbors = append(neighbors, Position{i, j})
-			}
-		}
-	}
-	return neighbors
+func (b *BasicBoard) GetNeighborPositions(p Position, neighborhood Neighborhood) []Position {
+	return neighborhood.Positions(p, b.Bounds())
 }
 
-func (b *BasicBoard) GetNeighbors(p Position) []Cell {
-	neighbors := make([]Cell, 0, 8)
-	for _, neighborPosition := range b.GetNeighborPositions(p) {
+func (b *BasicBoard) GetNeighbors(p Position, neighborhood Neighborhood) []Cell {
+	neighbors := make([]Cell, 0, len(neighborhood.Offsets))
+	for _, neighborPosition := range b.GetNeighborPositions(p, neighborhood) {
 		neighbors = append(neighbors, b.Get(neighborPosition))
 	}
 	return neighbors
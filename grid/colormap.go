@@ -0,0 +1,57 @@
+package grid
+
+import "math"
+
+// Colormap maps values from 0 to 1 onto colors by interpolating evenly spaced color stops. Cells with continuous
+// states can use it to implement ColoredCell.
+type Colormap []Color
+
+var Grayscale = Colormap{{0x0e, 0x0e, 0x0e}, {0xff, 0xff, 0xff}}
+
+// Viridis approximates the perceptually uniform colormap of the same name from matplotlib.
+var Viridis = Colormap{
+	{0x44, 0x01, 0x54},
+	{0x3b, 0x52, 0x8b},
+	{0x21, 0x90, 0x8d},
+	{0x5d, 0xc8, 0x63},
+	{0xfd, 0xe7, 0x25},
+}
+
+// Rainbow runs around the color wheel and back to where it started, for states that cycle.
+var Rainbow = Colormap{
+	{0xff, 0x33, 0x58},
+	{0xff, 0xf9, 0x33},
+	{0x33, 0xff, 0x6b},
+	{0x33, 0xe6, 0xff},
+	{0x33, 0x3f, 0xff},
+	{0xc9, 0x33, 0xff},
+	{0xff, 0x33, 0x58},
+}
+
+// At returns the color for t, which is clamped to between 0 and 1.
+func (c Colormap) At(t float64) Color {
+	if len(c) == 1 || t <= 0 || math.IsNaN(t) {
+		return c[0]
+	}
+	if t >= 1 {
+		return c[len(c)-1]
+	}
+	scaled := t * float64(len(c)-1)
+	i := int(scaled)
+	return Gradient(c[i], c[i+1], scaled-float64(i))
+}
+
+// shades are block characters of increasing density, for renderers limited to characters.
+var shades = []rune{' ', '░', '▒', '▓', '█'}
+
+// ShadeRune returns a block character whose density shows t, from a blank for 0 to a full block for 1.
+func ShadeRune(t float64) rune {
+	i := int(t*float64(len(shades)-1) + 0.5)
+	if i < 0 || math.IsNaN(t) {
+		i = 0
+	}
+	if i >= len(shades) {
+		i = len(shades) - 1
+	}
+	return shades[i]
+}
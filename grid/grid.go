@@ -7,8 +7,9 @@ import (
 
 type Plane interface {
 	Get(position Position) Cell
-	GetNeighborPositions(p Position) []Position
-	GetNeighbors(p Position) []Cell
+	// GetNeighborPositions returns the positions in the neighborhood of p that lie within the bounds of the plane.
+	GetNeighborPositions(p Position, neighborhood Neighborhood) []Position
+	GetNeighbors(p Position, neighborhood Neighborhood) []Cell
 	Set(position Position, cell Cell)
 	Bounds() Rectangle
 }
@@ -19,6 +20,60 @@ type Cell interface {
 	BgAttribute() termbox.Attribute
 }
 
+// ColoredCell may be implemented by cells that need more colors than the termbox attributes offer, e.g. gradients.
+// Renderers that support true color draw Color instead of FgAttribute.
+type ColoredCell interface {
+	Cell
+	Color() Color
+}
+
+type Color struct {
+	R, G, B uint8
+}
+
+// Hex returns the color as 0x00RRGGBB.
+func (c Color) Hex() uint32 {
+	return uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+}
+
+// Gradient returns the color at fraction t, from 0 to 1, of the way from c1 to c2.
+func Gradient(c1, c2 Color, t float64) Color {
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5)
+	}
+	return Color{mix(c1.R, c2.R), mix(c1.G, c2.G), mix(c1.B, c2.B)}
+}
+
+// AttributeColor returns the color renderers draw a termbox attribute in, outside of a terminal.
+func AttributeColor(attribute termbox.Attribute) Color {
+	switch attribute {
+	case termbox.ColorBlue:
+		return Color{0x33, 0x3f, 0xff}
+	case termbox.ColorRed:
+		return Color{0xff, 0x33, 0x58}
+	case termbox.ColorYellow:
+		return Color{0xff, 0xf9, 0x33}
+	case termbox.ColorGreen:
+		return Color{0x33, 0xff, 0x6b}
+	case termbox.ColorCyan:
+		return Color{0x33, 0xe6, 0xff}
+	case termbox.ColorMagenta:
+		return Color{0xc9, 0x33, 0xff}
+	case termbox.ColorWhite:
+		return Color{0xff, 0xff, 0xff}
+	default:
+		return Color{0x0e, 0x0e, 0x0e}
+	}
+}
+
+// CellColor returns the color of a ColoredCell, or of the foreground attribute of any other cell.
+func CellColor(cell Cell) Color {
+	if colored, ok := cell.(ColoredCell); ok {
+		return colored.Color()
+	}
+	return AttributeColor(cell.FgAttribute())
+}
+
 type Position struct {
 	X, Y int
 }
@@ -0,0 +1,100 @@
+package grid
+
+// Hexagonal grids use two coordinate systems. Planes and renderers use offset coordinates, in which rows are stacked
+// like the rows of a square grid with odd rows shifted half a cell to the right, so that hexagonal planes keep
+// rectangular bounds. Neighborhoods and the hex helpers on Position use axial coordinates, in which the six neighbors
+// of a cell are its orthogonal neighbors plus its NW and SE diagonals, matching Hexagonal and the hexagonal rule
+// tables of Golly. Axial coordinates make distances, rotations and directions the same everywhere on the grid.
+
+// HexDirection is one of the six directions from a hexagonal cell to its neighbors, clockwise from east.
+type HexDirection int
+
+const (
+	East HexDirection = iota
+	SouthEast
+	SouthWest
+	West
+	NorthWest
+	NorthEast
+)
+
+// axial offsets of the neighbor in each direction
+var hexDirections = [6]Position{{1, 0}, {1, 1}, {0, 1}, {-1, 0}, {-1, -1}, {0, -1}}
+
+func (d HexDirection) Rotate(sixthTurnsCW int) HexDirection {
+	applied := (int(d) + sixthTurnsCW) % 6
+	if applied < 0 {
+		applied += 6
+	}
+	return HexDirection(applied)
+}
+
+// OffsetToAxial converts offset coordinates to axial coordinates.
+func OffsetToAxial(p Position) Position {
+	return Position{p.X + (p.Y+1)>>1, p.Y}
+}
+
+// AxialToOffset converts axial coordinates to offset coordinates.
+func AxialToOffset(p Position) Position {
+	return Position{p.X - (p.Y+1)>>1, p.Y}
+}
+
+// HexTranslate moves an axial position distance cells in the direction.
+func (p Position) HexTranslate(direction HexDirection, distance int) Position {
+	offset := hexDirections[direction]
+	return Position{p.X + offset.X*distance, p.Y + offset.Y*distance}
+}
+
+// HexRotate rotates an axial position around the center in steps of a sixth of a turn clockwise.
+func (p Position) HexRotate(center Position, sixthTurnsCW int) Position {
+	x, y := p.X-center.X, p.Y-center.Y
+	turns := ((sixthTurnsCW % 6) + 6) % 6
+	for i := 0; i < turns; i++ {
+		// east turns to south east and south west to west
+		x, y = x-y, x
+	}
+	return Position{center.X + x, center.Y + y}
+}
+
+// HexDistanceTo is the number of steps between two axial positions.
+func (p Position) HexDistanceTo(q Position) int {
+	return HexDistance(q.X-p.X, q.Y-p.Y)
+}
+
+// HexBoard is a hexagonal plane stored in offset coordinates. Neighborhoods passed to it are in axial coordinates,
+// e.g. Hexagonal(1) for the six adjacent cells.
+type HexBoard struct {
+	*BasicBoard
+}
+
+func NewHexBoard(w, h int) *HexBoard {
+	return &HexBoard{NewBasicBoard(w, h)}
+}
+
+func (b *HexBoard) GetNeighborPositions(p Position, neighborhood Neighborhood) []Position {
+	bounds := b.Bounds()
+	axial := OffsetToAxial(p)
+	positions := make([]Position, 0, len(neighborhood.Offsets))
+	for _, offset := range neighborhood.Offsets {
+		neighbor := AxialToOffset(Position{axial.X + offset.X, axial.Y + offset.Y})
+		if bounds.Contains(neighbor) {
+			positions = append(positions, neighbor)
+		}
+	}
+	return positions
+}
+
+func (b *HexBoard) GetNeighbors(p Position, neighborhood Neighborhood) []Cell {
+	neighbors := make([]Cell, 0, len(neighborhood.Offsets))
+	for _, neighborPosition := range b.GetNeighborPositions(p, neighborhood) {
+		neighbors = append(neighbors, b.Get(neighborPosition))
+	}
+	return neighbors
+}
+
+// IsHex reports whether the plane is hexagonal, in which case its positions are offset coordinates and renderers
+// shift its odd rows by half a cell.
+func IsHex(plane Plane) bool {
+	_, ok := plane.(*HexBoard)
+	return ok
+}
@@ -0,0 +1,69 @@
+package grid
+
+import "testing"
+
+func TestHexCoordinates(t *testing.T) {
+	for _, p := range []Position{{0, 0}, {3, 1}, {2, 4}, {-1, -3}} {
+		if q := AxialToOffset(OffsetToAxial(p)); q != p {
+			t.Errorf("Expected %v to convert back to itself but found %v", p, q)
+		}
+	}
+
+	center := Position{2, 3}
+	p := center.HexTranslate(East, 2)
+	for turn := 0; turn < 6; turn++ {
+		rotated := p.HexRotate(center, turn)
+		expected := center.HexTranslate(East.Rotate(turn), 2)
+		if rotated != expected {
+			t.Errorf("Expected %d sixth turns to move %v to %v but found %v", turn, p, expected, rotated)
+		}
+		if d := center.HexDistanceTo(rotated); d != 2 {
+			t.Errorf("Expected %v to be 2 steps from %v but found %d", rotated, center, d)
+		}
+	}
+	if d := (Position{0, 0}).HexDistanceTo(Position{2, -1}); d != 3 {
+		t.Errorf("Expected distance 3 but found %d", d)
+	}
+}
+
+func TestHexNeighbors(t *testing.T) {
+	board := NewHexBoard(5, 5)
+	// odd rows are shifted right, so cells of even rows touch the previous and same column of the rows above and below
+	expected := map[Position]bool{{1, 1}: true, {2, 1}: true, {1, 2}: true, {3, 2}: true, {1, 3}: true, {2, 3}: true}
+	neighbors := board.GetNeighborPositions(Position{2, 2}, Hexagonal(1))
+	if len(neighbors) != 6 {
+		t.Fatalf("Expected 6 neighbors but found %v", neighbors)
+	}
+	for _, n := range neighbors {
+		if !expected[n] {
+			t.Errorf("Unexpected neighbor %v of an even row", n)
+		}
+	}
+}
+
+func TestHexPath(t *testing.T) {
+	board := NewHexBoard(5, 3)
+	board.Initialize(testCell{0})
+	// a wall down the middle column with a gap at the bottom
+	board.Set(Position{2, 0}, testCell{1})
+	board.Set(Position{2, 1}, testCell{1})
+	graph := &PlaneGraph{Plane: board, Neighborhood: Hexagonal(1), Passable: func(cell Cell) bool {
+		return cell.(testCell).value == 0
+	}}
+	path, ok := FindPath(graph.Node(Position{0, 0}), graph.Node(Position{4, 0}), graph.EstimateCost)
+	if !ok {
+		t.Fatal("Expected a path around the wall")
+	}
+	for i, node := range path.Nodes {
+		p := node.(PlaneNode).Position
+		if board.Get(p).(testCell).value != 0 {
+			t.Errorf("Path passes through the wall at %v", p)
+		}
+		if i > 0 {
+			previous := OffsetToAxial(path.Nodes[i-1].(PlaneNode).Position)
+			if d := previous.HexDistanceTo(OffsetToAxial(p)); d != 1 {
+				t.Errorf("Expected each step to move to an adjacent hex but %v is %d steps away", p, d)
+			}
+		}
+	}
+}
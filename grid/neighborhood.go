@@ -0,0 +1,196 @@
+package grid
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Neighborhood is the set of cells that affect a cell, given as offsets from its position. Rules that depend on the
+// order of their neighbors, such as rule tables, rely on Offsets keeping the order they were created in.
+type Neighborhood struct {
+	Name    string
+	Offsets []Position
+}
+
+// Moore is the square of cells within r steps in any direction, including diagonals.
+func Moore(r int) Neighborhood {
+	return filtered(fmt.Sprintf("Moore(%d)", r), r, func(dx, dy int) bool {
+		return true
+	})
+}
+
+// VonNeumann is the diamond of cells within r orthogonal steps.
+func VonNeumann(r int) Neighborhood {
+	return filtered(fmt.Sprintf("vonNeumann(%d)", r), r, func(dx, dy int) bool {
+		return abs(dx)+abs(dy) <= r
+	})
+}
+
+// Cross is the cells within r steps along the row and column of a cell.
+func Cross(r int) Neighborhood {
+	return filtered(fmt.Sprintf("cross(%d)", r), r, func(dx, dy int) bool {
+		return dx == 0 || dy == 0
+	})
+}
+
+// Hexagonal is the cells within r steps on a hexagonal grid in axial coordinates, where the six neighbors of a cell
+// are its orthogonal neighbors plus its NW and SE diagonals.
+func Hexagonal(r int) Neighborhood {
+	return filtered(fmt.Sprintf("hexagonal(%d)", r), r, func(dx, dy int) bool {
+		return HexDistance(dx, dy) <= r
+	})
+}
+
+// HexDistance is the number of steps between cells dx, dy apart in the axial coordinates used by Hexagonal.
+func HexDistance(dx, dy int) int {
+	d := abs(dx)
+	if abs(dy) > d {
+		d = abs(dy)
+	}
+	if abs(dx-dy) > d {
+		d = abs(dx - dy)
+	}
+	return d
+}
+
+// NeighborhoodNames are the names accepted by ParseNeighborhood.
+var NeighborhoodNames = []string{"moore", "vonneumann", "cross", "hexagonal"}
+
+// ParseNeighborhood returns the named neighborhood of range r, e.g. for command line flags.
+func ParseNeighborhood(name string, r int) (Neighborhood, error) {
+	if r < 1 {
+		return Neighborhood{}, fmt.Errorf("neighborhood range must be at least 1 but was %d", r)
+	}
+	switch strings.ToLower(name) {
+	case "moore":
+		return Moore(r), nil
+	case "vonneumann":
+		return VonNeumann(r), nil
+	case "cross":
+		return Cross(r), nil
+	case "hexagonal":
+		return Hexagonal(r), nil
+	}
+	return Neighborhood{}, fmt.Errorf("neighborhood %q must be one of %s", name, strings.Join(NeighborhoodNames, ", "))
+}
+
+// Custom is a neighborhood of arbitrary offsets.
+func Custom(name string, offsets ...Position) Neighborhood {
+	return Neighborhood{Name: name, Offsets: offsets}
+}
+
+// Mask builds a neighborhood from rows of equal, odd length centered on the cell, where '#' or '1' marks a neighbor
+// and '.' or '0' does not, e.g. Mask("#.#", ".#.", "#.#") for the diagonals plus the cell itself.
+func Mask(rows ...string) (Neighborhood, error) {
+	if len(rows)%2 == 0 {
+		return Neighborhood{}, fmt.Errorf("mask must have an odd number of rows but had %d", len(rows))
+	}
+	ry := len(rows) / 2
+	rx := len(rows[0]) / 2
+	var offsets []Position
+	for y, row := range rows {
+		if len(row) != len(rows[0]) || len(row)%2 == 0 {
+			return Neighborhood{}, fmt.Errorf("mask rows must all have the same odd length but row %d was %q", y, row)
+		}
+		for x, c := range row {
+			switch c {
+			case '#', '1':
+				offsets = append(offsets, Position{x - rx, y - ry})
+			case '.', '0':
+			default:
+				return Neighborhood{}, fmt.Errorf("mask row %q may only contain '#', '1', '.' or '0'", row)
+			}
+		}
+	}
+	return Neighborhood{Name: strings.Join(rows, "/"), Offsets: offsets}, nil
+}
+
+func filtered(name string, r int, include func(dx, dy int) bool) Neighborhood {
+	offsets := make([]Position, 0, (2*r+1)*(2*r+1)-1)
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			if !(dx == 0 && dy == 0) && include(dx, dy) {
+				offsets = append(offsets, Position{dx, dy})
+			}
+		}
+	}
+	return Neighborhood{Name: name, Offsets: offsets}
+}
+
+// Radius is the largest number of rows or columns between the cell and any of its neighbors.
+func (n Neighborhood) Radius() int {
+	r := 0
+	for _, offset := range n.Offsets {
+		if abs(offset.X) > r {
+			r = abs(offset.X)
+		}
+		if abs(offset.Y) > r {
+			r = abs(offset.Y)
+		}
+	}
+	return r
+}
+
+// Contains reports whether the offset is part of the neighborhood.
+func (n Neighborhood) Contains(offset Position) bool {
+	for _, o := range n.Offsets {
+		if o == offset {
+			return true
+		}
+	}
+	return false
+}
+
+// Positions returns the neighbors of p that lie within the bounds, in the order of the offsets.
+func (n Neighborhood) Positions(p Position, bounds Rectangle) []Position {
+	positions := make([]Position, 0, len(n.Offsets))
+	for _, offset := range n.Offsets {
+		neighbor := Position{p.X + offset.X, p.Y + offset.Y}
+		if bounds.Contains(neighbor) {
+			positions = append(positions, neighbor)
+		}
+	}
+	return positions
+}
+
+func (n Neighborhood) String() string {
+	return n.Name
+}
+
+// Rectangles splits the neighborhood into rectangles of offsets, merging runs of neighboring offsets in a row and
+// then rows with the same runs, so that a Moore neighborhood that includes the cell itself is a single rectangle.
+// Summing a value over the rectangles of a neighborhood with a SummedAreaTable is much cheaper than visiting every
+// neighbor of large neighborhoods.
+func (n Neighborhood) Rectangles() []Rectangle {
+	rows := make(map[int][]int)
+	for _, offset := range n.Offsets {
+		rows[offset.Y] = append(rows[offset.Y], offset.X)
+	}
+	r := n.Radius()
+	var rectangles []Rectangle
+	// rectangles that are still open, by their run of columns
+	open := make(map[[2]int]int)
+	for y := -r; y <= r; y++ {
+		xs := rows[y]
+		sort.Ints(xs)
+		next := make(map[[2]int]int)
+		for i := 0; i < len(xs); {
+			j := i
+			for j+1 < len(xs) && xs[j+1] <= xs[j]+1 {
+				j++
+			}
+			run := [2]int{xs[i], xs[j]}
+			if index, ok := open[run]; ok {
+				rectangles[index].Corner2.Y = y
+				next[run] = index
+			} else {
+				next[run] = len(rectangles)
+				rectangles = append(rectangles, Rectangle{Position{run[0], y}, Position{run[1], y}})
+			}
+			i = j + 1
+		}
+		open = next
+	}
+	return rectangles
+}
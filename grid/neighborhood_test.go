@@ -0,0 +1,64 @@
+package grid
+
+import "testing"
+
+func TestNeighborhoodSizes(t *testing.T) {
+	cases := []struct {
+		neighborhood Neighborhood
+		size         int
+		radius       int
+	}{
+		{Moore(1), 8, 1},
+		{Moore(2), 24, 2},
+		{VonNeumann(1), 4, 1},
+		{VonNeumann(2), 12, 2},
+		{Cross(3), 12, 3},
+		{Hexagonal(1), 6, 1},
+		{Hexagonal(2), 18, 2},
+	}
+	for _, c := range cases {
+		if len(c.neighborhood.Offsets) != c.size {
+			t.Errorf("Expected %s to have %d cells but found %d", c.neighborhood, c.size, len(c.neighborhood.Offsets))
+		}
+		if c.neighborhood.Radius() != c.radius {
+			t.Errorf("Expected %s to have radius %d but found %d", c.neighborhood, c.radius, c.neighborhood.Radius())
+		}
+	}
+	if hex := Hexagonal(1); hex.Contains(Position{1, -1}) || !hex.Contains(Position{1, 1}) {
+		t.Errorf("Expected hexagonal neighborhood to include the SE diagonal but not the NE one: %v", hex.Offsets)
+	}
+}
+
+func TestMask(t *testing.T) {
+	mask, err := Mask(
+		"#.#",
+		".1.",
+		"#.#",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Position{{-1, -1}, {1, -1}, {0, 0}, {-1, 1}, {1, 1}}
+	if len(mask.Offsets) != len(expected) {
+		t.Fatalf("Expected offsets %v but found %v", expected, mask.Offsets)
+	}
+	for i := range expected {
+		if mask.Offsets[i] != expected[i] {
+			t.Errorf("Expected offset %d to be %v but found %v", i, expected[i], mask.Offsets[i])
+		}
+	}
+
+	if _, err := Mask("##", "##"); err == nil {
+		t.Error("Expected a mask of even size to be rejected")
+	}
+}
+
+func TestNeighborPositionsClippedToBounds(t *testing.T) {
+	board := NewBasicBoard(3, 3)
+	if n := len(board.GetNeighborPositions(Origin, Moore(1))); n != 3 {
+		t.Errorf("Expected 3 neighbors in the corner but found %d", n)
+	}
+	if n := len(board.GetNeighborPositions(Position{1, 1}, VonNeumann(1))); n != 4 {
+		t.Errorf("Expected 4 neighbors in the center but found %d", n)
+	}
+}
@@ -0,0 +1,63 @@
+package grid
+
+// PlaneGraph adapts a plane for FindPath, connecting each position to the passable positions in its neighborhood.
+// On hexagonal planes every step costs 1, and on square planes steps cost the distance between the positions.
+type PlaneGraph struct {
+	Plane        Plane
+	Neighborhood Neighborhood
+	Passable     func(cell Cell) bool
+}
+
+// PlaneNode is a position of a PlaneGraph.
+type PlaneNode struct {
+	graph    *PlaneGraph
+	Position Position
+}
+
+type planeNeighbor struct {
+	node     PlaneNode
+	distance float64
+}
+
+func (n planeNeighbor) GetNode() Node {
+	return n.node
+}
+
+func (n planeNeighbor) GetDistance() float64 {
+	return n.distance
+}
+
+func (g *PlaneGraph) Node(p Position) PlaneNode {
+	return PlaneNode{g, p}
+}
+
+func (n PlaneNode) Id() NodeId {
+	return n.Position
+}
+
+func (n PlaneNode) GetNeighbors() []Neighbor {
+	g := n.graph
+	hex := IsHex(g.Plane)
+	positions := g.Plane.GetNeighborPositions(n.Position, g.Neighborhood)
+	results := make([]Neighbor, 0, len(positions))
+	for _, p := range positions {
+		if g.Passable != nil && !g.Passable(g.Plane.Get(p)) {
+			continue
+		}
+		distance := 1.0
+		if !hex {
+			distance = n.Position.DistanceTo(p)
+		}
+		results = append(results, planeNeighbor{PlaneNode{g, p}, distance})
+	}
+	return results
+}
+
+// EstimateCost is a HeuristicCostEstimateFunc for nodes of the graph, which never overestimates the cost of a path.
+func (g *PlaneGraph) EstimateCost(n1, n2 Node) float64 {
+	p1, p2 := n1.(PlaneNode).Position, n2.(PlaneNode).Position
+	if IsHex(g.Plane) {
+		return float64(OffsetToAxial(p1).HexDistanceTo(OffsetToAxial(p2)))
+	}
+	return p1.DistanceTo(p2)
+}
@@ -0,0 +1,164 @@
+package grid
+
+// ReservationTable records which owner holds a node at each time step so that several units can plan
+// paths that do not collide. See "Cooperative Pathfinding" (Silver, 2005).
+type ReservationTable struct {
+	reservations map[reservation]interface{}
+}
+
+type reservation struct {
+	id   NodeId
+	time int
+}
+
+func NewReservationTable() *ReservationTable {
+	return &ReservationTable{reservations: make(map[reservation]interface{})}
+}
+
+// Reserve claims the node for the owner at the given time. Returns false if another owner already holds it.
+func (r *ReservationTable) Reserve(id NodeId, time int, owner interface{}) bool {
+	key := reservation{id, time}
+	if current, ok := r.reservations[key]; ok && current != owner {
+		return false
+	}
+	r.reservations[key] = owner
+	return true
+}
+
+// Owner returns the owner holding the node at the given time, or nil if it is free.
+func (r *ReservationTable) Owner(id NodeId, time int) interface{} {
+	return r.reservations[reservation{id, time}]
+}
+
+// Available reports if the owner may occupy the node at the given time. A node is unavailable if any other owner
+// holds it at that time or at the time step before. The second rule forbids both swaps and units following directly
+// behind each other, so two units never write to the same node in the same time step.
+func (r *ReservationTable) Available(id NodeId, time int, owner interface{}) bool {
+	for _, t := range []int{time - 1, time} {
+		if current := r.Owner(id, t); current != nil && current != owner {
+			return false
+		}
+	}
+	return true
+}
+
+// Release drops all reservations held by the owner at or after the given time.
+func (r *ReservationTable) Release(owner interface{}, from int) {
+	for key, current := range r.reservations {
+		if current == owner && key.time >= from {
+			delete(r.reservations, key)
+		}
+	}
+}
+
+// Prune drops all reservations before the given time.
+func (r *ReservationTable) Prune(before int) {
+	for key := range r.reservations {
+		if key.time < before {
+			delete(r.reservations, key)
+		}
+	}
+}
+
+type spaceTimeId struct {
+	id   NodeId
+	time int
+}
+
+type spaceTimeNode struct {
+	node   Node
+	time   int
+	search *cooperativeSearch
+}
+
+func (n spaceTimeNode) Id() NodeId {
+	return spaceTimeId{n.node.Id(), n.time}
+}
+
+func (n spaceTimeNode) GetNeighbors() []Neighbor {
+	s := n.search
+	next := n.time + 1
+	if next > s.horizon {
+		// beyond the window reservations are ignored and the search collapses back to a spatial search
+		next = s.horizon
+	}
+	neighbors := n.node.GetNeighbors()
+	results := make([]Neighbor, 0, len(neighbors)+1)
+	if s.available(n.node, next) {
+		results = append(results, spaceTimeNeighbor{spaceTimeNode{n.node, next, s}, 1})
+	}
+	for _, neighbor := range neighbors {
+		if s.available(neighbor.GetNode(), next) {
+			results = append(results, spaceTimeNeighbor{spaceTimeNode{neighbor.GetNode(), next, s}, neighbor.GetDistance()})
+		}
+	}
+	return results
+}
+
+type spaceTimeNeighbor struct {
+	node     spaceTimeNode
+	distance float64
+}
+
+func (n spaceTimeNeighbor) GetNode() Node {
+	return n.node
+}
+
+func (n spaceTimeNeighbor) GetDistance() float64 {
+	return n.distance
+}
+
+type cooperativeSearch struct {
+	table   *ReservationTable
+	owner   interface{}
+	horizon int
+}
+
+func (s *cooperativeSearch) available(node Node, time int) bool {
+	if time >= s.horizon {
+		return true
+	}
+	return s.table.Available(node.Id(), time, s.owner)
+}
+
+// FindCooperativePath finds a path from start to goal that avoids the reservations of other owners for the next
+// window time steps (windowed hierarchical cooperative A*). The returned path contains one node per time step,
+// starting at startTime, so a node repeats when the owner must wait. Like FindPath, the nodes are ordered from goal to
+// start. The path is not reserved; use ReservePath once the owner commits to it.
+func FindCooperativePath(start, goal Node, startTime, window int, table *ReservationTable, owner interface{}, estimateCost HeuristicCostEstimateFunc) (path *Path, ok bool) {
+	search := &cooperativeSearch{table: table, owner: owner, horizon: startTime + window}
+	isGoal := func(n Node) bool {
+		return n.(spaceTimeNode).node.Id() == goal.Id()
+	}
+	estimateToGoal := func(n Node) float64 {
+		return estimateCost(n.(spaceTimeNode).node, goal)
+	}
+	spaceTimePath, ok := findPath(spaceTimeNode{start, startTime, search}, isGoal, estimateToGoal)
+	if !ok {
+		return nil, false
+	}
+	nodes := make([]Node, len(spaceTimePath.Nodes))
+	for i, n := range spaceTimePath.Nodes {
+		nodes[i] = n.(spaceTimeNode).node
+	}
+	return &Path{nodes}, true
+}
+
+// ReservePath reserves a path returned by FindCooperativePath for the owner, up to but excluding startTime+window. If
+// the path ends early its final node stays reserved for the rest of the window. Returns the number of time steps
+// reserved, which is where the owner should plan again.
+func (r *ReservationTable) ReservePath(path *Path, startTime, window int, owner interface{}) int {
+	nodes := path.Nodes
+	steps := 0
+	for t := startTime; t < startTime+window; t++ {
+		i := len(nodes) - 1 - (t - startTime)
+		if i < 0 {
+			i = 0
+		}
+		if !r.Reserve(nodes[i].Id(), t, owner) {
+			break
+		}
+		steps++
+	}
+	return steps
+}
@@ -0,0 +1,72 @@
+package grid
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCooperativePathWaits(t *testing.T) {
+	start, goal := createNodes([][]NodeType{
+		{S, O, O, G},
+	})
+	table := NewReservationTable()
+	other := "other"
+	// another unit crosses the corridor at (0, 2) at time 2
+	table.Reserve(Position{0, 2}, 2, other)
+
+	path, ok := FindCooperativePath(start, goal, 0, 8, table, "unit", distance)
+	if !ok {
+		t.Fatal("Expected FindCooperativePath to return ok=true, but got ok=false.")
+	}
+	expected := []Position{
+		{0, 3},
+		{0, 2},
+		{0, 1},
+		{0, 1},
+		{0, 1},
+		{0, 0},
+	}
+	route := path.Nodes
+	if len(route) != len(expected) {
+		t.Fatalf("Expected path of length %d but found %d", len(expected), len(route))
+	}
+	for i, n := range route {
+		if n.(*TestNode).Position != expected[i] {
+			t.Error(fmt.Sprintf("Expected path[%d] to be %v but found %v", i, expected[i], n.(*TestNode).Position))
+		}
+	}
+}
+
+func TestCooperativePathsDoNotCollide(t *testing.T) {
+	start, goal := createNodes([][]NodeType{
+		{S, O, O, O},
+		{O, O, O, O},
+		{O, O, O, G},
+	})
+	table := NewReservationTable()
+	first, ok := FindCooperativePath(start, goal, 0, 8, table, "first", distance)
+	if !ok {
+		t.Fatal("Expected first path to be found")
+	}
+	table.ReservePath(first, 0, 8, "first")
+
+	second, ok := FindCooperativePath(goal, start, 0, 8, table, "second", distance)
+	if !ok {
+		t.Fatal("Expected second path to be found")
+	}
+	at := func(path *Path, time int) Position {
+		i := len(path.Nodes) - 1 - time
+		if i < 0 {
+			i = 0
+		}
+		return path.Nodes[i].(*TestNode).Position
+	}
+	for time := 0; time < 8; time++ {
+		if at(first, time) == at(second, time) {
+			t.Errorf("Both paths occupy %v at time %d", at(first, time), time)
+		}
+		if time > 0 && at(first, time-1) == at(second, time) {
+			t.Errorf("Second path enters %v at time %d right behind the first", at(second, time), time)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package grid
+
+// SummedAreaTable holds, for every position of a plane, the sum of a value over the rectangle from the first corner of
+// the plane to that position. The sum over any rectangle can then be found from four entries, no matter its size.
+type SummedAreaTable struct {
+	bounds Rectangle
+	w, h   int
+	// sums has an extra leading row and column of zeros so that rectangles touching the edges need no special case
+	sums []int
+}
+
+func NewSummedAreaTable(plane Plane, value func(cell Cell) int) *SummedAreaTable {
+	bounds := plane.Bounds()
+	w := bounds.Corner2.X - bounds.Corner1.X + 1
+	h := bounds.Corner2.Y - bounds.Corner1.Y + 1
+	t := &SummedAreaTable{bounds: bounds, w: w, h: h, sums: make([]int, (w+1)*(h+1))}
+	for y := 0; y < h; y++ {
+		rowSum := 0
+		for x := 0; x < w; x++ {
+			rowSum += value(plane.Get(Position{bounds.Corner1.X + x, bounds.Corner1.Y + y}))
+			t.sums[(y+1)*(w+1)+x+1] = t.sums[y*(w+1)+x+1] + rowSum
+		}
+	}
+	return t
+}
+
+// Sum returns the sum of the value over the rectangle, counting only the part of it within the bounds of the plane.
+func (t *SummedAreaTable) Sum(r Rectangle) int {
+	x1 := clamp(r.Corner1.X-t.bounds.Corner1.X, 0, t.w)
+	y1 := clamp(r.Corner1.Y-t.bounds.Corner1.Y, 0, t.h)
+	x2 := clamp(r.Corner2.X-t.bounds.Corner1.X+1, 0, t.w)
+	y2 := clamp(r.Corner2.Y-t.bounds.Corner1.Y+1, 0, t.h)
+	if x1 >= x2 || y1 >= y2 {
+		return 0
+	}
+	stride := t.w + 1
+	return t.sums[y2*stride+x2] - t.sums[y1*stride+x2] - t.sums[y2*stride+x1] + t.sums[y1*stride+x1]
+}
+
+// NeighborhoodSum returns the sum of the value over the neighborhood of p, given the rectangles of the neighborhood.
+func (t *SummedAreaTable) NeighborhoodSum(p Position, rectangles []Rectangle) int {
+	sum := 0
+	for _, r := range rectangles {
+		sum += t.Sum(Rectangle{Position{p.X + r.Corner1.X, p.Y + r.Corner1.Y}, Position{p.X + r.Corner2.X, p.Y + r.Corner2.Y}})
+	}
+	return sum
+}
+
+func clamp(i, min, max int) int {
+	if i < min {
+		return min
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
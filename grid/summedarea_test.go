@@ -0,0 +1,56 @@
+package grid
+
+import (
+	"github.com/nsf/termbox-go"
+	"math/rand"
+	"testing"
+)
+
+type testCell struct {
+	value int
+}
+
+func (c testCell) Rune() rune {
+	return ' '
+}
+
+func (c testCell) FgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+func (c testCell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+func TestNeighborhoodSumMatchesNeighbors(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	board := NewBasicBoard(20, 15)
+	for i := range board.Cells {
+		board.Cells[i] = testCell{random.Intn(2)}
+	}
+	table := NewSummedAreaTable(board, func(cell Cell) int {
+		return cell.(testCell).value
+	})
+
+	for _, neighborhood := range []Neighborhood{Moore(1), Moore(3), VonNeumann(4), Cross(2), Hexagonal(3)} {
+		rectangles := neighborhood.Rectangles()
+		for _, p := range []Position{{0, 0}, {5, 7}, {19, 14}, {18, 2}} {
+			expected := 0
+			for _, neighbor := range board.GetNeighbors(p, neighborhood) {
+				expected += neighbor.(testCell).value
+			}
+			if sum := table.NeighborhoodSum(p, rectangles); sum != expected {
+				t.Errorf("Expected %s sum at %v to be %d but found %d", neighborhood, p, expected, sum)
+			}
+		}
+	}
+	// the row of the cell is split by the cell itself, so it can't be merged with the rows above and below
+	if n := len(Moore(5).Rectangles()); n != 4 {
+		t.Errorf("Expected Moore neighborhood to split into 4 rectangles but found %d", n)
+	}
+	withCell := Moore(5)
+	withCell.Offsets = append(withCell.Offsets, Origin)
+	if n := len(withCell.Rectangles()); n != 1 {
+		t.Errorf("Expected Moore neighborhood including the cell to be 1 rectangle but found %d", n)
+	}
+}
@@ -0,0 +1,101 @@
+package grid
+
+import (
+	"math"
+)
+
+// Line returns the positions on the line from p1 to p2, including both ends, using Bresenham's line algorithm.
+func Line(p1, p2 Position) []Position {
+	dx, dy := abs(p2.X-p1.X), -abs(p2.Y-p1.Y)
+	sx, sy := sign(p2.X-p1.X), sign(p2.Y-p1.Y)
+	err := dx + dy
+	line := make([]Position, 0, dx-dy+1)
+	p := p1
+	for {
+		line = append(line, p)
+		if p == p2 {
+			return line
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			p.X += sx
+		}
+		if e2 <= dx {
+			err += dx
+			p.Y += sy
+		}
+	}
+}
+
+// LineOfSight reports if p2 can be seen from p1, i.e. no position strictly between them on the line is opaque.
+func LineOfSight(p1, p2 Position, opaque func(Position) bool) bool {
+	line := Line(p1, p2)
+	for i := 1; i < len(line)-1; i++ {
+		if opaque(line[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// VisionCone returns the positions within bounds that can be seen from the origin when facing in the given
+// orientation. A position is in the cone if it is at most radius away and within halfAngle radians of the facing
+// direction. Opaque positions can be seen but block sight of the positions behind them.
+func VisionCone(origin Position, facing Orientation, radius int, halfAngle float64, bounds Rectangle, opaque func(Position) bool) []Position {
+	ahead := origin.Translate(facing, 1)
+	fx, fy := float64(ahead.X-origin.X), float64(ahead.Y-origin.Y)
+	visible := make([]Position, 0)
+	for x := origin.X - radius; x <= origin.X+radius; x++ {
+		for y := origin.Y - radius; y <= origin.Y+radius; y++ {
+			p := Position{x, y}
+			if !bounds.Contains(p) || origin.DistanceTo(p) > float64(radius) {
+				continue
+			}
+			if p != origin {
+				dx, dy := float64(x-origin.X), float64(y-origin.Y)
+				angle := math.Acos((dx*fx + dy*fy) / math.Hypot(dx, dy))
+				if angle > halfAngle {
+					continue
+				}
+			}
+			if LineOfSight(origin, p, opaque) {
+				visible = append(visible, p)
+			}
+		}
+	}
+	return visible
+}
+
+// OrientationTo returns the orientation that most directly faces p2 from p1.
+func (p1 Position) OrientationTo(p2 Position) Orientation {
+	dx, dy := p2.X-p1.X, p2.Y-p1.Y
+	if abs(dx) > abs(dy) {
+		if dx > 0 {
+			return Right
+		}
+		return Left
+	}
+	if dy > 0 {
+		return Down
+	}
+	return Up
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func sign(i int) int {
+	switch {
+	case i > 0:
+		return 1
+	case i < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,43 @@
+package grid
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBresenhamLine(t *testing.T) {
+	line := Line(Position{0, 0}, Position{4, 2})
+	expected := []Position{{0, 0}, {1, 1}, {2, 1}, {3, 2}, {4, 2}}
+	if len(line) != len(expected) {
+		t.Fatalf("Expected line %v but found %v", expected, line)
+	}
+	for i := range expected {
+		if line[i] != expected[i] {
+			t.Errorf("Expected line[%d] to be %v but found %v", i, expected[i], line[i])
+		}
+	}
+}
+
+func TestVisionConeBlockedByWall(t *testing.T) {
+	bounds := Rectangle{Origin, Position{9, 9}}
+	wall := Position{5, 3}
+	opaque := func(p Position) bool {
+		return p == wall
+	}
+	cone := VisionCone(Position{5, 5}, Up, 4, math.Pi/4, bounds, opaque)
+	visible := make(map[Position]bool)
+	for _, p := range cone {
+		visible[p] = true
+	}
+
+	for _, p := range []Position{{5, 5}, {5, 4}, {5, 3}, {3, 2}, {7, 2}} {
+		if !visible[p] {
+			t.Errorf("Expected %v to be visible", p)
+		}
+	}
+	for _, p := range []Position{{5, 2}, {5, 1}, {5, 6}, {8, 5}, {1, 5}} {
+		if visible[p] {
+			t.Errorf("Expected %v not to be visible", p)
+		}
+	}
+}
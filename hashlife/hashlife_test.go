@@ -0,0 +1,214 @@
+package hashlife
+
+import (
+	"bytes"
+	"github.com/jpbetz/cellularautomata/bitlife"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/nsf/termbox-go"
+	"math/rand"
+	"testing"
+)
+
+type testCell bool
+
+func (c testCell) Rune() rune                     { return ' ' }
+func (c testCell) FgAttribute() termbox.Attribute { return termbox.ColorDefault }
+func (c testCell) BgAttribute() termbox.Attribute { return termbox.ColorDefault }
+
+// soup puts the same random square of cells in a universe and in the middle of a bitboard big enough that nothing
+// reaches its edges.
+func soup(size, boardSize int, rule bitlife.Rule) (*Universe, *bitlife.Board) {
+	random := rand.New(rand.NewSource(1))
+	u := NewUniverse(rule)
+	board := bitlife.NewBoard(boardSize, boardSize, testCell(true), testCell(false))
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			if random.Intn(2) == 0 {
+				u.SetAlive(x-size/2, y-size/2, true)
+				board.SetAlive(grid.Position{x - size/2 + boardSize/2, y - size/2 + boardSize/2}, true)
+			}
+		}
+	}
+	return u, board
+}
+
+func assertSame(t *testing.T, u *Universe, board *bitlife.Board) {
+	if u.Population() != board.Population() {
+		t.Fatalf("Expected population %d at generation %d but found %d", board.Population(), u.Generation,
+			u.Population())
+	}
+	for x := 0; x < board.W; x++ {
+		for y := 0; y < board.H; y++ {
+			if board.IsAlive(grid.Position{x, y}) && !u.IsAlive(x-board.W/2, y-board.H/2) {
+				t.Fatalf("Expected %d,%d to be alive at generation %d", x-board.W/2, y-board.H/2, u.Generation)
+			}
+		}
+	}
+}
+
+func TestStepMatchesBitboard(t *testing.T) {
+	for _, rule := range []string{"B3/S23", "B36/S23"} {
+		rule, _ := bitlife.ParseRule(rule)
+		u, board := soup(16, 256, rule)
+		for generation := 0; generation < 20; generation++ {
+			u.Step()
+			board.Step(rule)
+			assertSame(t, u, board)
+		}
+
+		// jumping ahead gives the same result as stepping
+		u.SetStepExp(5)
+		u.Step()
+		for i := 0; i < 32; i++ {
+			board.Step(rule)
+		}
+		if u.Generation != 52 {
+			t.Errorf("Expected generation 52 but found %d", u.Generation)
+		}
+		assertSame(t, u, board)
+	}
+}
+
+func TestGliderJump(t *testing.T) {
+	u := NewUniverse(bitlife.Conway)
+	for _, p := range [][2]int{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}} {
+		u.SetAlive(p[0], p[1], true)
+	}
+	u.SetStepExp(20)
+	u.Step()
+	// a glider moves one cell diagonally every 4 generations
+	d := 1 << 18
+	for _, p := range [][2]int{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}} {
+		if !u.IsAlive(p[0]+d, p[1]+d) {
+			t.Errorf("Expected %d,%d to be alive", p[0]+d, p[1]+d)
+		}
+	}
+	if u.Population() != 5 {
+		t.Errorf("Expected a population of 5 but found %d", u.Population())
+	}
+}
+
+func TestCollectKeepsPattern(t *testing.T) {
+	u, board := soup(16, 256, bitlife.Conway)
+	u.MaxNodes = 100
+	for generation := 0; generation < 30; generation++ {
+		u.Step()
+		board.Step(bitlife.Conway)
+	}
+	assertSame(t, u, board)
+	if u.Nodes() > 100000 {
+		t.Errorf("Expected nodes to be collected but found %d", u.Nodes())
+	}
+}
+
+func TestMacrocellRoundTrip(t *testing.T) {
+	u, _ := soup(20, 64, bitlife.Conway)
+	u.Generation = 7
+	var buffer bytes.Buffer
+	if err := WriteMacrocell(&buffer, u); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadMacrocell(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatalf("Expected to read back\n%s\nbut got: %v", buffer.String(), err)
+	}
+	if read.Generation != 7 || read.Rule != u.Rule || read.Population() != u.Population() {
+		t.Errorf("Expected generation 7, %s and population %d but found generation %d, %s and population %d",
+			u.Rule, u.Population(), read.Generation, read.Rule, read.Population())
+	}
+	for x := -10; x < 10; x++ {
+		for y := -10; y < 10; y++ {
+			if read.IsAlive(x, y) != u.IsAlive(x, y) {
+				t.Errorf("Expected %d,%d to be alive=%t", x, y, u.IsAlive(x, y))
+			}
+		}
+	}
+}
+
+func TestReadMacrocell(t *testing.T) {
+	// a glider in the top left leaf of a 16x16 root, from Golly
+	mc := "[M2] (golly 2.8)\n#R B3/S23\n.*$..*$***$\n4 1 0 0 0\n"
+	u, err := ReadMacrocell(bytes.NewReader([]byte(mc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range [][2]int{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}} {
+		if !u.IsAlive(p[0]-8, p[1]-8) {
+			t.Errorf("Expected %d,%d to be alive", p[0]-8, p[1]-8)
+		}
+	}
+	for _, invalid := range []string{"", "[M2]\n", "[M2]\n4 1 0 0 0\n", "[M2]\n.*x\n", "[M2]\n#R 23/3\n.*$\n",
+		"[M2]\n#R B0/S8\n.*$\n"} {
+		if _, err := ReadMacrocell(bytes.NewReader([]byte(invalid))); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestView(t *testing.T) {
+	u := NewUniverse(bitlife.Conway)
+	view := NewView(u, 10, 10, testCell(true), testCell(false))
+	view.Set(grid.Position{5, 5}, testCell(true))
+	if !u.IsAlive(0, 0) {
+		t.Errorf("Expected the center of the view to be the origin")
+	}
+	u.SetAlive(2, 2, true)
+	changes := view.Changes()
+	if len(changes) != 1 || changes[0] != (grid.Position{7, 7}) {
+		t.Errorf("Expected only 7,7 to have changed but found %v", changes)
+	}
+	view.SetZoom(2)
+	// each cell shows 4x4 cells, so the two live cells, in the same block of 4x4 cells, show in one view cell
+	alive := 0
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			if view.Get(grid.Position{x, y}) == testCell(true) {
+				alive++
+			}
+		}
+	}
+	if alive != 1 {
+		t.Errorf("Expected the live cells to be shown in one cell but found %d", alive)
+	}
+}
+
+func TestCheckRule(t *testing.T) {
+	if err := CheckRule(bitlife.Conway); err != nil {
+		t.Errorf("Expected Conway's rule to be accepted but got %v", err)
+	}
+	rule, err := bitlife.ParseRule("B0/S8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckRule(rule); err == nil {
+		t.Errorf("Expected %s to be rejected", rule)
+	}
+}
+
+func TestViewReadsWhileStepping(t *testing.T) {
+	u, _ := soup(16, 64, bitlife.Conway)
+	view := NewView(u, 40, 40, testCell(true), testCell(false))
+	view.SetZoom(4)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for generation := 0; generation < 200; generation++ {
+			u.Step()
+		}
+	}()
+	for stepping := true; stepping; {
+		select {
+		case <-done:
+			stepping = false
+		default:
+		}
+		// reads beyond the root, at a level above it and outside of it, must not grow the universe
+		for x := 0; x < 40; x++ {
+			view.Get(grid.Position{X: x, Y: 20})
+		}
+		u.NodeAt(1<<40, 0, 0)
+	}
+	if root := u.Root(); root.Level > 20 {
+		t.Errorf("Expected reads not to grow the root but it is of level %d", root.Level)
+	}
+}
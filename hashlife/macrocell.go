@@ -0,0 +1,187 @@
+package hashlife
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/bitlife"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadMacrocell reads a universe in Golly's macrocell (.mc) format. Every line after the header defines a node, which
+// later lines refer to by number, counting from 1, with 0 for an empty node. Nodes of 8x8 cells are written as rows of
+// . and * ended by $, and bigger nodes as their level followed by the numbers of their NW, NE, SW and SE quadrants. The
+// last node is the root, centered on the origin. The rule is read from a #R line, and defaults to Conway's.
+func ReadMacrocell(r io.Reader) (*Universe, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "[M2]") {
+		return nil, fmt.Errorf("macrocell files must start with [M2]")
+	}
+	u := NewUniverse(bitlife.Conway)
+	nodes := []*Node{nil}
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "":
+		case strings.HasPrefix(text, "#R"):
+			rule, err := bitlife.ParseRule(strings.TrimSpace(text[2:]))
+			if err == nil {
+				err = CheckRule(rule)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line+1, err)
+			}
+			u.Rule = rule
+		case strings.HasPrefix(text, "#G"):
+			generation, err := strconv.ParseInt(strings.TrimSpace(text[2:]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid generation: %v", line+1, err)
+			}
+			u.Generation = generation
+		case strings.HasPrefix(text, "#"):
+		case strings.ContainsAny(text[:1], ".*$"):
+			leaf, err := u.readLeaf(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line+1, err)
+			}
+			nodes = append(nodes, leaf)
+		default:
+			node, err := u.readNode(text, nodes)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line+1, err)
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(nodes) == 1 {
+		return nil, fmt.Errorf("macrocell file has no nodes")
+	}
+	u.SetRoot(nodes[len(nodes)-1])
+	return u, nil
+}
+
+// readLeaf reads an 8x8 node from rows of . and * ended by $.
+func (u *Universe) readLeaf(text string) (*Node, error) {
+	var cells [8][8]bool
+	x, y := 0, 0
+	for _, c := range text {
+		switch c {
+		case '.', '*':
+			if x >= 8 || y >= 8 {
+				return nil, fmt.Errorf("leaf %q is bigger than 8x8", text)
+			}
+			cells[y][x] = c == '*'
+			x++
+		case '$':
+			x, y = 0, y+1
+		default:
+			return nil, fmt.Errorf("leaf %q has invalid character %q", text, c)
+		}
+	}
+	return u.build(3, func(x, y int) bool {
+		return cells[y][x]
+	}), nil
+}
+
+// build returns the node of the level whose cells are alive where alive returns true.
+func (u *Universe) build(level int, alive func(x, y int) bool) *Node {
+	if level == 0 {
+		if alive(0, 0) {
+			return u.alive
+		}
+		return u.dead
+	}
+	half := 1 << uint(level-1)
+	at := func(dx, dy int) func(x, y int) bool {
+		return func(x, y int) bool {
+			return alive(x+dx, y+dy)
+		}
+	}
+	return u.node(
+		u.build(level-1, alive),
+		u.build(level-1, at(half, 0)),
+		u.build(level-1, at(0, half)),
+		u.build(level-1, at(half, half)),
+	)
+}
+
+// readNode reads a node given by its level and the numbers of its quadrants.
+func (u *Universe) readNode(text string, nodes []*Node) (*Node, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("node %q must have a level and 4 quadrants", text)
+	}
+	level, err := strconv.Atoi(fields[0])
+	if err != nil || level <= 3 || level > 62 {
+		return nil, fmt.Errorf("node %q must have a level between 4 and 62", text)
+	}
+	var quadrants [4]*Node
+	for i, field := range fields[1:] {
+		index, err := strconv.Atoi(field)
+		if err != nil || index < 0 || index >= len(nodes) {
+			return nil, fmt.Errorf("node %q refers to undefined node %s", text, field)
+		}
+		if index == 0 {
+			quadrants[i] = u.empty(level - 1)
+		} else if quadrants[i] = nodes[index]; quadrants[i].Level != level-1 {
+			return nil, fmt.Errorf("node %q has quadrant %d of level %d", text, index, quadrants[i].Level)
+		}
+	}
+	return u.node(quadrants[0], quadrants[1], quadrants[2], quadrants[3]), nil
+}
+
+// WriteMacrocell writes the universe in Golly's macrocell format, see ReadMacrocell.
+func WriteMacrocell(w io.Writer, u *Universe) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "[M2] (cellularautomata)\n#R %s\n#G %d\n", u.Rule, u.Generation)
+	root := u.Root()
+	for root.Level < minLevel {
+		root = u.expand(root)
+	}
+	indexes := map[*Node]int{}
+	var write func(n *Node) int
+	write = func(n *Node) int {
+		if n.Population == 0 && n != root {
+			return 0
+		}
+		if index, ok := indexes[n]; ok {
+			return index
+		}
+		if n.Level == 3 {
+			out.WriteString(leafRows(n))
+		} else {
+			nw, ne, sw, se := write(n.NW), write(n.NE), write(n.SW), write(n.SE)
+			fmt.Fprintf(out, "%d %d %d %d %d", n.Level, nw, ne, sw, se)
+		}
+		out.WriteString("\n")
+		indexes[n] = len(indexes) + 1
+		return indexes[n]
+	}
+	write(root)
+	return out.Flush()
+}
+
+// leafRows returns the rows of an 8x8 node, leaving out dead cells at the ends of rows and empty rows at the end.
+func leafRows(n *Node) string {
+	rows := make([]string, 8)
+	for y := range rows {
+		row := ""
+		for x := 0; x < 8; x++ {
+			if cellAt(n, x, y) {
+				row += "*"
+			} else {
+				row += "."
+			}
+		}
+		rows[y] = strings.TrimRight(row, ".") + "$"
+	}
+	text := strings.Join(rows, "")
+	for strings.HasSuffix(text, "$$") {
+		text = text[:len(text)-1]
+	}
+	return text
+}
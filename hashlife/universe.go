@@ -0,0 +1,381 @@
+package hashlife
+
+import (
+	"fmt"
+	"github.com/jpbetz/cellularautomata/bitlife"
+	"sync"
+)
+
+// Node is a square of 2^Level by 2^Level cells, made of four nodes one level down. Nodes are canonical: a universe
+// creates every distinct square only once, so equal squares anywhere in the universe and at any time are the same
+// node, and what is computed for one is shared by all of them.
+type Node struct {
+	Level          int
+	NW, NE, SW, SE *Node
+	Population     int
+
+	// the center half of the node 2^resultStep generations ahead
+	result     *Node
+	resultStep int
+}
+
+type quad struct {
+	nw, ne, sw, se *Node
+}
+
+// DefaultMaxNodes is the number of nodes a universe keeps before it collects the ones no longer in use.
+const DefaultMaxNodes = 1 << 20
+
+// minLevel is the smallest the root gets, the size of a leaf in a macrocell file.
+const minLevel = 3
+
+// maxLevel is the biggest the root gets before its coordinates overflow.
+const maxLevel = 63
+
+// Universe runs a binary Life-like rule on an unbounded plane with Gosper's HashLife algorithm, which advances large
+// or regular patterns by huge numbers of generations at once by memoizing the future of every node.
+//
+// The root node is centered on the origin, so a root of level k covers -2^(k-1) to 2^(k-1)-1 in both directions.
+//
+// Reads such as NodeAt may be made while another goroutine steps or fills the universe, e.g. by a renderer.
+type Universe struct {
+	Rule       bitlife.Rule
+	Generation int64
+	// MaxNodes is the number of nodes kept before the ones no longer in use are collected
+	MaxNodes int
+
+	// mu is held to read while Step, Fill and SetRoot change the root and the nodes
+	mu      sync.RWMutex
+	root    *Node
+	stepExp int
+	nodes   map[quad]*Node
+	dead    *Node
+	alive   *Node
+	empties []*Node
+}
+
+func NewUniverse(rule bitlife.Rule) *Universe {
+	u := &Universe{
+		Rule:     rule,
+		MaxNodes: DefaultMaxNodes,
+		nodes:    map[quad]*Node{},
+		dead:     &Node{},
+		alive:    &Node{Population: 1},
+	}
+	// the empty nodes of every level are built up front, so that reads beyond the root never add nodes
+	u.empty(maxLevel)
+	u.root = u.empty(minLevel)
+	return u
+}
+
+// CheckRule returns an error for rules HashLife can't run. Under rules with B0 empty space comes alive, which breaks
+// the assumption that the empty nodes around a pattern stay empty.
+func CheckRule(rule bitlife.Rule) error {
+	if rule.Birth[0] {
+		return fmt.Errorf("rule %s has B0, which HashLife can't run", rule)
+	}
+	return nil
+}
+
+// node returns the canonical node with the given quadrants.
+func (u *Universe) node(nw, ne, sw, se *Node) *Node {
+	key := quad{nw, ne, sw, se}
+	if n, ok := u.nodes[key]; ok {
+		return n
+	}
+	n := &Node{
+		Level:      nw.Level + 1,
+		NW:         nw,
+		NE:         ne,
+		SW:         sw,
+		SE:         se,
+		Population: nw.Population + ne.Population + sw.Population + se.Population,
+	}
+	u.nodes[key] = n
+	return n
+}
+
+// empty returns the node of dead cells of the level.
+func (u *Universe) empty(level int) *Node {
+	for len(u.empties) <= level {
+		if len(u.empties) == 0 {
+			u.empties = append(u.empties, u.dead)
+		} else {
+			e := u.empties[len(u.empties)-1]
+			u.empties = append(u.empties, u.node(e, e, e, e))
+		}
+	}
+	return u.empties[level]
+}
+
+// full returns the node of live cells of the level.
+func (u *Universe) full(level int) *Node {
+	if level == 0 {
+		return u.alive
+	}
+	f := u.full(level - 1)
+	return u.node(f, f, f, f)
+}
+
+// expand returns a node one level up with n in its center.
+func (u *Universe) expand(n *Node) *Node {
+	e := u.empty(n.Level - 1)
+	return u.node(
+		u.node(e, e, e, n.NW),
+		u.node(e, e, n.NE, e),
+		u.node(e, n.SW, e, e),
+		u.node(n.SE, e, e, e),
+	)
+}
+
+// center returns the center half of n.
+func (u *Universe) center(n *Node) *Node {
+	return u.node(n.NW.SE, n.NE.SW, n.SW.NE, n.SE.NW)
+}
+
+// Root returns the node holding every live cell of the universe.
+func (u *Universe) Root() *Node {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.root
+}
+
+// Population returns the number of live cells.
+func (u *Universe) Population() int {
+	return u.Root().Population
+}
+
+// StepExp returns the log2 of the number of generations each Step advances.
+func (u *Universe) StepExp() int {
+	return u.stepExp
+}
+
+// SetStepExp makes each Step advance 2^exp generations, up to 2^62.
+func (u *Universe) SetStepExp(exp int) {
+	if exp < 0 {
+		exp = 0
+	}
+	if exp > 62 {
+		exp = 62
+	}
+	u.stepExp = exp
+}
+
+// Step advances the universe by 2^StepExp generations.
+func (u *Universe) Step() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	j := u.stepExp
+	// grow until the pattern fits in the center half of the root, then once more so that it can't grow past the
+	// center half of the bigger root, which is what result returns, in 2^j generations
+	for u.root.Level < j+2 || u.root.Population != u.center(u.root).Population {
+		u.root = u.expand(u.root)
+	}
+	u.root = u.result(u.expand(u.root), j)
+	u.Generation += 1 << uint(j)
+	if len(u.nodes) > u.MaxNodes {
+		u.collect()
+	}
+}
+
+// result returns the center half of n advanced by 2^j generations, or by 2^(n.Level-2) generations, the most that
+// n determines, if that is less.
+func (u *Universe) result(n *Node, j int) *Node {
+	if j > n.Level-2 {
+		j = n.Level - 2
+	}
+	if n.result != nil && n.resultStep == j {
+		return n.result
+	}
+
+	var r *Node
+	switch {
+	case n.Population == 0:
+		r = u.empty(n.Level - 1)
+	case n.Level == 2:
+		r = u.base(n)
+	default:
+		// nine overlapping nodes of half the size, each either advanced halfway or just centered
+		nine := [9]*Node{
+			n.NW, u.node(n.NW.NE, n.NE.NW, n.NW.SE, n.NE.SW), n.NE,
+			u.node(n.NW.SW, n.NW.SE, n.SW.NW, n.SW.NE), u.center(n), u.node(n.NE.SW, n.NE.SE, n.SE.NW, n.SE.NE),
+			n.SW, u.node(n.SW.NE, n.SE.NW, n.SW.SE, n.SE.SW), n.SE,
+		}
+		for i, m := range nine {
+			if j == n.Level-2 {
+				nine[i] = u.result(m, j)
+			} else {
+				nine[i] = u.center(m)
+			}
+		}
+		r = u.node(
+			u.result(u.node(nine[0], nine[1], nine[3], nine[4]), j),
+			u.result(u.node(nine[1], nine[2], nine[4], nine[5]), j),
+			u.result(u.node(nine[3], nine[4], nine[6], nine[7]), j),
+			u.result(u.node(nine[4], nine[5], nine[7], nine[8]), j),
+		)
+	}
+	n.result, n.resultStep = r, j
+	return r
+}
+
+// base advances the center 2x2 cells of a 4x4 node by one generation.
+func (u *Universe) base(n *Node) *Node {
+	var next [4]*Node
+	for i, p := range [4][2]int{{1, 1}, {2, 1}, {1, 2}, {2, 2}} {
+		live := 0
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if (dx != 0 || dy != 0) && cellAt(n, p[0]+dx, p[1]+dy) {
+					live++
+				}
+			}
+		}
+		next[i] = u.dead
+		if u.Rule.Next(cellAt(n, p[0], p[1]), live) {
+			next[i] = u.alive
+		}
+	}
+	return u.node(next[0], next[1], next[2], next[3])
+}
+
+// quadrant returns the quadrant of n that holds x, y, relative to the top left of n, and x, y relative to it.
+func quadrant(n *Node, x, y int) (*Node, int, int) {
+	half := 1 << uint(n.Level-1)
+	switch {
+	case x < half && y < half:
+		return n.NW, x, y
+	case y < half:
+		return n.NE, x - half, y
+	case x < half:
+		return n.SW, x, y - half
+	default:
+		return n.SE, x - half, y - half
+	}
+}
+
+// cellAt returns whether the cell at x, y relative to the top left of n is alive.
+func cellAt(n *Node, x, y int) bool {
+	for n.Level > 0 {
+		n, x, y = quadrant(n, x, y)
+	}
+	return n.Population > 0
+}
+
+// half returns the distance from the center of the root to its edges.
+func (u *Universe) half() int {
+	return 1 << uint(u.root.Level-1)
+}
+
+func (u *Universe) contains(x, y int) bool {
+	half := u.half()
+	return x >= -half && x < half && y >= -half && y < half
+}
+
+// IsAlive returns whether the cell at x, y is alive.
+func (u *Universe) IsAlive(x, y int) bool {
+	return u.NodeAt(x, y, 0).Population > 0
+}
+
+// NodeAt returns the node of the level that holds x, y, e.g. to draw the universe zoomed out. It only reads the
+// universe, which only Fill and Step grow, so the node is empty if x, y is outside the root or the level is not below
+// that of the root.
+func (u *Universe) NodeAt(x, y, level int) *Node {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	// nodes below the root line up with the nodes of a bigger root, but the root itself doesn't
+	if level >= u.root.Level || !u.contains(x, y) {
+		return u.empty(level)
+	}
+	n, half := u.root, u.half()
+	x, y = x+half, y+half
+	for n.Level > level {
+		n, x, y = quadrant(n, x, y)
+	}
+	return n
+}
+
+// SetAlive makes the cell at x, y alive or dead.
+func (u *Universe) SetAlive(x, y int, alive bool) {
+	u.Fill(x, y, 0, alive)
+}
+
+// Fill makes all cells of the node of the level that holds x, y alive or dead.
+func (u *Universe) Fill(x, y, level int, alive bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for !u.contains(x, y) || u.root.Level <= level {
+		u.root = u.expand(u.root)
+	}
+	value := u.empty(level)
+	if alive {
+		value = u.full(level)
+	}
+	half := u.half()
+	u.root = u.replace(u.root, x+half, y+half, value)
+}
+
+// replace returns n with the node that holds x, y at the level of value replaced by value.
+func (u *Universe) replace(n *Node, x, y int, value *Node) *Node {
+	if n.Level == value.Level {
+		return value
+	}
+	half := 1 << uint(n.Level-1)
+	switch {
+	case x < half && y < half:
+		return u.node(u.replace(n.NW, x, y, value), n.NE, n.SW, n.SE)
+	case y < half:
+		return u.node(n.NW, u.replace(n.NE, x-half, y, value), n.SW, n.SE)
+	case x < half:
+		return u.node(n.NW, n.NE, u.replace(n.SW, x, y-half, value), n.SE)
+	default:
+		return u.node(n.NW, n.NE, n.SW, u.replace(n.SE, x-half, y-half, value))
+	}
+}
+
+// SetRoot replaces every cell of the universe with the node, centered on the origin.
+func (u *Universe) SetRoot(root *Node) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for root.Level < minLevel {
+		root = u.expand(root)
+	}
+	u.root = root
+}
+
+// collect drops the nodes that are no longer part of the universe. Memoized results survive if their nodes do.
+func (u *Universe) collect() {
+	kept := make(map[quad]*Node, len(u.nodes)/2)
+	var mark func(n *Node)
+	mark = func(n *Node) {
+		if n.Level == 0 {
+			return
+		}
+		key := quad{n.NW, n.NE, n.SW, n.SE}
+		if _, ok := kept[key]; ok {
+			return
+		}
+		kept[key] = n
+		mark(n.NW)
+		mark(n.NE)
+		mark(n.SW)
+		mark(n.SE)
+	}
+	mark(u.root)
+	for _, e := range u.empties {
+		mark(e)
+	}
+	for _, n := range kept {
+		if r := n.result; r != nil && r.Level > 0 && kept[quad{r.NW, r.NE, r.SW, r.SE}] != r {
+			n.result = nil
+		}
+	}
+	u.nodes = kept
+}
+
+// Nodes returns the number of nodes the universe holds.
+func (u *Universe) Nodes() int {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return len(u.nodes)
+}
@@ -0,0 +1,88 @@
+package hashlife
+
+import (
+	"github.com/jpbetz/cellularautomata/grid"
+)
+
+// View is a window onto a universe that implements grid.Plane, so it can be drawn and edited like any other board.
+// Zoomed out, every cell of the view shows a square of 2^Zoom by 2^Zoom cells of the universe, alive if any of them is.
+type View struct {
+	Universe *Universe
+	W, H     int
+	// Left and Top are the universe coordinates of the top left cell of the view
+	Left, Top int
+	Zoom      int
+	Alive     grid.Cell
+	Dead      grid.Cell
+
+	// whether each cell was alive when last drawn, to find what changed
+	shown []bool
+}
+
+// NewView returns a view of w by h cells centered on the origin.
+func NewView(u *Universe, w, h int, alive, dead grid.Cell) *View {
+	return &View{Universe: u, W: w, H: h, Left: -w / 2, Top: -h / 2, Alive: alive, Dead: dead, shown: make([]bool, w*h)}
+}
+
+func (v *View) node(p grid.Position) *Node {
+	return v.Universe.NodeAt(v.Left+p.X<<uint(v.Zoom), v.Top+p.Y<<uint(v.Zoom), v.Zoom)
+}
+
+func (v *View) Get(p grid.Position) grid.Cell {
+	if v.node(p).Population > 0 {
+		return v.Alive
+	}
+	return v.Dead
+}
+
+func (v *View) GetNeighborPositions(p grid.Position, neighborhood grid.Neighborhood) []grid.Position {
+	return neighborhood.Positions(p, v.Bounds())
+}
+
+func (v *View) GetNeighbors(p grid.Position, neighborhood grid.Neighborhood) []grid.Cell {
+	neighbors := make([]grid.Cell, 0, len(neighborhood.Offsets))
+	for _, neighborPosition := range v.GetNeighborPositions(p, neighborhood) {
+		neighbors = append(neighbors, v.Get(neighborPosition))
+	}
+	return neighbors
+}
+
+// Set makes all the universe cells shown by the view cell alive if the cell equals Alive, and dead otherwise.
+func (v *View) Set(p grid.Position, cell grid.Cell) {
+	v.Universe.Fill(v.Left+p.X<<uint(v.Zoom), v.Top+p.Y<<uint(v.Zoom), v.Zoom, cell == v.Alive)
+	v.shown[p.Y*v.W+p.X] = cell == v.Alive
+}
+
+func (v *View) Bounds() grid.Rectangle {
+	return grid.Rectangle{Corner1: grid.Origin, Corner2: grid.Position{X: v.W - 1, Y: v.H - 1}}
+}
+
+// SetZoom changes the zoom level, keeping the center of the view in place.
+func (v *View) SetZoom(zoom int) {
+	if zoom < 0 {
+		zoom = 0
+	}
+	if zoom > 60 {
+		zoom = 60
+	}
+	centerX, centerY := v.Left+(v.W<<uint(v.Zoom))/2, v.Top+(v.H<<uint(v.Zoom))/2
+	v.Zoom = zoom
+	v.Left, v.Top = centerX-(v.W<<uint(zoom))/2, centerY-(v.H<<uint(zoom))/2
+}
+
+// Changes returns the cells of the view that changed since they were last returned, or since the view was created, or
+// that show something else after the view moved or zoomed.
+func (v *View) Changes() []grid.Position {
+	changes := []grid.Position{}
+	for y := 0; y < v.H; y++ {
+		for x := 0; x < v.W; x++ {
+			p := grid.Position{X: x, Y: y}
+			alive := v.node(p).Population > 0
+			if alive != v.shown[y*v.W+x] {
+				v.shown[y*v.W+x] = alive
+				changes = append(changes, p)
+			}
+		}
+	}
+	return changes
+}
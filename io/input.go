@@ -31,3 +31,12 @@ type Save struct{}
 func (Save) EventName() string {
 	return "Save"
 }
+
+// Key is emitted for key presses that have no dedicated event, so commands can bind their own controls.
+type Key struct {
+	Ch rune
+}
+
+func (Key) EventName() string {
+	return "Key"
+}
@@ -13,9 +13,69 @@ type Renderer interface {
 	Set(position grid.Position, change grid.Cell)
 	Draw()
 	SetStatus(msg string)
+	SetOverlay(name string, cells map[grid.Position]grid.Cell)
 }
 
+// NullRenderer draws nothing and has no input, for headless runs.
+type NullRenderer struct{}
+
+func (r NullRenderer) Input() chan InputEvent                                    { return nil }
+func (r NullRenderer) Run()                                                      {}
+func (r NullRenderer) Loop(done <-chan bool)                                     { <-done }
+func (r NullRenderer) Close()                                                    {}
+func (r NullRenderer) SetView(view *View)                                        {}
+func (r NullRenderer) Set(position grid.Position, change grid.Cell)              {}
+func (r NullRenderer) Draw()                                                     {}
+func (r NullRenderer) SetStatus(msg string)                                      {}
+func (r NullRenderer) SetOverlay(name string, cells map[grid.Position]grid.Cell) {}
+
 type View struct {
 	Plane  grid.Plane
 	Offset grid.Position
 }
+
+// Overlays are drawn over the cells of the view without changing them, e.g. to show routes. Each overlay is
+// identified by name, later overlays are drawn over earlier ones, and setting an overlay to nil removes it.
+type Overlays struct {
+	names []string
+	cells map[string]map[grid.Position]grid.Cell
+}
+
+// Set replaces the named overlay and returns the positions that need to be redrawn.
+func (o *Overlays) Set(name string, cells map[grid.Position]grid.Cell) []grid.Position {
+	if o.cells == nil {
+		o.cells = make(map[string]map[grid.Position]grid.Cell)
+	}
+	changed := make([]grid.Position, 0, len(o.cells[name])+len(cells))
+	for p := range o.cells[name] {
+		changed = append(changed, p)
+	}
+	for p := range cells {
+		changed = append(changed, p)
+	}
+	if _, ok := o.cells[name]; !ok {
+		o.names = append(o.names, name)
+	}
+	if cells == nil {
+		delete(o.cells, name)
+		for i, n := range o.names {
+			if n == name {
+				o.names = append(o.names[:i], o.names[i+1:]...)
+				break
+			}
+		}
+	} else {
+		o.cells[name] = cells
+	}
+	return changed
+}
+
+// Get returns the top most overlay cell at the position, if any.
+func (o *Overlays) Get(position grid.Position) (grid.Cell, bool) {
+	for i := len(o.names) - 1; i >= 0; i-- {
+		if cell, ok := o.cells[o.names[i]][position]; ok {
+			return cell, true
+		}
+	}
+	return nil, false
+}
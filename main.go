@@ -1,13 +1,30 @@
 package main
 
 import (
+	"flag"
+	"github.com/jpbetz/cellularautomata/api"
 	"github.com/jpbetz/cellularautomata/apps/conway"
+	"github.com/jpbetz/cellularautomata/apps/cyclic"
+	"github.com/jpbetz/cellularautomata/apps/forestfire"
+	"github.com/jpbetz/cellularautomata/apps/generations"
+	"github.com/jpbetz/cellularautomata/apps/greenberghastings"
 	"github.com/jpbetz/cellularautomata/apps/guardduty"
 	"github.com/jpbetz/cellularautomata/apps/langton"
+	"github.com/jpbetz/cellularautomata/apps/largerthanlife"
+	"github.com/jpbetz/cellularautomata/apps/lenia"
+	"github.com/jpbetz/cellularautomata/apps/margolus"
+	"github.com/jpbetz/cellularautomata/apps/ruletable"
+	"github.com/jpbetz/cellularautomata/apps/sandbox"
+	"github.com/jpbetz/cellularautomata/apps/sandpile"
+	"github.com/jpbetz/cellularautomata/apps/search"
 	"github.com/jpbetz/cellularautomata/apps/wireworld"
+	"github.com/jpbetz/cellularautomata/apps/wolfram"
 	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/monitor"
 	"github.com/jpbetz/cellularautomata/sdlui"
+	"github.com/jpbetz/cellularautomata/webui"
 	"github.com/mitchellh/cli"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
@@ -24,15 +41,65 @@ func main() {
 	c := cli.NewCLI("cellular", "1.0.0")
 	c.Args = os.Args[1:]
 
+	// global options come before the command, and anything else is left for the command line interface to handle
+	globals := flag.NewFlagSet("cellular", flag.ContinueOnError)
+	globals.SetOutput(ioutil.Discard)
+	debugAddr := globals.String("debug-addr", "", "")
+	apiAddr := globals.String("api-addr", "", "")
+	webAddr := globals.String("web-addr", "", "")
+	if err := globals.Parse(c.Args); err == nil {
+		c.Args = globals.Args()
+	}
+	basicHelp := cli.BasicHelpFunc("cellular")
+	c.HelpFunc = func(commands map[string]cli.CommandFactory) string {
+		return basicHelp(commands) + `
+
+Global options, given before the command:
+    --debug-addr=ADDR    Serve pprof profiles and a metrics page of the running simulation at an address such as
+                         localhost:6060.
+    --api-addr=ADDR      Serve an HTTP/JSON API to control the running simulation at an address such as
+                         localhost:7070: pause, resume, step, set the speed, read and write cells, load and save RLE
+                         patterns, save the board, and fetch it as JSON or PNG.
+    --web-addr=ADDR      Draw the simulation in a browser, on a page served at an address such as localhost:8080,
+                         instead of in a window.`
+	}
+	if *debugAddr != "" {
+		addr, err := monitor.Serve(*debugAddr)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Serving pprof and metrics at http://%s/\n", addr)
+	}
+
 	input := make(chan io.InputEvent, 10)
-	ui := sdlui.NewSdlUi(
-		input,
-		intEnvOrDefault("WIDTH", 60),
-		intEnvOrDefault("HEIGHT", 40),
-		intEnvOrDefault("CWIDTH", 15),
-		intEnvOrDefault("CHEIGHT", 15),
-		intEnvOrDefault("CBORDER", 1),
-	)
+	if *apiAddr != "" {
+		addr, err := api.Serve(*apiAddr, input)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Serving the control API at http://%s/\n", addr)
+	}
+	var ui io.Renderer
+	if *webAddr != "" {
+		web, err := webui.NewWebUI(input, *webAddr)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Drawing at http://%s/\n", web.Addr())
+		ui = web
+	} else {
+		ui = sdlui.NewSdlUi(
+			input,
+			intEnvOrDefault("WIDTH", 60),
+			intEnvOrDefault("HEIGHT", 40),
+			intEnvOrDefault("CWIDTH", 15),
+			intEnvOrDefault("CHEIGHT", 15),
+			intEnvOrDefault("CBORDER", 1),
+		)
+	}
 	defer ui.Close()
 
 	c.Commands = map[string]cli.CommandFactory{
@@ -56,6 +123,64 @@ func main() {
 				UI: ui,
 			}, nil
 		},
+		"generations": func() (cli.Command, error) {
+			return &generations.GenerationsCommand{
+				UI: ui,
+			}, nil
+		},
+		"wolfram": func() (cli.Command, error) {
+			return &wolfram.WolframCommand{
+				UI: ui,
+			}, nil
+		},
+		"ruletable": func() (cli.Command, error) {
+			return &ruletable.RuleTableCommand{
+				UI: ui,
+			}, nil
+		},
+		"ltl": func() (cli.Command, error) {
+			return &largerthanlife.LargerThanLifeCommand{
+				UI: ui,
+			}, nil
+		},
+		"lenia": func() (cli.Command, error) {
+			return &lenia.LeniaCommand{
+				UI: ui,
+			}, nil
+		},
+		"forestfire": func() (cli.Command, error) {
+			return &forestfire.ForestFireCommand{
+				UI: ui,
+			}, nil
+		},
+		"sandpile": func() (cli.Command, error) {
+			return &sandpile.SandpileCommand{
+				UI: ui,
+			}, nil
+		},
+		"cyclic": func() (cli.Command, error) {
+			return &cyclic.CyclicCommand{
+				UI: ui,
+			}, nil
+		},
+		"greenberghastings": func() (cli.Command, error) {
+			return &greenberghastings.GreenbergHastingsCommand{
+				UI: ui,
+			}, nil
+		},
+		"margolus": func() (cli.Command, error) {
+			return &margolus.MargolusCommand{
+				UI: ui,
+			}, nil
+		},
+		"sandbox": func() (cli.Command, error) {
+			return &sandbox.SandboxCommand{
+				UI: ui,
+			}, nil
+		},
+		"search": func() (cli.Command, error) {
+			return &search.SearchCommand{}, nil
+		},
 	}
 
 	exitStatus, err := c.Run()
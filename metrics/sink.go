@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jpbetz/cellularautomata/engine"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Format is how a Sink writes stats.
+type Format int
+
+const (
+	// CSV writes a header row of column names, then a row per generation.
+	CSV Format = iota
+	// JSONLines writes an object per generation, one per line.
+	JSONLines
+)
+
+// Sink is an engine.Observer that writes the stats of every generation, for analysis in other tools.
+type Sink struct {
+	format Format
+	out    *bufio.Writer
+	closer io.Closer
+	csv    *csv.Writer
+	// the metrics of the handler in the order of the columns, from the first generation written
+	metrics []string
+
+	mu sync.Mutex
+	// the first error writing, after which nothing more is written
+	err error
+}
+
+// NewSink returns a sink writing to w in the format.
+func NewSink(w io.Writer, format Format) *Sink {
+	s := &Sink{format: format, out: bufio.NewWriter(w)}
+	if format == CSV {
+		s.csv = csv.NewWriter(s.out)
+	}
+	return s
+}
+
+// Open creates the file of the -metrics-out flag of a command, truncating it if it exists, and returns a sink writing
+// to it, or nil if the path is empty. Paths ending in .csv are written as CSV and all others as JSON lines.
+func Open(path string) (*Sink, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	format := JSONLines
+	if strings.HasSuffix(strings.ToLower(path), ".csv") {
+		format = CSV
+	}
+	s := NewSink(f, format)
+	s.closer = f
+	return s, nil
+}
+
+// Usage describes the -metrics-out flag, for the help of the commands that add it with Flag. It is indented like the
+// other options of most commands, ending without a newline so it can be the last of them.
+const Usage = `  -metrics-out=FILE
+                Write the stats of every generation to the file, as CSV if it ends in .csv and as JSON lines otherwise.`
+
+// Flag adds the -metrics-out flag to the flags of a command. Once the flags are parsed, the function it returns runs
+// the command with the sink of the flag and closes the sink after. It prints the error and returns 1 if the file can't
+// be created or the stats couldn't all be written to it.
+func Flag(flags *flag.FlagSet) func(run func(sink *Sink)) int {
+	path := flags.String("metrics-out", "", "")
+	return func(run func(sink *Sink)) int {
+		sink, err := Open(*path)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		run(sink)
+		if err := sink.Close(); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		return 0
+	}
+}
+
+// Observe adds the sink to the observers of the engine, if the sink is not nil.
+func (s *Sink) Observe(e *engine.Engine) {
+	if s != nil {
+		e.Observers = append(e.Observers, s)
+	}
+}
+
+var columns = []string{"generation", "updates", "population", "births", "deaths", "active_area", "duration_ms"}
+
+func (s *Sink) ObserveGeneration(stats engine.Stats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	if s.metrics == nil {
+		s.metrics = []string{}
+		for name := range stats.Metrics {
+			s.metrics = append(s.metrics, name)
+		}
+		sort.Strings(s.metrics)
+		if s.format == CSV {
+			s.err = s.csv.Write(append(append([]string{}, columns...), s.metrics...))
+		}
+	}
+	if s.err == nil {
+		s.err = s.write(stats)
+	}
+	// flushed every generation so that runs can be followed while they go on
+	if s.csv != nil {
+		s.csv.Flush()
+		if s.err == nil {
+			s.err = s.csv.Error()
+		}
+	}
+	if err := s.out.Flush(); s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *Sink) write(stats engine.Stats) error {
+	values := []float64{
+		float64(stats.Generation),
+		float64(stats.Updates),
+		float64(stats.Population),
+		float64(stats.Births),
+		float64(stats.Deaths),
+		float64(stats.ActiveArea),
+		stats.Duration.Seconds() * 1000,
+	}
+	for _, name := range s.metrics {
+		values = append(values, stats.Metrics[name])
+	}
+
+	if s.format == CSV {
+		record := make([]string, len(values))
+		for i, value := range values {
+			record[i] = strconv.FormatFloat(value, 'g', -1, 64)
+		}
+		return s.csv.Write(record)
+	}
+	// written by hand to keep the columns in order
+	fmt.Fprint(s.out, "{")
+	for i, value := range values {
+		name := ""
+		if i < len(columns) {
+			name = columns[i]
+		} else {
+			name = s.metrics[i-len(columns)]
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprint(s.out, ",")
+		}
+		fmt.Fprintf(s.out, "%s:%s", key, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	_, err := fmt.Fprintln(s.out, "}")
+	return err
+}
+
+// Close flushes the stats written so far and closes the file of the sink, and returns the first error writing.
+// Generations observed after Close are not written.
+func (s *Sink) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.csv != nil {
+		s.csv.Flush()
+		if s.err == nil {
+			s.err = s.csv.Error()
+		}
+	}
+	if err := s.out.Flush(); s.err == nil {
+		s.err = err
+	}
+	if s.closer != nil {
+		if err := s.closer.Close(); s.err == nil {
+			s.err = err
+		}
+		s.closer = nil
+	}
+	err := s.err
+	if s.err == nil {
+		s.err = fmt.Errorf("metrics sink is closed")
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/jpbetz/cellularautomata/engine"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var generations = []engine.Stats{
+	{Generation: 1, Updates: 4, Population: 10, Births: 3, Deaths: 1, ActiveArea: 6, Duration: 1500 * time.Microsecond,
+		Metrics: map[string]float64{"replans": 2, "path_length": 15}},
+	{Generation: 2, Updates: 1, Population: 9, Deaths: 1, ActiveArea: 1, Duration: time.Millisecond,
+		Metrics: map[string]float64{"replans": 0, "path_length": 0}},
+}
+
+func TestCSV(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewSink(&out, CSV)
+	for _, stats := range generations {
+		sink.ObserveGeneration(stats)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "generation,updates,population,births,deaths,active_area,duration_ms,path_length,replans\n" +
+		"1,4,10,3,1,6,1.5,15,2\n" +
+		"2,1,9,0,1,1,1,0,0\n"
+	if out.String() != expected {
+		t.Errorf("Expected\n%s but was\n%s", expected, out.String())
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewSink(&out, JSONLines)
+	for _, stats := range generations {
+		sink.ObserveGeneration(stats)
+	}
+	sink.Close()
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a line per generation but was %q", out.String())
+	}
+	if !strings.HasPrefix(lines[0], `{"generation":1,"updates":4,`) {
+		t.Errorf("Expected the columns in order but was %s", lines[0])
+	}
+	values := map[string]float64{}
+	if err := json.Unmarshal([]byte(lines[0]), &values); err != nil {
+		t.Fatal(err)
+	}
+	if values["duration_ms"] != 1.5 || values["replans"] != 2 || values["births"] != 3 {
+		t.Errorf("Expected the stats of generation 1 but was %v", values)
+	}
+}
+
+func TestClosedSinkIgnoresGenerations(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewSink(&out, JSONLines)
+	sink.Close()
+	sink.ObserveGeneration(generations[0])
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing written after Close but was %q", out.String())
+	}
+	var none *Sink
+	if err := none.Close(); err != nil {
+		t.Errorf("Expected closing a nil sink to do nothing but was %v", err)
+	}
+}
+
+func TestFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.csv")
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	withMetrics := Flag(flags)
+	if err := flags.Parse([]string{"-metrics-out=" + path}); err != nil {
+		t.Fatal(err)
+	}
+	code := withMetrics(func(sink *Sink) {
+		sink.ObserveGeneration(generations[0])
+	})
+	if code != 0 {
+		t.Fatalf("Expected the command to run but it returned %d", code)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "generation,") || strings.Count(string(data), "\n") != 2 {
+		t.Errorf("Expected the sink to be closed with the generation written but the file was %q", data)
+	}
+
+	flags = flag.NewFlagSet("test", flag.ContinueOnError)
+	withMetrics = Flag(flags)
+	flags.Parse([]string{"-metrics-out=" + filepath.Join(path, "stats.csv")})
+	ran := false
+	if code := withMetrics(func(sink *Sink) { ran = true }); code != 1 || ran {
+		t.Errorf("Expected a file that can't be created to fail the command but it returned %d", code)
+	}
+
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full to fail writes to")
+	}
+	flags = flag.NewFlagSet("test", flag.ContinueOnError)
+	withMetrics = Flag(flags)
+	flags.Parse([]string{"-metrics-out=/dev/full"})
+	if code := withMetrics(func(sink *Sink) { sink.ObserveGeneration(generations[0]) }); code != 1 {
+		t.Errorf("Expected a failed write to fail the command but it returned %d", code)
+	}
+}
@@ -0,0 +1,210 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metric is a value shown on the metrics page, in the text format read by Prometheus.
+type Metric interface {
+	Name() string
+	Help() string
+	// Type is counter, for values that only go up, or gauge
+	Type() string
+	Value() float64
+}
+
+var registry = struct {
+	sync.Mutex
+	metrics []Metric
+}{}
+
+// Register adds the metric to the metrics page, after the metrics registered before it, or replaces the metric of the
+// same name. Metrics are typically package variables, updated for the life of the process whether the page is served
+// or not.
+func Register(metric Metric) {
+	registry.Lock()
+	defer registry.Unlock()
+	for i, m := range registry.metrics {
+		if m.Name() == metric.Name() {
+			registry.metrics[i] = metric
+			return
+		}
+	}
+	registry.metrics = append(registry.metrics, metric)
+}
+
+// Metrics returns the registered metrics in the order they were registered.
+func Metrics() []Metric {
+	registry.Lock()
+	defer registry.Unlock()
+	return append([]Metric{}, registry.metrics...)
+}
+
+// WriteText writes every registered metric in the Prometheus text format.
+func WriteText(w io.Writer) error {
+	for _, m := range Metrics() {
+		value := m.Value()
+		if math.IsNaN(value) {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.Name(), m.Help(), m.Name(), m.Type(),
+			m.Name(), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type description struct {
+	name, help string
+}
+
+func (d description) Name() string {
+	return d.name
+}
+
+func (d description) Help() string {
+	return d.help
+}
+
+// Counter is a count that only goes up, such as the number of generations computed. It is safe for concurrent use.
+type Counter struct {
+	description
+	value int64
+}
+
+func NewCounter(name, help string) *Counter {
+	c := &Counter{description: description{name, help}}
+	Register(c)
+	return c
+}
+
+func (c *Counter) Add(n int64) {
+	atomic.AddInt64(&c.value, n)
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+func (c *Counter) Type() string {
+	return "counter"
+}
+
+func (c *Counter) Value() float64 {
+	return float64(atomic.LoadInt64(&c.value))
+}
+
+// Seconds is a counter of time spent, such as in the update handlers.
+type Seconds struct {
+	description
+	nanos int64
+}
+
+func NewSeconds(name, help string) *Seconds {
+	s := &Seconds{description: description{name, help}}
+	Register(s)
+	return s
+}
+
+func (s *Seconds) Add(d time.Duration) {
+	atomic.AddInt64(&s.nanos, int64(d))
+}
+
+func (s *Seconds) Type() string {
+	return "counter"
+}
+
+func (s *Seconds) Value() float64 {
+	return time.Duration(atomic.LoadInt64(&s.nanos)).Seconds()
+}
+
+// Gauge is a value that goes up and down, read from a function whenever the page is served.
+type Gauge struct {
+	description
+	read func() float64
+}
+
+// NewGauge registers a gauge that reads its value from the function, which must be safe to call from the goroutine
+// serving the page. It may return NaN to leave the gauge out.
+func NewGauge(name, help string, read func() float64) *Gauge {
+	g := &Gauge{description: description{name, help}, read: read}
+	Register(g)
+	return g
+}
+
+func (g *Gauge) Type() string {
+	return "gauge"
+}
+
+func (g *Gauge) Value() float64 {
+	return g.read()
+}
+
+// Rate is a gauge of events per second, such as generations per second, over the last events within its window.
+type Rate struct {
+	description
+	window time.Duration
+
+	mu    sync.Mutex
+	times []time.Time
+	next  int
+	// the clock, replaced in tests
+	now func() time.Time
+}
+
+// rateEvents is the number of events a rate remembers.
+const rateEvents = 64
+
+func NewRate(name, help string, window time.Duration) *Rate {
+	r := &Rate{description: description{name, help}, window: window, now: time.Now}
+	Register(r)
+	return r
+}
+
+// Mark records an event.
+func (r *Rate) Mark() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.times) < rateEvents {
+		r.times = append(r.times, r.now())
+	} else {
+		r.times[r.next] = r.now()
+		r.next = (r.next + 1) % rateEvents
+	}
+}
+
+func (r *Rate) Type() string {
+	return "gauge"
+}
+
+// Value returns the events per second between the oldest and the newest of the remembered events within the window,
+// or 0 if there have been fewer than 2.
+func (r *Rate) Value() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	var oldest, newest time.Time
+	count := 0
+	for _, t := range r.times {
+		if now.Sub(t) > r.window {
+			continue
+		}
+		if count == 0 || t.Before(oldest) {
+			oldest = t
+		}
+		if count == 0 || t.After(newest) {
+			newest = t
+		}
+		count++
+	}
+	if count < 2 || !newest.After(oldest) {
+		return 0
+	}
+	return float64(count-1) / newest.Sub(oldest).Seconds()
+}
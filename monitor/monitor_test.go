@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteText(t *testing.T) {
+	counter := NewCounter("test_events_total", "Events counted.")
+	counter.Add(2)
+	counter.Inc()
+	seconds := NewSeconds("test_busy_seconds_total", "Time spent busy.")
+	seconds.Add(1500 * time.Millisecond)
+	NewGauge("test_depth", "Items waiting.", func() float64 { return 7 })
+	NewGauge("test_hidden", "Not shown.", func() float64 { return math.NaN() })
+
+	var out bytes.Buffer
+	if err := WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	for _, expected := range []string{
+		"# HELP test_events_total Events counted.\n# TYPE test_events_total counter\ntest_events_total 3\n",
+		"# TYPE test_busy_seconds_total counter\ntest_busy_seconds_total 1.5\n",
+		"# TYPE test_depth gauge\ntest_depth 7\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected the metrics page to contain\n%s but was\n%s", expected, text)
+		}
+	}
+	if strings.Contains(text, "test_hidden") {
+		t.Errorf("Expected gauges without a value to be left out but was\n%s", text)
+	}
+	if strings.Index(text, "test_events_total") > strings.Index(text, "test_depth") {
+		t.Errorf("Expected metrics in the order they were registered")
+	}
+}
+
+func TestRegisterReplaces(t *testing.T) {
+	NewGauge("test_replaced", "First.", func() float64 { return 1 })
+	NewGauge("test_replaced", "Second.", func() float64 { return 2 })
+	found := 0
+	for _, m := range Metrics() {
+		if m.Name() == "test_replaced" {
+			found++
+			if m.Value() != 2 {
+				t.Errorf("Expected the metric registered last but was %s", m.Help())
+			}
+		}
+	}
+	if found != 1 {
+		t.Errorf("Expected a metric once per name but found it %d times", found)
+	}
+}
+
+func TestRate(t *testing.T) {
+	rate := NewRate("test_rate", "Events per second.", 10*time.Second)
+	now := time.Unix(1000, 0)
+	rate.now = func() time.Time { return now }
+	if rate.Value() != 0 {
+		t.Errorf("Expected no rate without events but was %g", rate.Value())
+	}
+	for i := 0; i < 200; i++ {
+		rate.Mark()
+		now = now.Add(250 * time.Millisecond)
+	}
+	if rate.Value() != 4 {
+		t.Errorf("Expected 4 events per second but was %g", rate.Value())
+	}
+	// the events fall out of the window
+	now = now.Add(time.Minute)
+	if rate.Value() != 0 {
+		t.Errorf("Expected no rate once the events are older than the window but was %g", rate.Value())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	NewCounter("test_served_total", "Served.").Inc()
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+
+	for path, expected := range map[string]string{
+		"/metrics":      "test_served_total 1",
+		"/debug/pprof/": "goroutine",
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), expected) {
+			t.Errorf("Expected %s to serve %q but was %d %s", path, expected, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	addr, err := Serve("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the metrics page at %s but was %d", addr, resp.StatusCode)
+	}
+}
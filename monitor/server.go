@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// Handler serves the metrics page at /metrics and the profiles of net/http/pprof under /debug/pprof/.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := WriteText(w); err != nil {
+			log.Printf("Failed to write metrics: %v\n", err)
+		}
+	})
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "cellular debug server\n\n/metrics\n/debug/pprof/\n")
+	})
+	return mux
+}
+
+// Serve listens on the address, e.g. localhost:6060, and serves the Handler in the background. It returns the address
+// listened on, which has the port chosen if the address has port 0.
+func Serve(addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := http.Serve(listener, Handler()); err != nil {
+			log.Printf("Debug server stopped: %v\n", err)
+		}
+	}()
+	return listener.Addr(), nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/jpbetz/cellularautomata/grid"
 	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/monitor"
 	"github.com/nsf/termbox-go"
 	"github.com/veandco/go-sdl2/sdl"
 	sdlfont "github.com/veandco/go-sdl2/sdl_ttf"
@@ -18,15 +19,26 @@ type UIUpdate struct {
 type UIRefresh struct {
 }
 
+type UIOverlay struct {
+	Name  string
+	Cells map[grid.Position]grid.Cell
+}
+
 type SdlUi struct {
 	UpdateCh chan interface{}
 	window   *sdl.Window
 	surface  *sdl.Surface
 
-	cells []*sdl.Rect
+	// the rectangles that make up the shape of each cell, a single square for square planes
+	cells      [][]sdl.Rect
+	cellBorder int32
 
 	// UI
-	View *io.View
+	View     *io.View
+	overlays io.Overlays
+	// the colors of the cells as last set, to draw again when an overlay over them is cleared, without reading the
+	// plane, which the engine writes from its own goroutine
+	colors []uint32
 
 	// IO
 	input chan io.InputEvent
@@ -69,19 +81,30 @@ func NewSdlUi(input chan io.InputEvent, w, h int32, cellW, cellH int32, cellBord
 		Height:     h,
 		CellWidth:  cellW,
 		CellHeight: cellH,
+		cellBorder: cellBorder,
 	}
-
-	s.cells = make([]*sdl.Rect, w*h)
+	// the engine blocks on Set and Draw while the queue is full
+	monitor.NewGauge("cellular_ui_queue_depth", "Updates waiting for the SDL renderer to draw them.", func() float64 {
+		return float64(len(s.UpdateCh))
+	})
+	monitor.NewGauge("cellular_ui_queue_capacity", "Updates the queue of the SDL renderer holds before it blocks.",
+		func() float64 {
+			return float64(cap(s.UpdateCh))
+		})
+
+	s.cells = make([][]sdl.Rect, w*h)
+	s.colors = make([]uint32, w*h)
 	for i := int32(0); i < w; i++ {
 		for j := int32(0); j < h; j++ {
-			rect := &sdl.Rect{
+			rect := sdl.Rect{
 				i * cellW + cellBorder,
 				j * cellH + cellBorder,
 				cellW - cellBorder*2,
 				cellH - cellBorder*2,
 			}
-			s.cells[s.pos(int(i), int(j))] = rect
-			surface.FillRect(rect, toHex(termbox.ColorDefault))
+			s.cells[s.pos(int(i), int(j))] = []sdl.Rect{rect}
+			s.colors[s.pos(int(i), int(j))] = toHex(termbox.ColorDefault)
+			surface.FillRect(&rect, toHex(termbox.ColorDefault))
 		}
 	}
 
@@ -92,6 +115,54 @@ func NewSdlUi(input chan io.InputEvent, w, h int32, cellW, cellH int32, cellBord
 
 func (s *SdlUi) SetView(view *io.View) {
 	s.View = view
+	if grid.IsHex(view.Plane) {
+		s.layoutHexCells()
+	}
+}
+
+// layoutHexCells replaces the square cells with pointy topped hexagons, built from one rectangle per row of pixels.
+// Odd rows are shifted right by half a cell, and the tips of each row fit between the tips of the rows above and
+// below it, so hexagons are a third taller than the rows they are stacked in.
+func (s *SdlUi) layoutHexCells() {
+	s.surface.FillRect(&sdl.Rect{0, 0, s.Width * s.CellWidth, s.Height * s.CellHeight}, 0)
+	hexHeight := s.CellHeight * 4 / 3
+	tip := hexHeight / 4
+	for i := int32(0); i < s.Width; i++ {
+		for j := int32(0); j < s.Height; j++ {
+			left := i*s.CellWidth + (j%2)*s.CellWidth/2 + s.cellBorder
+			top := j*s.CellHeight - tip/2
+			width := s.CellWidth - s.cellBorder*2
+			rows := make([]sdl.Rect, 0, hexHeight)
+			for y := int32(0); y < hexHeight-s.cellBorder; y++ {
+				// how far into a tip the row is, from 0 at the point to tip where the sides become vertical
+				inset := int32(0)
+				if y < tip {
+					inset = (tip - y) * width / 2 / tip
+				} else if y >= hexHeight-tip {
+					inset = (y - (hexHeight - tip) + 1) * width / 2 / tip
+				}
+				// tips beyond the edges of the board would draw over the status line
+				if width-inset*2 > 0 && top+y >= 0 && top+y < s.Height*s.CellHeight {
+					rows = append(rows, sdl.Rect{left + inset, top + y, width - inset*2, 1})
+				}
+			}
+			s.cells[s.pos(int(i), int(j))] = rows
+			s.UpdateCell(grid.Position{int(i), int(j)}, toHex(termbox.ColorDefault))
+		}
+	}
+	s.Refresh()
+}
+
+// cellAt returns the position of the cell under a pixel of the window.
+func (s *SdlUi) cellAt(x, y int32) grid.Position {
+	row := y / s.CellHeight
+	if s.View != nil && grid.IsHex(s.View.Plane) && row%2 == 1 {
+		x -= s.CellWidth / 2
+		if x < 0 {
+			return grid.Position{-1, int(row)}
+		}
+	}
+	return grid.Position{int(x / s.CellWidth), int(row)}
 }
 
 func (s *SdlUi) Run() {
@@ -115,14 +186,14 @@ func (s *SdlUi) Loop(done <-chan bool) {
 				//log.Printf("[%d ms] MouseButton\ttype:%d\tid:%d\tx:%d\ty:%d\tbutton:%d\tstate:%d\n",
 				//	t.Timestamp, t.Type, t.Which, t.X, t.Y, t.Button, t.State)
 				if t.Button == 1 && t.State&sdl.BUTTON_LEFT > 0 {
-					s.input <- io.Click{Position: grid.Position{int(t.X) / int(s.CellWidth), int(t.Y) / int(s.CellHeight)}}
+					s.input <- io.Click{Position: s.cellAt(t.X, t.Y)}
 				}
 			case *sdl.MouseMotionEvent:
 				//log.Printf("[%d ms] MouseMotion\ttype:%d\tid:%d\tx:%d\ty:%d\ttxrel:%d\ttyrel:%d\tstate:%d\n",
 				//	t.Timestamp, t.Type, t.Which, t.X, t.Y, t.XRel, t.YRel, t.State)
 
-				newPosition := grid.Position{int(t.X) / int(s.CellWidth), int(t.Y) / int(s.CellHeight)}
-				if t.State&sdl.BUTTON_LEFT > 0 && newPosition != *lastMousePosition {
+				newPosition := s.cellAt(t.X, t.Y)
+				if t.State&sdl.BUTTON_LEFT > 0 && (lastMousePosition == nil || newPosition != *lastMousePosition) {
 					s.input <- io.Click{Position: newPosition}
 				}
 				lastMousePosition = &newPosition
@@ -137,7 +208,9 @@ func (s *SdlUi) Loop(done <-chan bool) {
 				case 'q':
 					s.input <- io.Quit{}
 				default:
-					// do nothing
+					if t.Keysym.Sym > ' ' && t.Keysym.Sym < 0x7f {
+						s.input <- io.Key{Ch: rune(t.Keysym.Sym)}
+					}
 				}
 			default:
 				// do nothing
@@ -150,7 +223,14 @@ func (s *SdlUi) Loop(done <-chan bool) {
 			case UIRefresh:
 				s.Refresh()
 			case UIUpdate:
-				s.UpdateCell(update.Position, update.Color)
+				if i := s.pos(update.Position.X, update.Position.Y); i >= 0 && i < len(s.colors) {
+					s.colors[i] = update.Color
+				}
+				if _, ok := s.overlays.Get(update.Position); !ok {
+					s.UpdateCell(update.Position, update.Color)
+				}
+			case UIOverlay:
+				s.applyOverlay(update.Name, update.Cells)
 			}
 		case <-done:
 			log.Println("Done event recieved. Exiting Loop.")
@@ -169,8 +249,20 @@ func (s *SdlUi) UpdateCell(position grid.Position, hexColor uint32) {
 	if s.pos(position.X, position.Y) >= len(s.cells) {
 		return
 	}
-	rect := s.cells[s.pos(position.X, position.Y)]
-	s.surface.FillRect(rect, hexColor)
+	for i := range s.cells[s.pos(position.X, position.Y)] {
+		s.surface.FillRect(&s.cells[s.pos(position.X, position.Y)][i], hexColor)
+	}
+}
+
+func (s *SdlUi) applyOverlay(name string, cells map[grid.Position]grid.Cell) {
+	for _, position := range s.overlays.Set(name, cells) {
+		if cell, ok := s.overlays.Get(position); ok {
+			s.UpdateCell(position, cellHex(cell))
+		} else if i := s.pos(position.X, position.Y); i >= 0 && i < len(s.colors) {
+			s.UpdateCell(position, s.colors[i])
+		}
+	}
+	s.Refresh()
 }
 
 func (s *SdlUi) handleInput() {
@@ -200,25 +292,15 @@ func (s *SdlUi) Close() {
 }
 
 func (s *SdlUi) Set(position grid.Position, change grid.Cell) {
-	hexColor := toHex(change.FgAttribute())
-	s.UpdateCh <- UIUpdate{position, hexColor}
+	s.UpdateCh <- UIUpdate{position, cellHex(change)}
+}
+
+func cellHex(cell grid.Cell) uint32 {
+	return grid.CellColor(cell).Hex()
 }
 
 func toHex(attribute termbox.Attribute) uint32 {
-	switch attribute {
-	case termbox.ColorBlue:
-		return 0x00333fff
-	case termbox.ColorRed:
-		return 0x00ff3358
-	case termbox.ColorYellow:
-		return 0x00fff933
-	case termbox.ColorWhite:
-		return 0x00ffffff
-	case termbox.ColorDefault:
-		return 0x000e0e0e
-	default:
-		return 0x000e0e0e
-	}
+	return grid.AttributeColor(attribute).Hex()
 }
 
 func (s *SdlUi) pos(x int, y int) int {
@@ -229,6 +311,10 @@ func (ui *SdlUi) Draw() {
 	ui.UpdateCh <- UIRefresh{}
 }
 
+func (s *SdlUi) SetOverlay(name string, cells map[grid.Position]grid.Cell) {
+	s.UpdateCh <- UIOverlay{name, cells}
+}
+
 func (s *SdlUi) SetStatus(msg string) {
 	var err error
 	var font *sdlfont.Font
@@ -6,6 +6,7 @@ import (
 	"github.com/jpbetz/cellularautomata/io"
 	"github.com/mattn/go-runewidth"
 	"github.com/nsf/termbox-go"
+	"sync"
 )
 
 type TermboxUI struct {
@@ -17,6 +18,10 @@ type TermboxUI struct {
 
 	// UI
 	View *io.View
+	// hexagonal planes take two columns per cell so odd rows can be shifted by half a cell
+	hex           bool
+	overlays      io.Overlays
+	overlaysMutex sync.Mutex
 
 	// IO
 	input chan io.InputEvent
@@ -28,6 +33,8 @@ func NewTermboxUI(input chan io.InputEvent) *TermboxUI {
 		panic(err)
 	}
 	termbox.SetInputMode(termbox.InputEsc | termbox.InputMouse)
+	// 256 colors keep the basic attributes as they are and leave room to approximate the colors of ColoredCells
+	termbox.SetOutputMode(termbox.Output256)
 
 	w, h := termbox.Size()
 
@@ -40,6 +47,7 @@ func NewTermboxUI(input chan io.InputEvent) *TermboxUI {
 
 func (ui *TermboxUI) SetView(view *io.View) {
 	ui.View = view
+	ui.hex = grid.IsHex(view.Plane)
 }
 
 func (ui *TermboxUI) SetStatus(msg string) {
@@ -70,17 +78,70 @@ func pos(x int, y int) int {
 }
 
 func (ui *TermboxUI) Set(position grid.Position, cell grid.Cell) {
-	p := pos(position.X-ui.View.Offset.X, position.Y-ui.View.Offset.Y)
+	x, y, columns := ui.screenPosition(position)
+	fg := cell.FgAttribute()
+	if colored, ok := cell.(grid.ColoredCell); ok {
+		fg = colorAttribute(colored.Color())
+	}
+	for i := 0; i < columns; i++ {
+		p := pos(x+i, y)
+		if p >= 0 && p < len(ui.backbuf) {
+			ui.backbuf[p] = termbox.Cell{Ch: cell.Rune(), Fg: fg, Bg: cell.BgAttribute()}
+		}
+	}
+}
 
-	if p >= 0 && p < len(ui.backbuf) {
-		ui.backbuf[p] = termbox.Cell{Ch: cell.Rune(), Fg: cell.FgAttribute(), Bg: cell.BgAttribute()}
+// screenPosition returns the column and row a position is drawn at, and how many columns it takes.
+func (ui *TermboxUI) screenPosition(position grid.Position) (x, y, columns int) {
+	x, y = position.X-ui.View.Offset.X, position.Y-ui.View.Offset.Y
+	if !ui.hex {
+		return x, y, 1
+	}
+	return 2*x + position.Y&1, y, 2
+}
+
+// planePosition returns the position drawn at a column and row of the screen.
+func (ui *TermboxUI) planePosition(x, y int) grid.Position {
+	if !ui.hex {
+		return grid.Position{x + ui.View.Offset.X, y + ui.View.Offset.Y}
+	}
+	row := y + ui.View.Offset.Y
+	return grid.Position{(x-row&1)>>1 + ui.View.Offset.X, row}
+}
+
+// colorAttribute returns the closest color of the 256 color palette, using the grayscale ramp for grays and the
+// 6x6x6 color cube otherwise.
+func colorAttribute(c grid.Color) termbox.Attribute {
+	var index int
+	if c.R == c.G && c.G == c.B {
+		// the ramp runs from 0x08 to 0xee in steps of 10
+		level := (int(c.R) - 8 + 5) / 10
+		if level < 0 {
+			level = 0
+		} else if level > 23 {
+			level = 23
+		}
+		index = 232 + level
+	} else {
+		cube := func(v uint8) int {
+			return (int(v)*5 + 127) / 255
+		}
+		index = 16 + 36*cube(c.R) + 6*cube(c.G) + cube(c.B)
 	}
+	// attributes are offset by one from palette indexes, leaving 0 for the default color
+	return termbox.Attribute(index + 1)
 }
 
 func (ui *TermboxUI) Draw() {
 	ui.refreshCh <- true
 }
 
+func (ui *TermboxUI) SetOverlay(name string, cells map[grid.Position]grid.Cell) {
+	ui.overlaysMutex.Lock()
+	defer ui.overlaysMutex.Unlock()
+	ui.overlays.Set(name, cells)
+}
+
 func (ui *TermboxUI) handleInput() {
 	for {
 		switch ev := termbox.PollEvent(); ev.Type {
@@ -90,10 +151,14 @@ func (ui *TermboxUI) handleInput() {
 				return
 			} else if ev.Key == termbox.KeySpace {
 				ui.input <- io.Pause{}
+			} else if ev.Ch == 's' {
+				ui.input <- io.Save{}
+			} else if ev.Ch != 0 {
+				ui.input <- io.Key{Ch: ev.Ch}
 			}
 		case termbox.EventMouse:
-			if ev.Key == termbox.MouseLeft {
-				ui.input <- io.Click{Position: grid.Position{ev.MouseX, ev.MouseY}}
+			if ev.Key == termbox.MouseLeft && ui.View != nil {
+				ui.input <- io.Click{Position: ui.planePosition(ev.MouseX, ev.MouseY)}
 				ui.Draw()
 			}
 		case termbox.EventResize:
@@ -132,11 +197,26 @@ func (ui *TermboxUI) refresh() {
 	for range ui.refreshCh {
 		termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
 		copy(termbox.CellBuffer(), ui.backbuf)
+		ui.refreshOverlays()
 		ui.refreshPowerline()
 		termbox.Flush()
 	}
 }
 
+func (ui *TermboxUI) refreshOverlays() {
+	ui.overlaysMutex.Lock()
+	defer ui.overlaysMutex.Unlock()
+	buffer := termbox.CellBuffer()
+	w, h := termbox.Size()
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if cell, ok := ui.overlays.Get(ui.planePosition(x, y)); ok {
+				buffer[pos(x, y)] = termbox.Cell{Ch: cell.Rune(), Fg: cell.FgAttribute(), Bg: cell.BgAttribute()}
+			}
+		}
+	}
+}
+
 func (ui *TermboxUI) refreshPowerline() {
 	_, h := termbox.Size()
 	inputLine := h - 1
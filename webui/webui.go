@@ -0,0 +1,347 @@
+package webui
+
+import (
+	_ "embed"
+	"encoding/json"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/jpbetz/cellularautomata/monitor"
+	"github.com/nsf/termbox-go"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+)
+
+//go:embed index.html
+var indexPage []byte
+
+// clientQueue is the number of frames waiting to be sent to a browser before it is disconnected for being too slow,
+// so that a stalled browser never blocks the engine.
+const clientQueue = 256
+
+// WebUI draws the view on a canvas in the browsers connected to it. The page is served at / and connects to /ws, a
+// WebSocket over which the cells changed by Set, and the status, are sent in batches on every Draw, and clicks and
+// key presses come back to be sent as input events. The whole plane is drawn, so the offset of the view is not used.
+type WebUI struct {
+	input    chan io.InputEvent
+	listener net.Listener
+
+	mu     sync.Mutex
+	view   *io.View
+	bounds grid.Rectangle
+	width  int
+	hex    bool
+	// the colors of the cells of the plane, row by row, as last set
+	colors   []grid.Color
+	overlays io.Overlays
+	// the indexes of the cells to send on the next Draw
+	dirty   []int
+	isDirty []bool
+	status  string
+	clients map[*client]bool
+}
+
+type client struct {
+	conn   *Conn
+	frames chan []byte
+}
+
+// frame is a message to the browser. Width and Height are only set when the board is sent whole, which clears it.
+type frame struct {
+	Width  int  `json:"width,omitempty"`
+	Height int  `json:"height,omitempty"`
+	Hex    bool `json:"hex,omitempty"`
+	// Cells are x, y and color triples, with x and y from the top left of the plane and colors as 0xRRGGBB
+	Cells  []int   `json:"cells,omitempty"`
+	Status *string `json:"status,omitempty"`
+}
+
+// event is a message from the browser, a click on a cell or a key press.
+type event struct {
+	Type string `json:"type"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	Key  string `json:"key"`
+}
+
+// NewWebUI listens on the address, e.g. localhost:8080, and serves the page in the background.
+func NewWebUI(input chan io.InputEvent, addr string) (*WebUI, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	ui := &WebUI{
+		input:    input,
+		listener: listener,
+		clients:  map[*client]bool{},
+	}
+	monitor.NewGauge("cellular_web_clients", "Browsers connected to the web renderer.", func() float64 {
+		ui.mu.Lock()
+		defer ui.mu.Unlock()
+		return float64(len(ui.clients))
+	})
+	go func() {
+		if err := http.Serve(listener, ui.Handler()); err != nil {
+			log.Printf("Web renderer stopped: %v\n", err)
+		}
+	}()
+	return ui, nil
+}
+
+// Addr returns the address listened on, which has the port chosen if the address has port 0.
+func (ui *WebUI) Addr() net.Addr {
+	return ui.listener.Addr()
+}
+
+// Handler serves the page at / and the WebSocket of its frames at /ws.
+func (ui *WebUI) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexPage)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			log.Printf("Refused browser connection: %v\n", err)
+			return
+		}
+		ui.connect(conn)
+	})
+	return mux
+}
+
+// connect sends the whole board to a new browser, then its changes, and reads its events until it disconnects.
+func (ui *WebUI) connect(conn *Conn) {
+	c := &client{conn: conn, frames: make(chan []byte, clientQueue)}
+	ui.mu.Lock()
+	ui.clients[c] = true
+	if ui.view != nil {
+		c.frames <- ui.encode(ui.fullFrame())
+	}
+	ui.mu.Unlock()
+
+	go func() {
+		for data := range c.frames {
+			if err := conn.WriteMessage(TextMessage, data); err != nil {
+				ui.disconnect(c)
+				return
+			}
+		}
+	}()
+	defer ui.disconnect(c)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var e event
+		if err := json.Unmarshal(data, &e); err != nil {
+			log.Printf("Invalid browser event %q: %v\n", data, err)
+			continue
+		}
+		if inputEvent := ui.inputEvent(e); inputEvent != nil {
+			ui.input <- inputEvent
+		}
+	}
+}
+
+func (ui *WebUI) disconnect(c *client) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.removeClient(c)
+}
+
+// removeClient closes the connection of a client, if it is still connected. It must be called with mu held.
+func (ui *WebUI) removeClient(c *client) {
+	if !ui.clients[c] {
+		return
+	}
+	delete(ui.clients, c)
+	close(c.frames)
+	// closing may wait for a write to the browser to time out, which must not hold up the engine
+	go c.conn.Close()
+}
+
+// inputEvent returns the input event for an event from the browser, with the same keys as the other renderers, or nil
+// if it has none.
+func (ui *WebUI) inputEvent(e event) io.InputEvent {
+	switch e.Type {
+	case "click":
+		ui.mu.Lock()
+		defer ui.mu.Unlock()
+		position := grid.Position{X: ui.bounds.Corner1.X + e.X, Y: ui.bounds.Corner1.Y + e.Y}
+		if ui.view == nil || !ui.bounds.Contains(position) {
+			return nil
+		}
+		return io.Click{Position: position}
+	case "key":
+		runes := []rune(e.Key)
+		if len(runes) != 1 {
+			return nil
+		}
+		switch runes[0] {
+		case 'q':
+			return io.Quit{}
+		case ' ':
+			return io.Pause{}
+		case 's':
+			return io.Save{}
+		default:
+			return io.Key{Ch: runes[0]}
+		}
+	}
+	return nil
+}
+
+func (ui *WebUI) Input() chan io.InputEvent {
+	return ui.input
+}
+
+func (ui *WebUI) Run() {
+}
+
+func (ui *WebUI) Loop(done <-chan bool) {
+	<-done
+}
+
+// Close disconnects the browsers and stops serving the page.
+func (ui *WebUI) Close() {
+	ui.listener.Close()
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	for c := range ui.clients {
+		ui.removeClient(c)
+	}
+}
+
+// SetView takes the colors of the cells from the plane and sends the whole board to the browsers.
+func (ui *WebUI) SetView(view *io.View) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.view = view
+	ui.bounds = view.Plane.Bounds()
+	ui.width = ui.bounds.Corner2.X - ui.bounds.Corner1.X + 1
+	height := ui.bounds.Corner2.Y - ui.bounds.Corner1.Y + 1
+	ui.hex = grid.IsHex(view.Plane)
+	ui.colors = make([]grid.Color, ui.width*height)
+	ui.isDirty = make([]bool, ui.width*height)
+	ui.dirty = nil
+	for i := range ui.colors {
+		ui.colors[i] = grid.AttributeColor(termbox.ColorDefault)
+		if cell := view.Plane.Get(ui.position(i)); cell != nil {
+			ui.colors[i] = grid.CellColor(cell)
+		}
+	}
+	ui.broadcast(ui.fullFrame())
+}
+
+func (ui *WebUI) Set(position grid.Position, cell grid.Cell) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	if ui.view == nil || !ui.bounds.Contains(position) {
+		return
+	}
+	i := ui.index(position)
+	ui.colors[i] = grid.CellColor(cell)
+	ui.markDirty(i)
+}
+
+// Draw sends the cells set since the last Draw to the browsers as one frame.
+func (ui *WebUI) Draw() {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	if len(ui.dirty) == 0 {
+		return
+	}
+	cells := make([]int, 0, 3*len(ui.dirty))
+	for _, i := range ui.dirty {
+		ui.isDirty[i] = false
+		p := ui.position(i)
+		cells = append(cells, p.X-ui.bounds.Corner1.X, p.Y-ui.bounds.Corner1.Y, int(ui.color(i).Hex()))
+	}
+	ui.dirty = ui.dirty[:0]
+	ui.broadcast(frame{Cells: cells})
+}
+
+func (ui *WebUI) SetStatus(msg string) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.status = msg
+	ui.broadcast(frame{Status: &msg})
+}
+
+func (ui *WebUI) SetOverlay(name string, cells map[grid.Position]grid.Cell) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	for _, position := range ui.overlays.Set(name, cells) {
+		if ui.view != nil && ui.bounds.Contains(position) {
+			ui.markDirty(ui.index(position))
+		}
+	}
+}
+
+func (ui *WebUI) index(position grid.Position) int {
+	return (position.Y-ui.bounds.Corner1.Y)*ui.width + position.X - ui.bounds.Corner1.X
+}
+
+func (ui *WebUI) position(index int) grid.Position {
+	return grid.Position{X: ui.bounds.Corner1.X + index%ui.width, Y: ui.bounds.Corner1.Y + index/ui.width}
+}
+
+func (ui *WebUI) markDirty(index int) {
+	if !ui.isDirty[index] {
+		ui.isDirty[index] = true
+		ui.dirty = append(ui.dirty, index)
+	}
+}
+
+// color returns the color a cell is drawn in, that of the top most overlay over it if there is one.
+func (ui *WebUI) color(index int) grid.Color {
+	if cell, ok := ui.overlays.Get(ui.position(index)); ok {
+		return grid.CellColor(cell)
+	}
+	return ui.colors[index]
+}
+
+// fullFrame returns the whole board as drawn, with the status.
+func (ui *WebUI) fullFrame() frame {
+	height := len(ui.colors) / ui.width
+	f := frame{Width: ui.width, Height: height, Hex: ui.hex, Cells: make([]int, 0, 3*len(ui.colors))}
+	for i := range ui.colors {
+		f.Cells = append(f.Cells, i%ui.width, i/ui.width, int(ui.color(i).Hex()))
+	}
+	status := ui.status
+	f.Status = &status
+	return f
+}
+
+func (ui *WebUI) encode(f frame) []byte {
+	data, err := json.Marshal(f)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// broadcast queues the frame for every browser, disconnecting those that fell too far behind. It must be called with
+// mu held, which keeps the frames in order.
+func (ui *WebUI) broadcast(f frame) {
+	if len(ui.clients) == 0 {
+		return
+	}
+	data := ui.encode(f)
+	for c := range ui.clients {
+		select {
+		case c.frames <- data:
+		default:
+			log.Printf("Disconnecting a browser that fell %d frames behind\n", clientQueue)
+			ui.removeClient(c)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/jpbetz/cellularautomata/grid"
+	"github.com/jpbetz/cellularautomata/io"
+	"github.com/nsf/termbox-go"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type cell bool
+
+func (c cell) Rune() rune {
+	if c {
+		return '█'
+	}
+	return ' '
+}
+
+func (c cell) FgAttribute() termbox.Attribute {
+	if c {
+		return termbox.ColorBlue
+	}
+	return termbox.ColorDefault
+}
+
+func (c cell) BgAttribute() termbox.Attribute {
+	return termbox.ColorDefault
+}
+
+var (
+	blue  = int(grid.AttributeColor(termbox.ColorBlue).Hex())
+	dark  = int(grid.AttributeColor(termbox.ColorDefault).Hex())
+	white = int(grid.AttributeColor(termbox.ColorWhite).Hex())
+)
+
+func TestWebSocketMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	}))
+	defer server.Close()
+
+	conn, err := Dial("ws" + strings.TrimPrefix(server.URL, "http") + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// the sizes take each of the three encodings of the length of a frame
+	for _, size := range []int{5, 300, 70000} {
+		sent := bytes.Repeat([]byte{byte(size)}, size)
+		if err := conn.WriteMessage(BinaryMessage, sent); err != nil {
+			t.Fatal(err)
+		}
+		messageType, received, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if messageType != BinaryMessage || !bytes.Equal(sent, received) {
+			t.Errorf("Expected a message of %d bytes to be echoed but got %d bytes of type %d", size, len(received),
+				messageType)
+		}
+	}
+
+	request, _ := http.NewRequest("GET", server.URL+"/", nil)
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	request.Header.Set("Origin", "http://example.com")
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a page of another origin to be refused but the handshake answered %s", resp.Status)
+	}
+}
+
+func readFrame(t *testing.T, conn *Conn) frame {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f frame
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func sendEvent(t *testing.T, conn *Conn, e event) {
+	data, _ := json.Marshal(e)
+	if err := conn.WriteMessage(TextMessage, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive(t *testing.T, input chan io.InputEvent) io.InputEvent {
+	select {
+	case e := <-input:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected an input event")
+		return nil
+	}
+}
+
+func TestWebUI(t *testing.T) {
+	input := make(chan io.InputEvent, 10)
+	ui, err := NewWebUI(input, "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ui.Close()
+	board := grid.NewBasicBoard(3, 2)
+	board.Initialize(cell(false))
+	board.Set(grid.Position{X: 2, Y: 0}, cell(true))
+	ui.SetView(&io.View{Plane: board, Offset: grid.Origin})
+	ui.SetStatus("ready")
+
+	resp, err := http.Get("http://" + ui.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), "<canvas") {
+		t.Errorf("Expected the page to have a canvas but was\n%s", page)
+	}
+
+	conn, err := Dial("ws://" + ui.Addr().String() + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	full := readFrame(t, conn)
+	expected := []int{0, 0, dark, 1, 0, dark, 2, 0, blue, 0, 1, dark, 1, 1, dark, 2, 1, dark}
+	if full.Width != 3 || full.Height != 2 || !reflect.DeepEqual(full.Cells, expected) {
+		t.Errorf("Expected the whole board %v but got %+v", expected, full)
+	}
+	if full.Status == nil || *full.Status != "ready" {
+		t.Errorf("Expected the whole board to come with the status but got %v", full.Status)
+	}
+
+	// cells are only sent on Draw, once each however often they were set
+	ui.Set(grid.Position{X: 0, Y: 1}, cell(true))
+	ui.Set(grid.Position{X: 2, Y: 0}, cell(true))
+	ui.Set(grid.Position{X: 2, Y: 0}, cell(false))
+	ui.Draw()
+	delta := readFrame(t, conn)
+	expected = []int{0, 1, blue, 2, 0, dark}
+	if delta.Width != 0 || !reflect.DeepEqual(delta.Cells, expected) || delta.Status != nil {
+		t.Errorf("Expected the changes %v but got %+v", expected, delta)
+	}
+
+	ui.SetOverlay("route", map[grid.Position]grid.Cell{{X: 1, Y: 1}: overlayCell{}})
+	ui.Draw()
+	if overlay := readFrame(t, conn); !reflect.DeepEqual(overlay.Cells, []int{1, 1, white}) {
+		t.Errorf("Expected the overlay to be drawn but got %+v", overlay)
+	}
+	ui.SetStatus("generation 1")
+	if status := readFrame(t, conn); status.Status == nil || *status.Status != "generation 1" || status.Cells != nil {
+		t.Errorf("Expected just the status but got %+v", status)
+	}
+
+	sendEvent(t, conn, event{Type: "click", X: 2, Y: 1})
+	if e := receive(t, input); e != (io.Click{Position: grid.Position{X: 2, Y: 1}}) {
+		t.Errorf("Expected a click on 2, 1 but got %v", e)
+	}
+	// clicks off the board are dropped
+	sendEvent(t, conn, event{Type: "click", X: 3, Y: 0})
+	for key, expected := range map[string]io.InputEvent{" ": io.Pause{}, "s": io.Save{}, "c": io.Key{Ch: 'c'}} {
+		sendEvent(t, conn, event{Type: "key", Key: key})
+		if e := receive(t, input); e != expected {
+			t.Errorf("Expected the key %q to send %v but got %v", key, expected, e)
+		}
+	}
+}
+
+type overlayCell struct{}
+
+func (c overlayCell) Rune() rune                     { return '*' }
+func (c overlayCell) FgAttribute() termbox.Attribute { return termbox.ColorWhite }
+func (c overlayCell) BgAttribute() termbox.Attribute { return termbox.ColorDefault }